@@ -4,7 +4,15 @@ import (
 	_ "embed"
 
 	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"glimpse/screenshots"
+	"glimpse/server"
 )
 
 //go:embed ocr-helper
@@ -14,6 +22,8 @@ var ocrHelper []byte
 type App struct {
 	ctx context.Context
 	screenshotService screenshots.Service
+	// logFile is nil when the log can only go to stderr
+	logFile *screenshots.LogFile
 }
 
 // NewApp creates a new App application struct
@@ -25,35 +35,281 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.setupLogging()
 
 	d := screenshots.NewDirProvider()
 	o := screenshots.NewOCRProvider(ocrHelper)
 	i := screenshots.NewIndexer()
+	sv := screenshots.NewSavedSearches()
+	an := screenshots.NewAnnotations()
+	e := screenshots.NewEmbedder()
+	events := screenshots.MultiEvents{&wailsEvents{ctx: ctx}}
+
+	// the HTTP API is opt-in
+	apiAddr := os.Getenv("GLIMPSE_API_ADDR")
+	var broadcast *server.Broadcaster
+	if apiAddr != "" {
+		broadcast = server.NewBroadcaster()
+		events = append(events, broadcast)
+	}
+
+	a.screenshotService = screenshots.NewScreenshotService(d, o, i, sv, an, e, events, a.ctx)
+
+	if apiAddr != "" {
+		a.serveAPI(apiAddr, broadcast)
+	}
+
+	go a.reindexIfOutdated()
+}
+
+// reindexIfOutdated rebuilds an index left by an older version of the app,
+// the frontend follows it through the usual scan events
+func (a *App) reindexIfOutdated() {
+	summary, err := a.screenshotService.ReindexIfOutdated()
+	if err != nil {
+		slog.Error("error rebuilding outdated index", "error", err)
+		return
+	}
+	if summary != nil {
+		slog.Info("outdated index rebuilt", "files", summary.Files, "indexed", summary.Indexed, "failed", summary.Failed)
+	}
+}
+
+// setupLogging logs at GLIMPSE_LOG_LEVEL, info by default, to stderr and
+// the rotating log in the app data dir
+func (a *App) setupLogging() {
+	level, levelErr := screenshots.ParseLogLevel(os.Getenv("GLIMPSE_LOG_LEVEL"))
 
-	a.screenshotService = screenshots.NewScreenshotService(d, o, i, a.ctx)
+	var out io.Writer = os.Stderr
+	dir, err := screenshots.LogDir()
+	if err == nil {
+		a.logFile, err = screenshots.OpenLogFile(filepath.Join(dir, screenshots.LogFileName), screenshots.DefaultLogMaxBytes, screenshots.DefaultLogMaxFiles)
+	}
+	if err == nil {
+		out = io.MultiWriter(a.logFile, os.Stderr)
+	}
+	slog.SetDefault(screenshots.NewLogger(out, level))
+
+	if err != nil {
+		slog.Error("logging to stderr only", "error", err)
+	}
+	if levelErr != nil {
+		slog.Warn("using the info log level", "error", levelErr)
+	}
+	slog.Info("glimpse started", "level", level.String())
+}
+
+// serveAPI serves the HTTP API on addr until the app quits
+func (a *App) serveAPI(addr string, broadcast *server.Broadcaster) {
+	srv, err := server.New(a.screenshotService, broadcast, server.Options{Addr: addr})
+	if err != nil {
+		slog.Error("error starting API server", "addr", addr, "error", err)
+		return
+	}
+	slog.Info("serving API", "addr", srv.Addr(), "token_file", srv.TokenFile())
+
+	go func() {
+		if err := srv.ListenAndServe(a.ctx); err != nil {
+			slog.Error("error serving API", "addr", addr, "error", err)
+		}
+	}()
 }
 
 func (a *App) shutdown(ctx context.Context) {
 	a.screenshotService.Shutdown()
+	if a.logFile != nil {
+		a.logFile.Close()
+	}
 }
 
-func (a *App) ScanScreenshots() error {
-	err := a.screenshotService.ScanAndIndex()
-	if err != nil {
-		return err
+// GetRecentLogs returns the last lines of the log, at most limit or the
+// default when limit is 0
+func (a *App) GetRecentLogs(limit int) ([]string, error) {
+	if a.logFile == nil {
+		return nil, errors.New("there is no log file, the log only goes to stderr")
 	}
-	return nil
+
+	return screenshots.RecentLogs(a.logFile.Path(), limit)
 }
 
-func (a *App) SearchScreenshots(query string) error {
-	err := a.screenshotService.Search(query)
+// GetDiagnostics returns the report to copy into a bug report
+func (a *App) GetDiagnostics() (string, error) {
+	logPath := ""
+	if a.logFile != nil {
+		logPath = a.logFile.Path()
+	}
+
+	var b strings.Builder
+	if err := screenshots.WriteDiagnostics(&b, a.screenshotService, logPath, screenshots.DefaultRecentLogs); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// scanFailedError is the error of a scan where every file failed. Wails
+// drops the result of a method that returns an error, so the summary goes
+// to the frontend with it, see formatError.
+type scanFailedError struct {
+	Message string `json:"error"`
+	Summary *screenshots.ScanSummary `json:"summary"`
+}
+
+func (e *scanFailedError) Error() string {
+	return e.Message
+}
+
+// formatError turns the errors of bound methods into what the frontend's
+// promises reject with, the message unless there is more to send
+func formatError(err error) any {
+	var scanErr *scanFailedError
+	if errors.As(err, &scanErr) {
+		return scanErr
+	}
+
+	return err.Error()
+}
+
+func (a *App) ScanScreenshots() (*screenshots.ScanSummary, error) {
+	summary, err := a.screenshotService.ScanAndIndex()
+	if errors.Is(err, screenshots.ErrScanFailed) && summary != nil {
+		return summary, &scanFailedError{Message: err.Error(), Summary: summary}
+	}
+
+	return summary, err
+}
+
+func (a *App) SearchScreenshots(query string, opts screenshots.SearchOptions) (*screenshots.SearchResults, error) {
+	results, err := a.screenshotService.Search(query, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	
-	return nil
+	return results, nil
+}
+
+func (a *App) Suggest(prefix string) ([]screenshots.Suggestion, error) {
+	suggestions, err := a.screenshotService.Suggest(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+func (a *App) SemanticSearchAvailable() bool {
+	return a.screenshotService.SemanticSearchAvailable()
+}
+
+func (a *App) GetSavedSearches() ([]screenshots.SavedSearch, error) {
+	searches, err := a.screenshotService.SavedSearches()
+	if err != nil {
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+func (a *App) SaveSearch(name string, query string, opts screenshots.SearchOptions, smart bool) (*screenshots.SavedSearch, error) {
+	search, err := a.screenshotService.SaveSearch(name, query, opts, smart)
+	if err != nil {
+		return nil, err
+	}
+
+	return search, nil
+}
+
+func (a *App) RenameSavedSearch(id string, name string) error {
+	return a.screenshotService.RenameSavedSearch(id, name)
+}
+
+func (a *App) DeleteSavedSearch(id string) error {
+	return a.screenshotService.DeleteSavedSearch(id)
+}
+
+func (a *App) RunSavedSearch(id string, opts screenshots.SearchOptions) (*screenshots.SearchResults, error) {
+	results, err := a.screenshotService.RunSavedSearch(id, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (a *App) FindSimilar(path string, maxDistance int) ([]screenshots.SimilarImage, error) {
+	similar, err := a.screenshotService.FindSimilar(path, maxDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	return similar, nil
 }
 
+func (a *App) FindDuplicates(opts screenshots.DuplicateOptions) (*screenshots.DuplicateReport, error) {
+	report, err := a.screenshotService.FindDuplicates(opts)
+	if err != nil {
+		return nil, err
+	}
 
+	return report, nil
+}
 
+func (a *App) ResolveDuplicates(groups []screenshots.DuplicateGroup, keep string, dryRun bool) (*screenshots.DuplicateResolution, error) {
+	resolution, err := a.screenshotService.ResolveDuplicates(groups, keep, dryRun)
+	if err != nil {
+		return nil, err
+	}
 
+	return resolution, nil
+}
+
+func (a *App) UndoDuplicates(batchID string) (*screenshots.DuplicateResolution, error) {
+	resolution, err := a.screenshotService.UndoDuplicates(batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolution, nil
+}
+
+func (a *App) AddTags(id string, tags []string) (*screenshots.Annotation, error) {
+	annotation, err := a.screenshotService.AddTags(id, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return annotation, nil
+}
+
+func (a *App) RemoveTags(id string, tags []string) (*screenshots.Annotation, error) {
+	annotation, err := a.screenshotService.RemoveTags(id, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return annotation, nil
+}
+
+func (a *App) SetNote(id string, note string) (*screenshots.Annotation, error) {
+	annotation, err := a.screenshotService.SetNote(id, note)
+	if err != nil {
+		return nil, err
+	}
+
+	return annotation, nil
+}
+
+// SetKeywordExtractor switches how tags are extracted, call RecomputeTags
+// to retag what is already indexed
+func (a *App) SetKeywordExtractor(name string, opts screenshots.KeywordOptions) error {
+	return a.screenshotService.SetKeywordExtractor(name, opts)
+}
+
+func (a *App) RecomputeTags(all bool) (int, error) {
+	updated, err := a.screenshotService.RecomputeTags(all)
+	if err != nil {
+		return 0, err
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"glimpse/screenshots"
+)
+
+// scanOutput is the last JSON line of scan, after a line per document
+type scanOutput struct {
+	Summary *screenshots.ScanSummary `json:"summary"`
+}
+
+// retagOutput is the JSON written once the tags are extracted again
+type retagOutput struct {
+	Retagged int `json:"retagged"`
+}
+
+// runScan indexes ~/Desktop like the app does, or the given folders
+func runScan(c *cli, args []string) error {
+	fs := c.flags("scan")
+	c.keywordFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	service, err := c.screenshots(true)
+	if err != nil {
+		return err
+	}
+	c.events.out = c.stdout
+	c.events.json = c.json
+
+	total := &screenshots.ScanSummary{Errors: make([]screenshots.ScanError, 0)}
+	add := func(summary *screenshots.ScanSummary, err error) error {
+		if summary != nil {
+			addSummary(total, summary)
+		}
+		// a folder where every file failed doesn't stop the others, the
+		// summary says why
+		if err != nil && !errors.Is(err, screenshots.ErrScanFailed) {
+			return err
+		}
+		return nil
+	}
+
+	if fs.NArg() == 0 {
+		if err := add(service.ScanAndIndex()); err != nil {
+			return err
+		}
+	}
+	for _, dir := range fs.Args() {
+		if err := add(service.ScanDir(dir)); err != nil {
+			return err
+		}
+	}
+
+	if c.json {
+		if err := writeJSONLine(c.stdout, scanOutput{Summary: total}); err != nil {
+			return err
+		}
+	} else {
+		printSummary(c.stdout, total)
+	}
+
+	return failedFiles(total)
+}
+
+// addSummary adds the counts and errors of summary to total
+func addSummary(total *screenshots.ScanSummary, summary *screenshots.ScanSummary) {
+	total.Files += summary.Files
+	total.Indexed += summary.Indexed
+	total.Skipped += summary.Skipped
+	total.Failed += summary.Failed
+	total.Errors = append(total.Errors, summary.Errors...)
+}
+
+func printSummary(w io.Writer, summary *screenshots.ScanSummary) {
+	fmt.Fprintf(w, "%d documents indexed from %d files", summary.Indexed, summary.Files)
+	if summary.Skipped > 0 {
+		fmt.Fprintf(w, ", %d without text", summary.Skipped)
+	}
+	if summary.Failed > 0 {
+		fmt.Fprintf(w, ", %d failed", summary.Failed)
+	}
+	fmt.Fprintln(w)
+}
+
+// failedFiles returns an error when files of a scan failed, they were
+// printed as they failed
+func failedFiles(summary *screenshots.ScanSummary) error {
+	if summary.Failed == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d of %d files failed to index", summary.Failed, summary.Files)
+}
+
+// searchOutput is the JSON written by search
+type searchOutput struct {
+	Total uint64 `json:"total"`
+	Hits []record `json:"hits"`
+}
+
+// runSearch prints the hits of a query, it fails with errNoResults when
+// there are none
+func runSearch(c *cli, args []string) error {
+	fs := c.flags("search")
+	limit := fs.Int("limit", 20, "number of hits to print, at most 100")
+	page := fs.Int("page", 1, "page of hits to print")
+	fuzzy := fs.Int("fuzzy", 0, "edit distance tolerated on words, 0 to 2")
+	mode := fs.String("mode", screenshots.SearchKeyword, "keyword, semantic or hybrid")
+	sortBy := fs.String("sort", screenshots.SortRelevance, "relevance, newest, oldest, path or size")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	keyword := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(keyword) == "" {
+		return usagef("missing query")
+	}
+	if *fuzzy < 0 || *fuzzy > 2 {
+		return usagef("-fuzzy must be between 0 and 2")
+	}
+
+	service, err := c.screenshots(false)
+	if err != nil {
+		return err
+	}
+
+	results, hits, err := service.SearchHits(keyword, screenshots.SearchOptions{
+		Fuzziness: *fuzzy,
+		Page: *page,
+		Size: *limit,
+		Sort: *sortBy,
+		Mode: *mode,
+	})
+	if err != nil {
+		return err
+	}
+
+	records := make([]record, 0, len(hits))
+	for i := range hits {
+		records = append(records, newRecord(&hits[i].ScreenshotDoc, false))
+	}
+
+	if c.json {
+		if err := writeJSON(c.stdout, searchOutput{Total: results.Total, Hits: records}); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range records {
+			fmt.Fprintln(c.stdout, r.Path)
+			for _, snippet := range r.Snippets {
+				fmt.Fprintf(c.stdout, "    %s\n", snippet)
+			}
+		}
+		if results.Total > uint64(len(records)) {
+			fmt.Fprintf(c.stdout, "%d of %d hits, see -page and -limit\n", len(records), results.Total)
+		}
+	}
+
+	if results.Total == 0 {
+		return errNoResults
+	}
+
+	return nil
+}
+
+// runStats prints what the index holds
+func runStats(c *cli, args []string) error {
+	fs := c.flags("stats")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	service, err := c.screenshots(false)
+	if err != nil {
+		return err
+	}
+
+	stats, err := service.Stats()
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return writeJSON(c.stdout, stats)
+	}
+
+	fmt.Fprintf(c.stdout, "documents  %d\n", stats.Documents)
+	fmt.Fprintf(c.stdout, "files      %d\n", stats.Files)
+	fmt.Fprintf(c.stdout, "index      %s (%s)\n", stats.IndexPath, formatBytes(stats.IndexBytes))
+
+	facets := make([]string, 0, len(stats.Facets))
+	for name := range stats.Facets {
+		facets = append(facets, name)
+	}
+	sort.Strings(facets)
+	for _, name := range facets {
+		if len(stats.Facets[name]) == 0 {
+			continue
+		}
+		values := make([]string, 0, len(stats.Facets[name]))
+		for _, v := range stats.Facets[name] {
+			values = append(values, fmt.Sprintf("%s (%d)", v.Value, v.Count))
+		}
+		fmt.Fprintf(c.stdout, "%-10s %s\n", name, strings.Join(values, ", "))
+	}
+
+	return nil
+}
+
+// runReindex rebuilds the index, or with -tags-only extracts the tags
+// again without running OCR
+func runReindex(c *cli, args []string) error {
+	fs := c.flags("reindex")
+	c.keywordFlags(fs)
+	tagsOnly := fs.Bool("tags-only", false, "only extract the tags again, from the stored text")
+	all := fs.Bool("all", false, "with -tags-only, update every document, not only those tagged by another extractor")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *all && !*tagsOnly {
+		return usagef("-all needs -tags-only")
+	}
+
+	service, err := c.screenshots(!*tagsOnly)
+	if err != nil {
+		return err
+	}
+
+	if *tagsOnly {
+		updated, err := service.RecomputeTags(*all)
+		if err != nil {
+			return err
+		}
+		if c.json {
+			return writeJSON(c.stdout, retagOutput{Retagged: updated})
+		}
+		fmt.Fprintf(c.stdout, "%d documents retagged\n", updated)
+		return nil
+	}
+
+	summary, err := service.Reindex()
+	if summary == nil {
+		return err
+	}
+	if c.json {
+		if err := writeJSON(c.stdout, summary); err != nil {
+			return err
+		}
+	} else {
+		printSummary(c.stdout, summary)
+	}
+	if err != nil {
+		return err
+	}
+
+	return failedFiles(summary)
+}
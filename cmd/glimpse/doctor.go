@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"glimpse/screenshots"
+)
+
+// Status of a doctor check. Only failures make doctor exit with an error,
+// warnings are features that won't work.
+const (
+	checkOK = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+type check struct {
+	Name string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// tools are the external programs Glimpse runs, and what they are for
+var tools = []struct {
+	name string
+	purpose string
+}{
+	{"tesseract", "word boxes for highlighting matches on the image"},
+	{"pdfinfo", "indexing PDFs"},
+	{"pdftotext", "indexing PDFs"},
+	{"pdftoppm", "indexing scanned PDFs"},
+	{"ffmpeg", "indexing screen recordings"},
+}
+
+// runDoctor checks what Glimpse needs to scan and search, it fails with
+// errChecksFailed if something essential is missing. With -report it
+// writes the diagnostics to attach to a bug report instead.
+func runDoctor(c *cli, args []string) error {
+	fs := c.flags("doctor")
+	report := fs.Bool("report", false, "write a diagnostics report with the latest lines of the app's log")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *report {
+		if c.json {
+			return usagef("-report writes text, it can't be used with -json")
+		}
+		return c.writeReport()
+	}
+
+	checks := []check{checkConfigDir()}
+	checks = append(checks, c.checkIndex()...)
+	checks = append(checks, c.checkOCRHelper())
+	for _, tool := range tools {
+		checks = append(checks, checkTool(tool.name, tool.purpose))
+	}
+	checks = append(checks, checkDesktop())
+
+	failed := false
+	for _, ch := range checks {
+		if ch.Status == checkFail {
+			failed = true
+		}
+	}
+
+	if c.json {
+		if err := writeJSON(c.stdout, checks); err != nil {
+			return err
+		}
+	} else {
+		for _, ch := range checks {
+			fmt.Fprintf(c.stdout, "%-4s  %-14s %s\n", ch.Status, ch.Name, ch.Detail)
+		}
+	}
+
+	if failed {
+		return errChecksFailed
+	}
+
+	return nil
+}
+
+// checkConfigDir checks the index and settings can be written
+func checkConfigDir() check {
+	ch := check{Name: "config dir", Status: checkFail}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		ch.Detail = err.Error()
+		return ch
+	}
+	dir := filepath.Join(configDir, "Glimpse")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		ch.Detail = err.Error()
+		return ch
+	}
+
+	file, err := os.CreateTemp(dir, "doctor-*")
+	if err != nil {
+		ch.Detail = fmt.Sprintf("%s is not writable: %v", dir, err)
+		return ch
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	ch.Status = checkOK
+	ch.Detail = dir
+	return ch
+}
+
+// checkIndex opens the index and checks semantic search, which needs the
+// service
+func (c *cli) checkIndex() []check {
+	index := check{Name: "index", Status: checkFail}
+	semantic := check{Name: "semantic", Status: checkWarn, Detail: "needs a build with vector support and a word vector model"}
+
+	service, err := c.screenshots(false)
+	if err != nil {
+		index.Detail = err.Error()
+		return []check{index, semantic}
+	}
+
+	stats, err := service.Stats()
+	if err != nil {
+		index.Detail = err.Error()
+	} else {
+		index.Status = checkOK
+		index.Detail = fmt.Sprintf("%d documents in %s", stats.Documents, stats.IndexPath)
+	}
+
+	if service.SemanticSearchAvailable() {
+		semantic.Status = checkOK
+		semantic.Detail = "available"
+	}
+
+	return []check{index, semantic}
+}
+
+// checkOCRHelper checks the helper scanning needs can be found
+func (c *cli) checkOCRHelper() check {
+	path, err := c.helperPath()
+	if err != nil {
+		return check{Name: "ocr helper", Status: checkFail, Detail: err.Error()}
+	}
+
+	return check{Name: "ocr helper", Status: checkOK, Detail: path}
+}
+
+// checkTool checks an optional program is on the PATH
+func checkTool(name, purpose string) check {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return check{Name: name, Status: checkWarn, Detail: "not found, needed for " + purpose}
+	}
+
+	return check{Name: name, Status: checkOK, Detail: path}
+}
+
+// checkDesktop checks the folder the app scans can be read
+func checkDesktop() check {
+	ch := check{Name: "desktop", Status: checkWarn}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		ch.Detail = err.Error()
+		return ch
+	}
+	dir := filepath.Join(home, "Desktop")
+	if _, err := os.ReadDir(dir); err != nil {
+		ch.Detail = fmt.Sprintf("%v, pass folders to scan instead", err)
+		return ch
+	}
+
+	ch.Status = checkOK
+	ch.Detail = dir
+	return ch
+}
+
+// writeReport writes the diagnostics the app copies, with the app's log
+func (c *cli) writeReport() error {
+	logPath := ""
+	if dir, err := screenshots.LogDir(); err == nil {
+		logPath = filepath.Join(dir, screenshots.LogFileName)
+	}
+
+	// a report is still worth having without the index
+	service, err := c.screenshots(false)
+	if err != nil {
+		fmt.Fprintf(c.stdout, "error opening the index: %v\n", err)
+	}
+
+	return screenshots.WriteDiagnostics(c.stdout, service, logPath, screenshots.DefaultRecentLogs)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"glimpse/screenshots"
+)
+
+// csvColumns are the columns of a CSV export, lists are joined with "; "
+var csvColumns = []string{
+	"path", "parent", "page", "dir", "ext", "size", "created", "modified",
+	"width", "height", "category", "language", "source_app", "tags",
+	"user_tags", "note", "urls", "content_hash",
+}
+
+// runExport writes every indexed document as JSON lines or CSV
+func runExport(c *cli, args []string) error {
+	fs := c.flags("export")
+	format := fs.String("format", "json", "json (one document per line) or csv")
+	withText := fs.Bool("text", false, "include the OCR'd text")
+	output := fs.String("o", "", "file to write to instead of stdout")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if c.json {
+		*format = "json"
+	}
+	if *format != "json" && *format != "csv" {
+		return usagef("unknown format %q", *format)
+	}
+
+	service, err := c.screenshots(false)
+	if err != nil {
+		return err
+	}
+
+	w := c.stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	var write func(r record) error
+	var flush func() error
+	if *format == "csv" {
+		write, flush = csvWriter(w, *withText)
+	} else {
+		write = func(r record) error { return writeJSONLine(w, r) }
+		flush = func() error { return nil }
+	}
+
+	count := 0
+	err = service.Documents(func(doc *screenshots.ScreenshotDoc) error {
+		count++
+		return write(newRecord(doc, *withText))
+	})
+	if err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if *output != "" {
+		fmt.Fprintf(c.stderr, "%d documents exported to %s\n", count, *output)
+	}
+
+	return nil
+}
+
+// csvWriter returns funcs writing records as CSV rows under a header, and
+// flushing them
+func csvWriter(w io.Writer, withText bool) (func(r record) error, func() error) {
+	writer := csv.NewWriter(w)
+	columns := csvColumns
+	if withText {
+		columns = append(columns[:len(columns):len(columns)], "text")
+	}
+	header := false
+
+	write := func(r record) error {
+		if !header {
+			header = true
+			if err := writer.Write(columns); err != nil {
+				return err
+			}
+		}
+
+		row := []string{
+			r.Path, r.Parent, itoa(r.Page), r.Dir, r.Ext,
+			strconv.FormatInt(r.Size, 10), r.Created, r.Modified,
+			itoa(r.Width), itoa(r.Height), r.Category, r.Language,
+			r.SourceApp, strings.Join(r.Tags, "; "),
+			strings.Join(r.UserTags, "; "), r.Note,
+			strings.Join(r.Entities["urls"], "; "), r.ContentHash,
+		}
+		if withText {
+			row = append(row, r.Text)
+		}
+
+		return writer.Write(row)
+	}
+
+	flush := func() error {
+		writer.Flush()
+		return writer.Error()
+	}
+
+	return write, flush
+}
+
+// itoa leaves zero values empty
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,290 @@
+// Command glimpse scans, searches and inspects the Glimpse screenshot index
+// from a terminal, without the desktop app.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+
+	"glimpse/screenshots"
+	"glimpse/server"
+)
+
+// Exit codes
+const (
+	exitOK = 0
+	// exitError is returned when a command fails, or doctor finds a problem
+	exitError = 1
+	exitUsage = 2
+	// exitNoResults is returned when a search matches nothing
+	exitNoResults = 3
+)
+
+var (
+	errNoResults = errors.New("no results")
+	errChecksFailed = errors.New("some checks failed")
+)
+
+// usageError is returned for invalid arguments, it exits with exitUsage
+type usageError struct {
+	message string
+	// shown is set when the flag package already printed the error
+	shown bool
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// command is a subcommand of glimpse
+type command struct {
+	usage string
+	summary string
+	run func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"scan": {"scan [dir ...]", "index the screenshots in ~/Desktop or the given folders", runScan},
+	"search": {"search [flags] query", "search the index with the app's query syntax", runSearch},
+	"serve": {"serve [flags]", "serve the index over HTTP on the loopback interface", runServe},
+	"watch": {"watch [flags] [dir]", "index new screenshots as they are saved", runWatch},
+	"stats": {"stats", "count what is indexed", runStats},
+	"reindex": {"reindex [flags]", "rebuild the index from the indexed files", runReindex},
+	"export": {"export [flags]", "write the metadata of every document as JSON lines or CSV", runExport},
+	"doctor": {"doctor", "check the index and the tools Glimpse depends on", runDoctor},
+}
+
+// cli holds what the commands share: where to write, the output mode and
+// the service, which is created on first use
+type cli struct {
+	ctx context.Context
+	stdout io.Writer
+	stderr io.Writer
+	json bool
+	ocrHelper string
+	logLevel slog.Level
+	// extractor and keywordOpts choose how the commands that index tag
+	// screenshots, RAKE with the defaults unless set
+	extractor string
+	keywordOpts screenshots.KeywordOptions
+
+	service screenshots.Service
+	events *cliEvents
+	// broadcast also receives the events of the service when serving
+	broadcast *server.Broadcaster
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+
+	os.Exit(code)
+}
+
+// run runs the subcommand named by the first argument and returns the exit
+// code
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "glimpse: unknown command %q\n\n", args[0])
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	c := &cli{ctx: ctx, stdout: stdout, stderr: stderr, logLevel: defaultLogLevel()}
+	err := cmd.run(c, args[1:])
+	if c.service != nil {
+		c.service.Shutdown()
+	}
+
+	var usage *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usage):
+		if usage.shown {
+			return exitUsage
+		}
+		fmt.Fprintf(stderr, "glimpse %s: %v\nusage: glimpse %s\n", args[0], err, cmd.usage)
+		return exitUsage
+	case errors.Is(err, errNoResults):
+		return exitNoResults
+	case errors.Is(err, errChecksFailed):
+		return exitError
+	}
+
+	fmt.Fprintf(stderr, "glimpse %s: %v\n", args[0], err)
+	return exitError
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: glimpse <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-9s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Every command takes -json for machine readable output. Run glimpse <command> -h for its flags.")
+	fmt.Fprintln(w, "Exit codes: 0 success, 1 failure, 2 invalid usage, 3 no search results.")
+}
+
+// flags returns the flag set of a command with the flags every command
+// takes
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.BoolVar(&c.json, "json", false, "write JSON instead of text")
+	fs.StringVar(&c.ocrHelper, "ocr-helper", "", "path of the OCR helper binary (default $GLIMPSE_OCR_HELPER or ocr-helper next to glimpse)")
+	fs.Func("log-level", "log debug, info, warn or error records to stderr (default $GLIMPSE_LOG_LEVEL or error)", func(value string) error {
+		level, err := screenshots.ParseLogLevel(value)
+		c.logLevel = level
+		return err
+	})
+
+	return fs
+}
+
+// keywordFlags adds the flags of the commands that extract tags
+func (c *cli) keywordFlags(fs *flag.FlagSet) {
+	fs.Func("extractor", "extract tags with rake, tfidf or yake (default rake)", func(value string) error {
+		if _, err := screenshots.NewKeywordExtractor(value, nil); err != nil {
+			return err
+		}
+		c.extractor = value
+		return nil
+	})
+	fs.Func("max-tags", "most tags per screenshot (default 20)", func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid tag count %q", value)
+		}
+		c.keywordOpts.MaxTags = n
+		return nil
+	})
+	fs.Float64Var(&c.keywordOpts.MinScore, "min-score", 0, "leave out keywords scoring below this")
+}
+
+// defaultLogLevel is GLIMPSE_LOG_LEVEL, or error so the log doesn't repeat
+// what commands print
+func defaultLogLevel() slog.Level {
+	level := os.Getenv("GLIMPSE_LOG_LEVEL")
+	if parsed, err := screenshots.ParseLogLevel(level); level != "" && err == nil {
+		return parsed
+	}
+
+	return slog.LevelError
+}
+
+// parse parses args, turning flag errors into usage errors
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{message: err.Error(), shown: true}
+	}
+
+	return nil
+}
+
+// screenshots returns the service, creating it on first use. The OCR
+// helper is only read when ocr is set, searching doesn't need it.
+func (c *cli) screenshots(ocr bool) (screenshots.Service, error) {
+	if c.service != nil {
+		return c.service, nil
+	}
+
+	var helper []byte
+	if ocr {
+		path, err := c.helperPath()
+		if err != nil {
+			return nil, err
+		}
+		helper, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading OCR helper: %v", err)
+		}
+	}
+
+	slog.SetDefault(screenshots.NewLogger(c.stderr, c.logLevel))
+
+	c.events = &cliEvents{errOut: c.stderr}
+	var events screenshots.EventSink = c.events
+	if c.broadcast != nil {
+		events = screenshots.MultiEvents{c.events, c.broadcast}
+	}
+	c.service = screenshots.NewScreenshotService(
+		screenshots.NewDirProvider(),
+		screenshots.NewOCRProvider(helper),
+		screenshots.NewIndexer(),
+		screenshots.NewSavedSearches(),
+		screenshots.NewAnnotations(),
+		screenshots.NewEmbedder(),
+		events,
+		c.ctx,
+	)
+
+	if c.extractor != "" || c.keywordOpts != (screenshots.KeywordOptions{}) {
+		name := c.extractor
+		if name == "" {
+			name = screenshots.ExtractorRAKE
+		}
+		if err := c.service.SetKeywordExtractor(name, c.keywordOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.service, nil
+}
+
+// helperPath finds the OCR helper the desktop app embeds
+func (c *cli) helperPath() (string, error) {
+	candidates := []string{c.ocrHelper, os.Getenv("GLIMPSE_OCR_HELPER")}
+	if exe, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(exe), "ocr-helper"))
+	}
+
+	for _, path := range candidates {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+
+	return "", errors.New("OCR helper not found, pass -ocr-helper or set GLIMPSE_OCR_HELPER")
+}
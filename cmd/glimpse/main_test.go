@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		expected int
+	}{
+		{"No command", nil, exitUsage},
+		{"Help", []string{"help"}, exitOK},
+		{"Unknown command", []string{"index"}, exitUsage},
+		{"Unknown flag", []string{"stats", "-verbose"}, exitUsage},
+		{"Missing query", []string{"search"}, exitUsage},
+		{"Bad fuzziness", []string{"search", "-fuzzy", "3", "error"}, exitUsage},
+		{"Bad export format", []string{"export", "-format", "xml"}, exitUsage},
+		{"Bad log level", []string{"stats", "-log-level", "loud"}, exitUsage},
+		{"Bad extractor", []string{"reindex", "-tags-only", "-extractor", "magic"}, exitUsage},
+		{"Bad tag count", []string{"scan", "-max-tags", "-1"}, exitUsage},
+		{"JSON report", []string{"doctor", "-report", "-json"}, exitUsage},
+		{"Command help", []string{"search", "-h"}, exitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(context.Background(), tt.args, &stdout, &stderr); code != tt.expected {
+				t.Errorf("expected exit code %d, got %d: %s", tt.expected, code, stderr.String())
+			}
+		})
+	}
+}
+
+func TestPlainSnippet(t *testing.T) {
+	got := plainSnippet("kubectl get <mark>pods</mark> &gt; out.txt\n<mark>502</mark> &amp; retry")
+	expected := "kubectl get [pods] > out.txt [502] & retry"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var out bytes.Buffer
+	write, flush := csvWriter(&out, true)
+
+	err := write(record{
+		Path: "/home/me/Desktop/shot.png",
+		Size: 2048,
+		Tags: []string{"deploy", "error 502"},
+		Entities: map[string][]string{"urls": {"https://grafana.example.com"}},
+		Text: "deploy failed, \"error 502\"",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], ",content_hash,text") {
+		t.Fatalf("expected a header and a row, got %q", out.String())
+	}
+	expected := `/home/me/Desktop/shot.png,,,,,2048,,,,,,,,deploy; error 502,,,https://grafana.example.com,,"deploy failed, ""error 502"""`
+	if lines[1] != expected {
+		t.Errorf("expected %s, got %s", expected, lines[1])
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	now := time.Now()
+	indexed := map[string]fileState{"old.png": {size: 10, modified: now}}
+	pending := make(map[string]fileState)
+
+	// a new file waits a poll to be sure it's written
+	current := map[string]fileState{
+		"old.png": {size: 10, modified: now},
+		"new.png": {size: 5, modified: now},
+	}
+	if paths := changedFiles(indexed, pending, current); len(paths) != 0 {
+		t.Errorf("expected nothing ready, got %v", paths)
+	}
+
+	// still being written
+	current["new.png"] = fileState{size: 20, modified: now.Add(time.Second)}
+	if paths := changedFiles(indexed, pending, current); len(paths) != 0 {
+		t.Errorf("expected nothing ready, got %v", paths)
+	}
+
+	if paths := changedFiles(indexed, pending, current); len(paths) != 1 || paths[0] != "new.png" {
+		t.Errorf("expected new.png, got %v", paths)
+	}
+	if paths := changedFiles(indexed, pending, current); len(paths) != 0 {
+		t.Errorf("expected new.png to be indexed once, got %v", paths)
+	}
+
+	delete(current, "old.png")
+	changedFiles(indexed, pending, current)
+	if _, ok := indexed["old.png"]; ok {
+		t.Error("expected deleted files to be forgotten")
+	}
+}
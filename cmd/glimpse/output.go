@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"glimpse/screenshots"
+)
+
+// cliEvents prints the documents indexed by a scan as they come, and the
+// files that failed to errOut
+type cliEvents struct {
+	mu sync.Mutex
+	out io.Writer
+	errOut io.Writer
+	json bool
+}
+
+func (e *cliEvents) Emit(name string, data interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if scanErr, ok := data.(*screenshots.ScanError); ok && name == "scan:error" {
+		if e.errOut != nil {
+			fmt.Fprintf(e.errOut, "failed %s\n", scanErr)
+		}
+		return
+	}
+
+	doc, ok := data.(screenshots.ScreenshotDoc)
+	if name != "result:found" || !ok {
+		return
+	}
+
+	if e.out == nil {
+		return
+	}
+	if e.json {
+		writeJSONLine(e.out, newRecord(&doc, false))
+		return
+	}
+	fmt.Fprintf(e.out, "indexed %s\n", doc.Path)
+}
+
+// record is a document as the CLI prints it, without the fields only the
+// app uses like the URL, word boxes and embedding
+type record struct {
+	Path string `json:"path"`
+	Parent string `json:"parent,omitempty"`
+	Page int `json:"page,omitempty"`
+	Timestamp float64 `json:"timestamp,omitempty"`
+	Dir string `json:"dir,omitempty"`
+	Ext string `json:"ext,omitempty"`
+	Size int64 `json:"size,omitempty"`
+	Created string `json:"created,omitempty"`
+	Modified string `json:"modified,omitempty"`
+	Width int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	Category string `json:"category,omitempty"`
+	Language string `json:"language,omitempty"`
+	SourceApp string `json:"source_app,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	UserTags []string `json:"user_tags,omitempty"`
+	Note string `json:"note,omitempty"`
+	ContentHash string `json:"content_hash,omitempty"`
+	Entities map[string][]string `json:"entities,omitempty"`
+	Text string `json:"text,omitempty"`
+	// Snippets are the highlights of a search hit as plain text with the
+	// matches in [brackets]
+	Snippets []string `json:"snippets,omitempty"`
+}
+
+func newRecord(doc *screenshots.ScreenshotDoc, withText bool) record {
+	r := record{
+		Path: doc.Path,
+		Parent: doc.Parent,
+		Page: doc.Page,
+		Timestamp: doc.Timestamp,
+		Dir: doc.Dir,
+		Ext: doc.Ext,
+		Size: doc.Size,
+		Width: doc.Width,
+		Height: doc.Height,
+		Category: doc.Category,
+		Language: doc.Language,
+		SourceApp: doc.SourceApp,
+		Tags: doc.Tags,
+		UserTags: doc.UserTags,
+		Note: doc.Note,
+		ContentHash: doc.ContentHash,
+		Entities: entityMap(doc.Entities),
+		Snippets: snippets(doc.Highlights),
+	}
+	if !doc.Created.IsZero() {
+		r.Created = doc.Created.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if !doc.Modified.IsZero() {
+		r.Modified = doc.Modified.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if withText {
+		r.Text = doc.Text
+	}
+
+	return r
+}
+
+// entityMap keys the non-empty entity lists by their field name
+func entityMap(e screenshots.Entities) map[string][]string {
+	lists := map[string][]string{
+		"urls": e.URLs,
+		"emails": e.Emails,
+		"ips": e.IPs,
+		"hashes": e.Hashes,
+		"status_codes": e.StatusCodes,
+		"uuids": e.UUIDs,
+		"timestamps": e.Timestamps,
+		"tickets": e.Tickets,
+	}
+	for name, values := range lists {
+		if len(values) == 0 {
+			delete(lists, name)
+		}
+	}
+	if len(lists) == 0 {
+		return nil
+	}
+
+	return lists
+}
+
+// snippets turns highlights into plain text, the text field first
+func snippets(highlights map[string][]string) []string {
+	fields := make([]string, 0, len(highlights))
+	for field := range highlights {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(a, b int) bool {
+		if (fields[a] == "text") != (fields[b] == "text") {
+			return fields[a] == "text"
+		}
+		return fields[a] < fields[b]
+	})
+
+	var out []string
+	for _, field := range fields {
+		for _, fragment := range highlights[field] {
+			out = append(out, plainSnippet(fragment))
+		}
+	}
+
+	return out
+}
+
+var markReplacer = strings.NewReplacer("<mark>", "[", "</mark>", "]", "\n", " ")
+
+// plainSnippet renders an HTML highlight fragment for a terminal
+func plainSnippet(fragment string) string {
+	return strings.TrimSpace(html.UnescapeString(markReplacer.Replace(fragment)))
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeJSONLine writes v as a single line, for streams of records
+func writeJSONLine(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// formatBytes formats n with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"glimpse/server"
+)
+
+// serveInfo is the JSON written once the server is listening
+type serveInfo struct {
+	Addr string `json:"addr"`
+	TokenFile string `json:"tokenFile,omitempty"`
+}
+
+// runServe serves the HTTP API until interrupted
+func runServe(c *cli, args []string) error {
+	fs := c.flags("serve")
+	c.keywordFlags(fs)
+	addr := fs.String("addr", server.DefaultAddr, "loopback host:port to listen on")
+	token := fs.String("token", "", "token clients must send (default read from, or saved to, -token-file)")
+	tokenFile := fs.String("token-file", "", "file holding the token (default api-token in the Glimpse config folder)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("serve takes no arguments")
+	}
+
+	c.broadcast = server.NewBroadcaster()
+	service, err := c.screenshots(true)
+	if err != nil {
+		return err
+	}
+
+	srv, err := server.New(service, c.broadcast, server.Options{Addr: *addr, Token: *token, TokenFile: *tokenFile})
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		writeJSONLine(c.stdout, serveInfo{Addr: srv.Addr(), TokenFile: srv.TokenFile()})
+	} else {
+		fmt.Fprintf(c.stdout, "serving on http://%s\n", srv.Addr())
+		if srv.TokenFile() != "" {
+			fmt.Fprintf(c.stdout, "token in %s\n", srv.TokenFile())
+		}
+	}
+
+	return srv.ListenAndServe(c.ctx)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"glimpse/screenshots"
+)
+
+// fileState is what watch compares between polls to tell a file changed
+type fileState struct {
+	size int64
+	modified time.Time
+}
+
+// runWatch indexes the screenshots saved to a folder until interrupted.
+// Files are indexed once they stop changing between two polls, so a
+// screenshot still being written isn't OCR'd half done.
+func runWatch(c *cli, args []string) error {
+	fs := c.flags("watch")
+	c.keywordFlags(fs)
+	interval := fs.Duration("interval", 2*time.Second, "how often to look for new files")
+	noInitial := fs.Bool("no-initial", false, "don't scan the files already there first")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usagef("watch takes a single folder")
+	}
+	if *interval <= 0 {
+		return usagef("-interval must be positive")
+	}
+
+	dir := fs.Arg(0)
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(home, "Desktop")
+	}
+
+	service, err := c.screenshots(true)
+	if err != nil {
+		return err
+	}
+	c.events.out = c.stdout
+	c.events.json = c.json
+
+	indexed, err := snapshot(dir)
+	if err != nil {
+		return err
+	}
+	if !*noInitial {
+		if _, err := service.ScanDir(dir); err != nil && !errors.Is(err, screenshots.ErrScanFailed) {
+			return err
+		}
+	}
+	if !c.json {
+		fmt.Fprintf(c.stderr, "watching %s, press Ctrl+C to stop\n", dir)
+	}
+
+	pending := make(map[string]fileState)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := snapshot(dir)
+		if err != nil {
+			return err
+		}
+
+		paths := changedFiles(indexed, pending, current)
+		if len(paths) == 0 {
+			continue
+		}
+		// files that fail were printed, watching goes on
+		if _, err := service.ScanFiles(paths); err != nil && !errors.Is(err, screenshots.ErrScanFailed) {
+			return err
+		}
+	}
+}
+
+// changedFiles returns the files that are new or changed since they were
+// indexed and have been stable since the last poll, marking them indexed.
+// Files still changing wait in pending for the next poll.
+func changedFiles(indexed, pending, current map[string]fileState) []string {
+	var ready []string
+	for path, state := range current {
+		if indexed[path] == state {
+			delete(pending, path)
+			continue
+		}
+		if last, ok := pending[path]; ok && last == state {
+			ready = append(ready, path)
+			indexed[path] = state
+			delete(pending, path)
+			continue
+		}
+		pending[path] = state
+	}
+
+	for path := range indexed {
+		if _, ok := current[path]; !ok {
+			delete(indexed, path)
+		}
+	}
+
+	return ready
+}
+
+// snapshot lists the supported files in dir with their size and mtime
+func snapshot(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", dir, err)
+	}
+
+	files := make(map[string]fileState)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !screenshots.IsSupported(path) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files[path] = fileState{size: info.Size(), modified: info.ModTime()}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// wailsEvents forwards the events of the screenshot service to the
+// frontend
+type wailsEvents struct {
+	ctx context.Context
+}
+
+func (w *wailsEvents) Emit(name string, data interface{}) {
+	runtime.EventsEmit(w.ctx, name, data)
+}
@@ -1,5 +1,50 @@
 export interface SearchResult {
     path: string,
     tags?: string[],
+    user_tags?: string[],
+    note?: string,
     url: string,
+    parent?: string,
+    page?: number,
+    timestamp?: number,
+    size?: number,
+    created?: string,
+    modified?: string,
+    width?: number,
+    height?: number,
+    dpi?: number,
+    scale?: number,
+    exif?: Record<string, string>,
+    source_app?: string,
+    window_title?: string,
+    category?: string,
+    language?: string,
+    urls?: string[],
+    emails?: string[],
+    ips?: string[],
+    hashes?: string[],
+    status_codes?: string[],
+    uuids?: string[],
+    timestamps?: string[],
+    tickets?: string[],
+    entity_types?: string[],
+    last_error?: string,
+    error_stage?: string,
+    highlights?: Record<string, string[]>,
+    regions?: WordBox[],
+    queryId?: number,
+}
+
+export interface WordBox {
+    text: string,
+    x: number,
+    y: number,
+    width: number,
+    height: number,
+}
+
+export interface QueryError {
+    position: number,
+    message: string,
+    queryId?: number,
 }
\ No newline at end of file
@@ -1,6 +1,43 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {screenshots} from '../models';
 
-export function ScanScreenshots():Promise<void>;
+export function AddTags(arg1:string,arg2:Array<string>):Promise<screenshots.Annotation>;
 
-export function SearchScreenshots(arg1:string):Promise<void>;
+export function DeleteSavedSearch(arg1:string):Promise<void>;
+
+export function FindDuplicates(arg1:screenshots.DuplicateOptions):Promise<screenshots.DuplicateReport>;
+
+export function FindSimilar(arg1:string,arg2:number):Promise<Array<screenshots.SimilarImage>>;
+
+export function GetDiagnostics():Promise<string>;
+
+export function GetRecentLogs(arg1:number):Promise<Array<string>>;
+
+export function GetSavedSearches():Promise<Array<screenshots.SavedSearch>>;
+
+export function RecomputeTags(arg1:boolean):Promise<number>;
+
+export function RemoveTags(arg1:string,arg2:Array<string>):Promise<screenshots.Annotation>;
+
+export function RenameSavedSearch(arg1:string,arg2:string):Promise<void>;
+
+export function ResolveDuplicates(arg1:Array<screenshots.DuplicateGroup>,arg2:string,arg3:boolean):Promise<screenshots.DuplicateResolution>;
+
+export function RunSavedSearch(arg1:string,arg2:screenshots.SearchOptions):Promise<screenshots.SearchResults>;
+
+export function SaveSearch(arg1:string,arg2:string,arg3:screenshots.SearchOptions,arg4:boolean):Promise<screenshots.SavedSearch>;
+
+export function ScanScreenshots():Promise<screenshots.ScanSummary>;
+
+export function SearchScreenshots(arg1:string,arg2:screenshots.SearchOptions):Promise<screenshots.SearchResults>;
+
+export function SemanticSearchAvailable():Promise<boolean>;
+
+export function SetKeywordExtractor(arg1:string,arg2:screenshots.KeywordOptions):Promise<void>;
+
+export function SetNote(arg1:string,arg2:string):Promise<screenshots.Annotation>;
+
+export function Suggest(arg1:string):Promise<Array<screenshots.Suggestion>>;
+
+export function UndoDuplicates(arg1:string):Promise<screenshots.DuplicateResolution>;
@@ -2,10 +2,82 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddTags(arg1, arg2) {
+  return window['go']['main']['App']['AddTags'](arg1, arg2);
+}
+
+export function DeleteSavedSearch(arg1) {
+  return window['go']['main']['App']['DeleteSavedSearch'](arg1);
+}
+
+export function FindDuplicates(arg1) {
+  return window['go']['main']['App']['FindDuplicates'](arg1);
+}
+
+export function FindSimilar(arg1, arg2) {
+  return window['go']['main']['App']['FindSimilar'](arg1, arg2);
+}
+
+export function GetDiagnostics() {
+  return window['go']['main']['App']['GetDiagnostics']();
+}
+
+export function GetRecentLogs(arg1) {
+  return window['go']['main']['App']['GetRecentLogs'](arg1);
+}
+
+export function GetSavedSearches() {
+  return window['go']['main']['App']['GetSavedSearches']();
+}
+
+export function RecomputeTags(arg1) {
+  return window['go']['main']['App']['RecomputeTags'](arg1);
+}
+
+export function RemoveTags(arg1, arg2) {
+  return window['go']['main']['App']['RemoveTags'](arg1, arg2);
+}
+
+export function RenameSavedSearch(arg1, arg2) {
+  return window['go']['main']['App']['RenameSavedSearch'](arg1, arg2);
+}
+
+export function ResolveDuplicates(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ResolveDuplicates'](arg1, arg2, arg3);
+}
+
+export function RunSavedSearch(arg1, arg2) {
+  return window['go']['main']['App']['RunSavedSearch'](arg1, arg2);
+}
+
+export function SaveSearch(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SaveSearch'](arg1, arg2, arg3, arg4);
+}
+
 export function ScanScreenshots() {
   return window['go']['main']['App']['ScanScreenshots']();
 }
 
-export function SearchScreenshots(arg1) {
-  return window['go']['main']['App']['SearchScreenshots'](arg1);
+export function SearchScreenshots(arg1, arg2) {
+  return window['go']['main']['App']['SearchScreenshots'](arg1, arg2);
+}
+
+export function SemanticSearchAvailable() {
+  return window['go']['main']['App']['SemanticSearchAvailable']();
+}
+
+export function SetKeywordExtractor(arg1, arg2) {
+  return window['go']['main']['App']['SetKeywordExtractor'](arg1, arg2);
+}
+
+export function SetNote(arg1, arg2) {
+  return window['go']['main']['App']['SetNote'](arg1, arg2);
+}
+
+export function Suggest(arg1) {
+  return window['go']['main']['App']['Suggest'](arg1);
+}
+
+export function UndoDuplicates(arg1) {
+  return window['go']['main']['App']['UndoDuplicates'](arg1);
 }
@@ -0,0 +1,455 @@
+export namespace screenshots {
+	
+	export class Annotation {
+	    tags: string[];
+	    note: string;
+	    // Go type: time
+	    updated: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Annotation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tags = source["tags"];
+	        this.note = source["note"];
+	        this.updated = this.convertValues(source["updated"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DuplicateFile {
+	    path: string;
+	    size: number;
+	    // Go type: time
+	    modified: any;
+	    contentHash?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.size = source["size"];
+	        this.modified = this.convertValues(source["modified"], null);
+	        this.contentHash = source["contentHash"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DuplicateGroup {
+	    exact: boolean;
+	    files: DuplicateFile[];
+	    reclaimableBytes: number;
+	    maxDistance?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.exact = source["exact"];
+	        this.files = this.convertValues(source["files"], DuplicateFile);
+	        this.reclaimableBytes = source["reclaimableBytes"];
+	        this.maxDistance = source["maxDistance"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DuplicateOptions {
+	    maxDistance: number;
+	    exactOnly: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.maxDistance = source["maxDistance"];
+	        this.exactOnly = source["exactOnly"];
+	    }
+	}
+	export class DuplicateReport {
+	    groups: DuplicateGroup[];
+	    reclaimableBytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.groups = this.convertValues(source["groups"], DuplicateGroup);
+	        this.reclaimableBytes = source["reclaimableBytes"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DuplicateResolution {
+	    batchId?: string;
+	    dryRun: boolean;
+	    kept: string[];
+	    trashed: TrashedFile[];
+	    restored?: string[];
+	    freedBytes: number;
+	    errors?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DuplicateResolution(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.batchId = source["batchId"];
+	        this.dryRun = source["dryRun"];
+	        this.kept = source["kept"];
+	        this.trashed = this.convertValues(source["trashed"], TrashedFile);
+	        this.restored = source["restored"];
+	        this.freedBytes = source["freedBytes"];
+	        this.errors = source["errors"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FacetValue {
+	    value: string;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FacetValue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.value = source["value"];
+	        this.count = source["count"];
+	    }
+	}
+	export class KeywordOptions {
+	    maxTags: number;
+	    minScore: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new KeywordOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.maxTags = source["maxTags"];
+	        this.minScore = source["minScore"];
+	    }
+	}
+	export class SavedSearch {
+	    id: string;
+	    name: string;
+	    query: string;
+	    fuzziness: number;
+	    sort?: string;
+	    filters?: Record<string, Array<string>>;
+	    // Go type: time
+	    created: any;
+	    smart: boolean;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SavedSearch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.query = source["query"];
+	        this.fuzziness = source["fuzziness"];
+	        this.sort = source["sort"];
+	        this.filters = source["filters"];
+	        this.created = this.convertValues(source["created"], null);
+	        this.smart = source["smart"];
+	        this.count = source["count"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScanError {
+	    path: string;
+	    stage: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.stage = source["stage"];
+	        this.message = source["message"];
+	    }
+	}
+	export class ScanSummary {
+	    files: number;
+	    indexed: number;
+	    skipped: number;
+	    failed: number;
+	    errors: ScanError[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.files = source["files"];
+	        this.indexed = source["indexed"];
+	        this.skipped = source["skipped"];
+	        this.failed = source["failed"];
+	        this.errors = this.convertValues(source["errors"], ScanError);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SearchOptions {
+	    id: number;
+	    asYouType: boolean;
+	    fuzziness: number;
+	    page: number;
+	    size: number;
+	    cursor?: string[];
+	    sort: string;
+	    filters?: Record<string, Array<string>>;
+	    mode?: string;
+	    semanticWeight?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.asYouType = source["asYouType"];
+	        this.fuzziness = source["fuzziness"];
+	        this.page = source["page"];
+	        this.size = source["size"];
+	        this.cursor = source["cursor"];
+	        this.sort = source["sort"];
+	        this.filters = source["filters"];
+	        this.mode = source["mode"];
+	        this.semanticWeight = source["semanticWeight"];
+	    }
+	}
+	export class SearchResults {
+	    queryId: number;
+	    total: number;
+	    page: number;
+	    size: number;
+	    nextCursor?: string[];
+	    facets?: Record<string, Array<FacetValue>>;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchResults(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.queryId = source["queryId"];
+	        this.total = source["total"];
+	        this.page = source["page"];
+	        this.size = source["size"];
+	        this.nextCursor = source["nextCursor"];
+	        this.facets = this.convertValues(source["facets"], Array<FacetValue>, true);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SimilarImage {
+	    path: string;
+	    url: string;
+	    parent?: string;
+	    size: number;
+	    width?: number;
+	    height?: number;
+	    distance: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SimilarImage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.url = source["url"];
+	        this.parent = source["parent"];
+	        this.size = source["size"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.distance = source["distance"];
+	    }
+	}
+	export class Suggestion {
+	    text: string;
+	    kind: string;
+	    count?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Suggestion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.kind = source["kind"];
+	        this.count = source["count"];
+	    }
+	}
+	export class TrashedFile {
+	    path: string;
+	    trashPath?: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrashedFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.trashPath = source["trashPath"];
+	        this.size = source["size"];
+	    }
+	}
+
+}
@@ -26,6 +26,7 @@ func main() {
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup: app.startup,
 		OnShutdown: app.shutdown,
+		ErrorFormatter: formatError,
 		Bind: []interface{}{
 			app,
 		},
@@ -0,0 +1,333 @@
+package screenshots
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+var ErrNotIndexed = errors.New("screenshot is not indexed")
+
+// ErrNoAnnotations is returned when tags or notes are changed on a service
+// without annotations
+var ErrNoAnnotations = errors.New("tags and notes are not available")
+
+// Annotation is what the user added to a screenshot by hand. It is kept
+// apart from the index so scanning again never overwrites it.
+type Annotation struct {
+	Tags []string `json:"tags"`
+	Note string `json:"note"`
+	Updated time.Time `json:"updated"`
+}
+
+func (a Annotation) empty() bool {
+	return len(a.Tags) == 0 && a.Note == ""
+}
+
+type AnnotationProvider interface {
+	Get(id string) (Annotation, error)
+	Set(id string, annotation Annotation) error
+}
+
+// Annotations keeps the annotation of each document ID in a JSON file next
+// to the index
+type Annotations struct {
+	appName string
+	fileName string
+	o osProvider
+
+	mu sync.Mutex
+	annotations map[string]Annotation
+	loaded bool
+}
+
+func NewAnnotations() *Annotations {
+	return &Annotations{
+		appName: "Glimpse",
+		fileName: "annotations.json",
+		o: &realOsProvider{},
+	}
+}
+
+func (a *Annotations) path() (string, error) {
+	return appDataPath(a.o, a.appName, a.fileName)
+}
+
+// load reads the annotations on first use. Callers hold mu.
+func (a *Annotations) load() error {
+	if a.loaded {
+		return nil
+	}
+
+	path, err := a.path()
+	if err != nil {
+		return fmt.Errorf("error finding annotations: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error reading annotations: %v", err)
+	}
+
+	annotations := make(map[string]Annotation)
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &annotations); err != nil {
+			return fmt.Errorf("error parsing annotations: %v", err)
+		}
+	}
+
+	a.annotations = annotations
+	a.loaded = true
+
+	return nil
+}
+
+// save writes through a temporary file like SavedSearches. Callers hold mu.
+func (a *Annotations) save() error {
+	path, err := a.path()
+	if err != nil {
+		return fmt.Errorf("error finding annotations: %v", err)
+	}
+
+	data, err := json.MarshalIndent(a.annotations, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing annotations: %v", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Get returns the annotation of id, which is empty if there is none
+func (a *Annotations) Get(id string) (Annotation, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.load(); err != nil {
+		return Annotation{}, err
+	}
+
+	return a.annotations[id], nil
+}
+
+// Set replaces the annotation of id, an empty one removes it
+func (a *Annotations) Set(id string, annotation Annotation) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.load(); err != nil {
+		return err
+	}
+
+	previous, existed := a.annotations[id]
+	if annotation.empty() {
+		delete(a.annotations, id)
+	} else {
+		a.annotations[id] = annotation
+	}
+
+	if err := a.save(); err != nil {
+		if existed {
+			a.annotations[id] = previous
+		} else {
+			delete(a.annotations, id)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// annotate copies the user's tags and note onto a document being indexed
+func (s *ScreenshotService) annotate(doc *ScreenshotDoc) {
+	if s.Annotations == nil {
+		return
+	}
+
+	// a broken annotations file shouldn't keep screenshots out of the index
+	if a, err := s.Annotations.Get(doc.Path); err == nil {
+		doc.UserTags = a.Tags
+		doc.Note = a.Note
+	}
+}
+
+// AddTags adds tags to the screenshot with the given ID, ignoring ones it
+// already has in any case
+func (s *ScreenshotService) AddTags(id string, tags []string) (*Annotation, error) {
+	return s.updateAnnotation(id, func(a *Annotation) {
+		for _, tag := range tags {
+			tag = strings.Join(strings.Fields(tag), " ")
+			if tag != "" && indexOfTag(a.Tags, tag) < 0 {
+				a.Tags = append(a.Tags, tag)
+			}
+		}
+	})
+}
+
+func (s *ScreenshotService) RemoveTags(id string, tags []string) (*Annotation, error) {
+	return s.updateAnnotation(id, func(a *Annotation) {
+		for _, tag := range tags {
+			if i := indexOfTag(a.Tags, strings.Join(strings.Fields(tag), " ")); i >= 0 {
+				a.Tags = append(a.Tags[:i:i], a.Tags[i+1:]...)
+			}
+		}
+	})
+}
+
+// SetNote replaces the note of a screenshot, an empty note removes it
+func (s *ScreenshotService) SetNote(id string, note string) (*Annotation, error) {
+	return s.updateAnnotation(id, func(a *Annotation) {
+		a.Note = strings.TrimSpace(note)
+	})
+}
+
+func indexOfTag(tags []string, tag string) int {
+	for i, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// updateAnnotation changes the annotation of an indexed document and
+// indexes the document again from its stored fields, so the change is
+// searchable right away without running OCR again
+func (s *ScreenshotService) updateAnnotation(id string, change func(a *Annotation)) (*Annotation, error) {
+	if s.Annotations == nil {
+		return nil, ErrNoAnnotations
+	}
+
+	err := s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	doc, err := s.storedDoc(id)
+	if err != nil {
+		return nil, err
+	}
+
+	annotation, err := s.Annotations.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	annotation.Tags = append([]string(nil), annotation.Tags...)
+	change(&annotation)
+	annotation.Updated = time.Now()
+
+	if err := s.Annotations.Set(id, annotation); err != nil {
+		return nil, err
+	}
+
+	doc.UserTags = annotation.Tags
+	doc.Note = annotation.Note
+	s.embed(doc)
+	if err := s.Indexer.Index(doc.Path, doc); err != nil {
+		return nil, fmt.Errorf("error indexing annotation: %v", err)
+	}
+
+	if annotation.Tags == nil {
+		annotation.Tags = make([]string, 0)
+	}
+
+	return &annotation, nil
+}
+
+// listFields are stored as a single value when they hold one item
+var listFields = func() map[string]struct{} {
+	fields := map[string]struct{}{
+		"tags": {},
+		"user_tags": {},
+		"entity_types": {},
+	}
+	for _, field := range entityFields {
+		fields[field] = struct{}{}
+	}
+	return fields
+}()
+
+// storedDoc rebuilds an indexed document from its stored fields. The
+// embedding isn't stored and has to be computed again.
+func (s *ScreenshotService) storedDoc(id string) (*ScreenshotDoc, error) {
+	request := bleve.NewSearchRequestOptions(query.NewDocIDQuery([]string{id}), 1, 0, false)
+	request.Fields = []string{"*"}
+
+	result, err := s.Indexer.Search(s.ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Hits) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotIndexed, id)
+	}
+
+	return docFromFields(result.Hits[0])
+}
+
+// docFromFields maps stored fields back onto ScreenshotDoc through its JSON
+// tags, nesting dotted fields like exif.Model again
+func docFromFields(hit *search.DocumentMatch) (*ScreenshotDoc, error) {
+	fields := make(map[string]interface{}, len(hit.Fields))
+	for name, value := range hit.Fields {
+		if parent, child, ok := strings.Cut(name, "."); ok {
+			nested, _ := fields[parent].(map[string]interface{})
+			if nested == nil {
+				nested = make(map[string]interface{})
+				fields[parent] = nested
+			}
+			nested[child] = value
+			continue
+		}
+
+		if _, ok := listFields[name]; ok {
+			if _, isList := value.([]interface{}); !isList {
+				value = []interface{}{value}
+			}
+		}
+		fields[name] = value
+	}
+	fields["path"] = hit.ID
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &ScreenshotDoc{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("error reading stored document: %v", err)
+	}
+
+	return doc, nil
+}
+
+// storedStrings reads a stored list field of a search hit
+func storedStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package screenshots
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+func newTestAnnotations(t *testing.T) *Annotations {
+	configDir := t.TempDir()
+	// the mock doesn't create directories
+	if err := os.MkdirAll(filepath.Join(configDir, "Glimpse"), 0755); err != nil {
+		t.Fatalf("error creating config dir: %v", err)
+	}
+
+	return &Annotations{
+		appName: "Glimpse",
+		fileName: "annotations.json",
+		o: &mockOsProvider{userConfigDir: configDir},
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	a := newTestAnnotations(t)
+
+	if err := a.Set("/a.png", Annotation{Tags: []string{"invoice"}, Note: "paid"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a fresh store reads what was written
+	reloaded := &Annotations{appName: a.appName, fileName: a.fileName, o: a.o}
+	got, err := reloaded.Get("/a.png")
+	if err != nil || len(got.Tags) != 1 || got.Note != "paid" {
+		t.Errorf("expected the annotation back, got %+v, %v", got, err)
+	}
+
+	if err := reloaded.Set("/a.png", Annotation{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reloaded.annotations) != 0 {
+		t.Errorf("expected an empty annotation to be removed, got %v", reloaded.annotations)
+	}
+}
+
+// searchIDs runs a glimpse query against idx and returns the sorted IDs
+func searchIDs(t *testing.T, idx bleve.Index, input string) []string {
+	t.Helper()
+
+	q, err := (&queryParser{homeDir: "/home/me"}).parse(input)
+	if err != nil {
+		t.Fatalf("%q: unexpected error: %v", input, err)
+	}
+	res, err := idx.Search(bleve.NewSearchRequest(q))
+	if err != nil {
+		t.Fatalf("%q: unexpected error: %v", input, err)
+	}
+
+	ids := make([]string, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		ids = append(ids, hit.ID)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func TestUserTagsAndNotes(t *testing.T) {
+	docs := testDocs()
+	docs[0].Text = "error rate 5%"
+	docs[0].Size = 2048
+	docs[0].EXIF = map[string]string{"Model": "MacBook Pro"}
+	docs[0].WordBoxes = "error 1 2 3 4"
+
+	idx := newTestIndex(t, docs...)
+	s := &ScreenshotService{
+		Indexer: &Indexer{idx: idx},
+		Annotations: newTestAnnotations(t),
+		ctx: context.Background(),
+	}
+	grafana := docs[0].Path
+
+	t.Run("Add tags", func(t *testing.T) {
+		a, err := s.AddTags(grafana, []string{"  on call ", "Incident", "incident", ""})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(a.Tags) != 2 || a.Tags[0] != "on call" || a.Tags[1] != "Incident" {
+			t.Errorf("expected trimmed tags without duplicates, got %v", a.Tags)
+		}
+
+		if ids := searchIDs(t, idx, "tag:incident"); len(ids) != 1 || ids[0] != grafana {
+			t.Errorf("expected the user tag to be searchable, got %v", ids)
+		}
+		if ids := searchIDs(t, idx, `tag:grafana tag:"on call"`); len(ids) != 1 {
+			t.Errorf("expected automatic and user tags to combine, got %v", ids)
+		}
+	})
+
+	t.Run("Facet", func(t *testing.T) {
+		request := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
+		addFacets(request)
+		res, err := idx.Search(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n := facetCount(collectFacets(res.Facets)[FacetUserTag], "on call"); n != 1 {
+			t.Errorf("expected one screenshot tagged on call, got %d", n)
+		}
+	})
+
+	t.Run("Note", func(t *testing.T) {
+		if _, err := s.SetNote(grafana, "  follow up with the database team "); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ids := searchIDs(t, idx, `note:"database team"`); len(ids) != 1 {
+			t.Errorf("expected the note to be searchable, got %v", ids)
+		}
+		if ids := searchIDs(t, idx, "follow"); len(ids) != 1 {
+			t.Errorf("expected free text to match notes, got %v", ids)
+		}
+	})
+
+	t.Run("Stored fields survive", func(t *testing.T) {
+		doc, err := s.storedDoc(grafana)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if doc.Text != docs[0].Text || doc.Size != 2048 || doc.Width != 2880 || !doc.Created.Equal(docs[0].Created) {
+			t.Errorf("expected indexed fields to be kept, got %+v", doc)
+		}
+		if doc.EXIF["Model"] != "MacBook Pro" || doc.WordBoxes != docs[0].WordBoxes {
+			t.Errorf("expected exif and word boxes to be kept, got %+v", doc)
+		}
+		if len(doc.Tags) != 2 || doc.Note != "follow up with the database team" {
+			t.Errorf("expected tags and note, got %+v", doc)
+		}
+	})
+
+	t.Run("Remove tags", func(t *testing.T) {
+		a, err := s.RemoveTags(grafana, []string{"INCIDENT"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(a.Tags) != 1 || a.Tags[0] != "on call" {
+			t.Errorf("expected only on call left, got %v", a.Tags)
+		}
+		if ids := searchIDs(t, idx, "tag:incident"); len(ids) != 0 {
+			t.Errorf("expected the removed tag not to match, got %v", ids)
+		}
+	})
+
+	t.Run("Kept on rescan", func(t *testing.T) {
+		doc := docs[0]
+		s.annotate(&doc)
+		if len(doc.UserTags) != 1 || doc.Note == "" {
+			t.Errorf("expected the annotation to be applied to a rescanned document, got %+v", doc)
+		}
+	})
+
+	t.Run("Not indexed", func(t *testing.T) {
+		if _, err := s.AddTags("/home/me/missing.png", []string{"x"}); !errors.Is(err, ErrNotIndexed) {
+			t.Errorf("expected ErrNotIndexed, got %v", err)
+		}
+	})
+
+	t.Run("No annotations", func(t *testing.T) {
+		s := &ScreenshotService{Indexer: &Indexer{idx: idx}, ctx: context.Background()}
+		if _, err := s.SetNote(grafana, "x"); !errors.Is(err, ErrNoAnnotations) {
+			t.Errorf("expected ErrNoAnnotations, got %v", err)
+		}
+	})
+}
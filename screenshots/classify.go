@@ -0,0 +1,357 @@
+package screenshots
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Categories assigned by the classifier, faceted and filtered on as
+// category:terminal and so on
+const (
+	CategoryTerminal = "terminal"
+	CategoryCode = "code"
+	CategoryChat = "chat"
+	CategoryBrowser = "browser"
+	CategoryDocument = "document"
+	CategoryOther = "other"
+)
+
+// minCategoryScore is the score below which a screenshot is filed as other
+const minCategoryScore = 0.35
+
+// ClassifierInput is what is known about a screenshot when it is classified
+type ClassifierInput struct {
+	// Text is the OCR'd text before normalisation, which drops prompt
+	// markers and lone brackets
+	Text string
+	// Words are the OCR'd word boxes, empty unless word boxes are enabled
+	Words []WordBox
+	// Image is the decoded image, nil if it couldn't be decoded
+	Image image.Image
+	Ext string
+	SourceApp string
+	WindowTitle string
+	Entities Entities
+}
+
+// Classification is the category of a screenshot and how sure the
+// classifier is of it, from 0 to 1
+type Classification struct {
+	Category string `json:"category"`
+	Score float64 `json:"score"`
+}
+
+// Classifier files a screenshot under a category. The heuristics can be
+// swapped for a local model by setting ScreenshotService.Classifier.
+type Classifier interface {
+	Classify(input ClassifierInput) Classification
+}
+
+// classify sets the category of doc from the OCR'd text, which needs its
+// metadata and entities set already
+func (s *ScreenshotService) classify(doc *ScreenshotDoc, text string, words []WordBox, data []byte) {
+	if s.Classifier == nil {
+		return
+	}
+
+	input := ClassifierInput{
+		Text: text,
+		Words: words,
+		Ext: doc.Ext,
+		SourceApp: doc.SourceApp,
+		WindowTitle: doc.WindowTitle,
+		Entities: doc.Entities,
+	}
+	if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+		input.Image = img
+	}
+
+	doc.Category = s.Classifier.Classify(input).Category
+}
+
+// HeuristicClassifier scores each category from the text, the layout of the
+// OCR'd words, the colours of the image and the app it was taken from
+type HeuristicClassifier struct{}
+
+// appCategories maps lower case app names, as found in the source app or
+// window title, to the category their screenshots almost always are
+var appCategories = map[string]string{
+	"terminal": CategoryTerminal, "iterm": CategoryTerminal, "iterm2": CategoryTerminal, "warp": CategoryTerminal,
+	"alacritty": CategoryTerminal, "kitty": CategoryTerminal, "wezterm": CategoryTerminal,
+	"hyper": CategoryTerminal, "konsole": CategoryTerminal, "powershell": CategoryTerminal,
+	"ghostty": CategoryTerminal, "windowsterminal": CategoryTerminal,
+	"visual studio code": CategoryCode, "vscode": CategoryCode, "xcode": CategoryCode,
+	"intellij": CategoryCode, "goland": CategoryCode, "pycharm": CategoryCode,
+	"webstorm": CategoryCode, "sublime text": CategoryCode, "neovim": CategoryCode,
+	"zed": CategoryCode, "cursor": CategoryCode, "android studio": CategoryCode,
+	"slack": CategoryChat, "discord": CategoryChat, "teams": CategoryChat,
+	"messages": CategoryChat, "whatsapp": CategoryChat, "telegram": CategoryChat,
+	"signal": CategoryChat, "mattermost": CategoryChat,
+	"chrome": CategoryBrowser, "safari": CategoryBrowser, "firefox": CategoryBrowser,
+	"edge": CategoryBrowser, "arc": CategoryBrowser, "brave": CategoryBrowser,
+	"opera": CategoryBrowser, "vivaldi": CategoryBrowser,
+	"preview": CategoryDocument, "word": CategoryDocument, "pages": CategoryDocument,
+	"acrobat": CategoryDocument, "notion": CategoryDocument, "obsidian": CategoryDocument,
+}
+
+var (
+	appWordPattern = regexp.MustCompile(`[\p{L}\p{N}]+(?: (?:studio code|text|studio))?`)
+	// user@host:~$, $, %, #, ❯, ➜, PS C:\> and >>> at the start of a line
+	promptPattern = regexp.MustCompile(`^(?:[\w.-]+@[\w.-]+[:\s]\S*\s*[$#%]|[$%#❯➜λ»]|>>>|PS [A-Za-z]:\\\S*>|[A-Za-z]:\\\S*>)(?:\s|$)`)
+	shellCommandPattern = regexp.MustCompile(`^(?:sudo|git|go|npm|npx|yarn|pnpm|docker|kubectl|helm|ls|cd|cat|grep|ssh|curl|make|brew|apt|pip|python3?|node|cargo|terraform|tail|ps|top|echo|export|vim?)\b`)
+	codeLinePattern = regexp.MustCompile(`^(?:func|def|class|import|from|package|return|const|let|var|if|else|for|while|switch|case|public|private|protected|static|fn|impl|struct|interface|type|#include|using|namespace|try|catch|async|await|export)\b|[{};]\s*$|\b\w+\([^)]*\)\s*[{:]?\s*$|:=|=>|->|(?:^|\s)//|/\*|^\s*#\s`)
+	identifierPattern = regexp.MustCompile(`\b(?:[a-z]+[A-Z]\w*|[a-z]+_[a-z_]+)\b`)
+	// 10:42, 10:42 AM, Today at 9:30
+	chatTimePattern = regexp.MustCompile(`(?i)(?:^|\s)(?:\d{1,2}:\d{2}\s?(?:am|pm)|today at|yesterday at|\d+\s?(?:min|h|m) ago)\b`)
+	chatWordPattern = regexp.MustCompile(`(?i)\b(?:reply|replied|replies|thread|reacted|typing|message #|new messages|joined the channel|sent an attachment|edited)\b|@\w+`)
+	browserWordPattern = regexp.MustCompile(`(?i)\b(?:new tab|bookmarks|search google or type a url|address bar|incognito|reader view|extensions|sign in|cookies|accept all|privacy policy|terms of service)\b`)
+)
+
+func (h *HeuristicClassifier) Classify(input ClassifierInput) Classification {
+	text := textStats(input.Text)
+	monospace := monospaceScore(input.Words)
+	dark, colourful := colourStats(input.Image)
+	app := appCategory(input.SourceApp + " " + input.WindowTitle)
+
+	scores := map[string]float64{
+		CategoryTerminal: 0.5*clamp01(text.prompts*4) +
+			0.15*clamp01(text.shellCommands*4) +
+			0.2*monospace +
+			0.15*dark,
+		CategoryCode: 0.45*clamp01(text.codeLines*2.5) +
+			0.15*clamp01(text.symbols*6) +
+			0.1*clamp01(text.identifiers*4) +
+			0.15*monospace +
+			0.15*dark,
+		CategoryChat: 0.5*clamp01(text.chatTimes*4) +
+			0.3*clamp01(text.chatWords*4) +
+			0.2*clamp01(text.shortLines),
+		CategoryBrowser: 0.45*clamp01(float64(len(input.Entities.URLs))/3) +
+			0.35*clamp01(text.browserWords*5) +
+			0.2*colourful,
+		// plain text on a light background, but only if it reads as prose:
+		// long lines full of stop words
+		CategoryDocument: clamp01(text.wordsPerLine/12) * clamp01(text.stopWords*2.5) *
+			(0.6 + 0.2*(1-clamp01(text.symbols*6)) + 0.2*(1-dark)),
+	}
+	if app != "" {
+		scores[app] += 0.6
+	}
+	if input.Ext == "pdf" {
+		scores[CategoryDocument] += 0.5
+	}
+	// a terminal running code still reads as a terminal when there are prompts
+	if text.prompts > 0 {
+		scores[CategoryCode] -= 0.1
+	}
+
+	best := Classification{Category: CategoryOther}
+	for _, category := range []string{CategoryTerminal, CategoryCode, CategoryChat, CategoryBrowser, CategoryDocument} {
+		if score := clamp01(scores[category]); score > best.Score {
+			best = Classification{Category: category, Score: score}
+		}
+	}
+	if best.Score < minCategoryScore {
+		return Classification{Category: CategoryOther, Score: best.Score}
+	}
+
+	return best
+}
+
+// classifierText are the share of lines or words with the features each
+// category is scored on
+type classifierText struct {
+	prompts float64
+	shellCommands float64
+	codeLines float64
+	identifiers float64
+	chatTimes float64
+	chatWords float64
+	shortLines float64
+	browserWords float64
+	wordsPerLine float64
+	stopWords float64
+	symbols float64
+}
+
+func textStats(text string) classifierText {
+	var stats classifierText
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return stats
+	}
+
+	words, stops, short := 0, 0, 0
+	for _, line := range lines {
+		if promptPattern.MatchString(line) {
+			stats.prompts++
+			command := promptPattern.ReplaceAllString(line, "")
+			if shellCommandPattern.MatchString(strings.TrimSpace(command)) {
+				stats.shellCommands++
+			}
+		} else if shellCommandPattern.MatchString(line) {
+			stats.shellCommands++
+		}
+		if codeLinePattern.MatchString(line) {
+			stats.codeLines++
+		}
+		if chatTimePattern.MatchString(line) {
+			stats.chatTimes++
+		}
+		if chatWordPattern.MatchString(line) {
+			stats.chatWords++
+		}
+		if browserWordPattern.MatchString(line) {
+			stats.browserWords++
+		}
+
+		lineWords := wordPattern.FindAllString(line, -1)
+		if len(lineWords) <= 6 {
+			short++
+		}
+		for _, w := range lineWords {
+			if _, ok := stopWords[strings.ToLower(w)]; ok {
+				stops++
+			}
+		}
+		words += len(lineWords)
+		stats.identifiers += float64(len(identifierPattern.FindAllString(line, -1)))
+	}
+
+	n := float64(len(lines))
+	stats.prompts /= n
+	stats.shellCommands /= n
+	stats.codeLines /= n
+	stats.chatTimes /= n
+	stats.chatWords /= n
+	stats.browserWords /= n
+	stats.shortLines = float64(short) / n
+	stats.wordsPerLine = float64(words) / n
+	if words > 0 {
+		stats.stopWords = float64(stops) / float64(words)
+		stats.identifiers /= float64(words)
+	}
+
+	symbols, chars := 0, 0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		chars++
+		if strings.ContainsRune("{}[]()<>;=_/\\|$#&*:", r) {
+			symbols++
+		}
+	}
+	if chars > 0 {
+		stats.symbols = float64(symbols) / float64(chars)
+	}
+
+	return stats
+}
+
+// monospaceScore is close to 1 when every word is about as wide per
+// character as any other, as in terminals and editors, and 0 for
+// proportional fonts or without word boxes
+func monospaceScore(words []WordBox) float64 {
+	widths := make([]float64, 0, len(words))
+	for _, w := range words {
+		n := utf8.RuneCountInString(w.Text)
+		if n < 3 || w.Width <= 0 || strings.IndexFunc(w.Text, unicode.IsLetter) < 0 {
+			continue
+		}
+		widths = append(widths, float64(w.Width)/float64(n))
+	}
+	if len(widths) < 5 {
+		return 0
+	}
+
+	var mean, variance float64
+	for _, w := range widths {
+		mean += w
+	}
+	mean /= float64(len(widths))
+	for _, w := range widths {
+		variance += (w - mean) * (w - mean)
+	}
+	cv := math.Sqrt(variance/float64(len(widths))) / mean
+
+	// monospaced words vary by a few percent from rounding and punctuation,
+	// proportional ones by a quarter or more
+	return clamp01((0.25 - cv) / 0.17)
+}
+
+// colourSamples is how many pixels per side colourStats looks at
+const colourSamples = 64
+
+// colourStats returns the share of dark pixels and how colourful the image
+// is, both from 0 to 1. Terminals and editors are mostly dark and a handful
+// of colours, web pages light with pictures.
+func colourStats(img image.Image) (float64, float64) {
+	if img == nil {
+		return 0, 0
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return 0, 0
+	}
+
+	dark, saturated, total := 0, 0, 0
+	buckets := make(map[uint32]struct{})
+	for y := 0; y < colourSamples; y++ {
+		for x := 0; x < colourSamples; x++ {
+			px := bounds.Min.X + x*bounds.Dx()/colourSamples
+			py := bounds.Min.Y + y*bounds.Dy()/colourSamples
+			r, g, b, _ := img.At(px, py).RGBA()
+			r, g, b = r>>8, g>>8, b>>8
+
+			luminance := 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			if luminance < 64 {
+				dark++
+			}
+			high, low := max(r, g, b), min(r, g, b)
+			if high > 0 && float64(high-low)/float64(high) > 0.4 && high > 64 {
+				saturated++
+			}
+			// 4 bits per channel
+			buckets[(r>>4)<<8|(g>>4)<<4|b>>4] = struct{}{}
+			total++
+		}
+	}
+
+	colourful := 0.5*clamp01(float64(len(buckets))/400) + 0.5*clamp01(float64(saturated)/float64(total)*4)
+
+	return float64(dark) / float64(total), colourful
+}
+
+// appCategory returns the category of the first known app named in source,
+// or ""
+func appCategory(source string) string {
+	source = strings.ToLower(source)
+	for _, word := range appWordPattern.FindAllString(source, -1) {
+		if category, ok := appCategories[word]; ok {
+			return category
+		}
+		if first, _, ok := strings.Cut(word, " "); ok {
+			if category, ok := appCategories[first]; ok {
+				return category
+			}
+		}
+	}
+
+	return ""
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,158 @@
+package screenshots
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+func fillImage(c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			img.Set(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func TestHeuristicClassifier(t *testing.T) {
+	dark := fillImage(color.RGBA{R: 30, G: 30, B: 35, A: 255})
+	light := fillImage(color.White)
+
+	tests := []struct {
+		name string
+		input ClassifierInput
+		expected string
+	}{
+		{
+			"Terminal",
+			ClassifierInput{
+				Text: "me@build:~/glimpse$ go test ./...\nok  glimpse/screenshots 0.3s\n$ git status\nOn branch main\n❯ kubectl get pods",
+				Image: dark,
+			},
+			CategoryTerminal,
+		},
+		{
+			"Code",
+			ClassifierInput{
+				Text: "func (s *Service) getUserConfigDir() (string, error) {\n\tdir, err := os.UserConfigDir()\n\tif err != nil {\n\t\treturn \"\", err\n\t}\n\treturn dir, nil\n}",
+				Image: dark,
+			},
+			CategoryCode,
+		},
+		{
+			"Chat",
+			ClassifierInput{
+				Text: "Dana 10:42 AM\nis the deploy stuck again?\nSam 10:44 AM\n@dana looks like it, rolling back\n3 replies\nToday at 11:02",
+				Image: light,
+			},
+			CategoryChat,
+		},
+		{
+			"Browser",
+			ClassifierInput{
+				Text: "New Tab\nhttps://grafana.example.com/d/api\nBookmarks\nAccept all cookies",
+				Entities: Entities{URLs: []string{"https://grafana.example.com/d/api"}},
+				Image: light,
+			},
+			CategoryBrowser,
+		},
+		{
+			"Document",
+			ClassifierInput{
+				Text: "The quarterly report shows that the number of incidents went down in the second half of the year, which is the result of the work the team did on alerting.\nIt is expected that this trend will continue as more of the services move to the new platform.",
+				Image: light,
+			},
+			CategoryDocument,
+		},
+		{
+			"Source app",
+			ClassifierInput{Text: "deploy", SourceApp: "Slack"},
+			CategoryChat,
+		},
+		{
+			"Window title",
+			ClassifierInput{Text: "deploy", WindowTitle: "main.go — glimpse — Visual Studio Code"},
+			CategoryCode,
+		},
+		{
+			"Too little to go on",
+			ClassifierInput{Text: "42"},
+			CategoryOther,
+		},
+	}
+
+	c := &HeuristicClassifier{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.Classify(tt.input)
+			if got.Category != tt.expected {
+				t.Errorf("expected %s, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMonospaceScore(t *testing.T) {
+	mono := []WordBox{
+		{Text: "kubectl", Width: 70}, {Text: "get", Width: 30}, {Text: "pods", Width: 40},
+		{Text: "running", Width: 70}, {Text: "restarts", Width: 80},
+	}
+	proportional := []WordBox{
+		{Text: "illicit", Width: 30}, {Text: "mammoth", Width: 90}, {Text: "will", Width: 16},
+		{Text: "women", Width: 60}, {Text: "little", Width: 28},
+	}
+
+	if score := monospaceScore(mono); score < 0.9 {
+		t.Errorf("expected a monospaced score, got %f", score)
+	}
+	if score := monospaceScore(proportional); score != 0 {
+		t.Errorf("expected a proportional score, got %f", score)
+	}
+	if score := monospaceScore(mono[:2]); score != 0 {
+		t.Errorf("expected too few words to count, got %f", score)
+	}
+}
+
+func TestColourStats(t *testing.T) {
+	dark, colourful := colourStats(fillImage(color.Black))
+	if dark != 1 || colourful > 0.01 {
+		t.Errorf("expected a dark plain image, got %f, %f", dark, colourful)
+	}
+
+	if dark, colourful := colourStats(nil); dark != 0 || colourful != 0 {
+		t.Errorf("expected nothing without an image, got %f, %f", dark, colourful)
+	}
+}
+
+func TestCategoryFilter(t *testing.T) {
+	docs := testDocs()
+	docs[0].Category = CategoryBrowser
+	docs[2].Category = CategoryTerminal
+	idx := newTestIndex(t, docs...)
+
+	if ids := searchIDs(t, idx, "is:Terminal"); len(ids) != 1 || ids[0] != docs[2].Path {
+		t.Errorf("expected the terminal screenshot, got %v", ids)
+	}
+	if ids := searchIDs(t, idx, "-category:browser"); len(ids) != 2 {
+		t.Errorf("expected everything but the browser screenshot, got %v", ids)
+	}
+	// the category isn't free text
+	if ids := searchIDs(t, idx, "terminal"); len(ids) != 0 {
+		t.Errorf("expected no text match, got %v", ids)
+	}
+
+	request := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
+	addFacets(request)
+	res, err := idx.Search(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := facetCount(collectFacets(res.Facets)[FacetCategory], CategoryBrowser); n != 1 {
+		t.Errorf("expected one browser screenshot, got %d", n)
+	}
+}
@@ -0,0 +1,192 @@
+package screenshots
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	regexpTokenizer "github.com/blevesearch/bleve/v2/analysis/tokenizer/regexp"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+// Names of the code analyzer and its parts in the index mapping
+const (
+	codeAnalyzer = "code"
+	codeTokenizer = "code_identifiers"
+	identifierPartsFilter = "identifier_parts"
+)
+
+// minLanguageScore is how many of a language's markers a screenshot needs
+// before its language is guessed
+const minLanguageScore = 2
+
+func init() {
+	err := registry.RegisterTokenFilter(identifierPartsFilter, func(config map[string]interface{}, cache *registry.Cache) (analysis.TokenFilter, error) {
+		return &identifierPartsTokenFilter{}, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// addCodeAnalyzer adds the analyzer of the code field to m: identifiers are
+// kept whole and also split into their words, so getUserConfigDir is found
+// by itself and by "user config dir"
+func addCodeAnalyzer(m *mapping.IndexMappingImpl) error {
+	err := m.AddCustomTokenizer(codeTokenizer, map[string]interface{}{
+		"type": regexpTokenizer.Name,
+		"regexp": `[\p{L}\p{N}_]+`,
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.AddCustomAnalyzer(codeAnalyzer, map[string]interface{}{
+		"type": custom.Name,
+		"tokenizer": codeTokenizer,
+		"token_filters": []string{identifierPartsFilter, lowercase.Name},
+	})
+}
+
+// identifierPartsTokenFilter follows every identifier made of several words
+// with the words, split on underscores and camelCase
+type identifierPartsTokenFilter struct{}
+
+func (f *identifierPartsTokenFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	output := make(analysis.TokenStream, 0, len(input))
+
+	position := 1
+	for _, token := range input {
+		parts := identifierParts(string(token.Term))
+
+		whole := *token
+		whole.Position = position
+		output = append(output, &whole)
+		if len(parts) < 2 {
+			position++
+			continue
+		}
+
+		// the whole identifier shares its position with its first word, so
+		// phrases of words still line up
+		offset := token.Start
+		for _, part := range parts {
+			start := offset + strings.Index(string(token.Term[offset-token.Start:]), part)
+			output = append(output, &analysis.Token{
+				Term: []byte(part),
+				Start: start,
+				End: start + len(part),
+				Position: position,
+				Type: token.Type,
+			})
+			offset = start + len(part)
+			position++
+		}
+	}
+
+	return output
+}
+
+// identifierParts splits an identifier into its words: on underscores,
+// where lower case turns upper case, before the last capital of an acronym
+// (HTTPServer is HTTP and Server) and between letters and digits
+func identifierParts(identifier string) []string {
+	parts := make([]string, 0)
+	runes := []rune(identifier)
+
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			parts = append(parts, string(runes[start:end]))
+		}
+		start = end
+	}
+
+	for i, r := range runes {
+		if r == '_' {
+			flush(i)
+			start = i + 1
+			continue
+		}
+		if i == 0 || runes[i-1] == '_' {
+			continue
+		}
+
+		prev := runes[i-1]
+		switch {
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush(i)
+		case unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			flush(i)
+		case unicode.IsDigit(r) != unicode.IsDigit(prev):
+			flush(i)
+		}
+	}
+	flush(len(runes))
+
+	return parts
+}
+
+// languageMarkers are keywords and idioms that give a language away, each
+// match counts once
+var languageMarkers = map[string]*regexp.Regexp{
+	"go": regexp.MustCompile(`\bfunc\b|\bpackage \w+|:=|\berr != nil\b|\bfmt\.|\bchan\b|\bdefer\b`),
+	"python": regexp.MustCompile(`\bdef \w+\(|\bself\b|\belif\b|\bNone\b|\bimport \w+$|\bfrom \w+ import\b|\b__\w+__\b`),
+	"javascript": regexp.MustCompile(`\bfunction\b|\bconst \w+ =|\blet \w+ =|=>|\bconsole\.log\b|\brequire\(|===`),
+	"typescript": regexp.MustCompile(`\binterface \w+ \{|: (?:string|number|boolean)\b|\bexport type\b|\bas const\b`),
+	"java": regexp.MustCompile(`\bpublic (?:static )?(?:class|void)\b|\bSystem\.out\b|\bprivate final\b|@Override\b|\bextends\b`),
+	"rust": regexp.MustCompile(`\bfn \w+|\blet mut\b|\bimpl\b|\bpub fn\b|::new\(|\bmatch \w+ \{|&str\b`),
+	"c": regexp.MustCompile(`#include\b|\bprintf\(|\bmalloc\(|\bint main\(|\bstd::|->`),
+	"ruby": regexp.MustCompile(`\bend$|\bputs\b|\bdo \|\w+\||\battr_accessor\b|\brequire '`),
+	"sql": regexp.MustCompile(`(?i)\bselect\b.+\bfrom\b|\binsert into\b|\bcreate table\b|\bwhere\b.+=|\bgroup by\b|\bjoin\b.+\bon\b`),
+}
+
+// guessLanguage returns the language of code whose markers appear on the
+// most lines, or "" if none is convincing
+func guessLanguage(code string) string {
+	scores := make(map[string]int)
+	for _, line := range strings.Split(code, "\n") {
+		for language, pattern := range languageMarkers {
+			if pattern.MatchString(line) {
+				scores[language]++
+			}
+		}
+	}
+	// TypeScript is a superset of JavaScript, its markers decide
+	if scores["typescript"] > 0 {
+		scores["typescript"] += scores["javascript"]
+	}
+
+	languages := make([]string, 0, len(scores))
+	for language := range scores {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(a, b int) bool {
+		if scores[languages[a]] != scores[languages[b]] {
+			return scores[languages[a]] > scores[languages[b]]
+		}
+		return languages[a] < languages[b]
+	})
+
+	if len(languages) == 0 || scores[languages[0]] < minLanguageScore {
+		return ""
+	}
+
+	return languages[0]
+}
+
+// indexCode fills in the code field and language of screenshots classified
+// as code, from the OCR'd text before normalisation dropped its brackets
+func indexCode(doc *ScreenshotDoc, text string) {
+	if doc.Category != CategoryCode {
+		return
+	}
+
+	doc.Code = strings.TrimSpace(text)
+	doc.Language = guessLanguage(doc.Code)
+}
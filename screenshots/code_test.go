@@ -0,0 +1,86 @@
+package screenshots
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIdentifierParts(t *testing.T) {
+	tests := []struct {
+		identifier string
+		expected []string
+	}{
+		{"getUserConfigDir", []string{"get", "User", "Config", "Dir"}},
+		{"user_config_dir", []string{"user", "config", "dir"}},
+		{"HTTPServer", []string{"HTTP", "Server"}},
+		{"__init__", []string{"init"}},
+		{"utf8Decode", []string{"utf", "8", "Decode"}},
+		{"config", []string{"config"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.identifier, func(t *testing.T) {
+			if got := identifierParts(tt.identifier); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGuessLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		expected string
+	}{
+		{"Go", "package main\nfunc main() {\n\tdir, err := os.UserConfigDir()\n\tif err != nil {", "go"},
+		{"Python", "def load(self, path):\n    if path is None:\n        return\n    elif self.cache:", "python"},
+		{"TypeScript", "interface Result {\n  path: string,\n}\nconst hits = results.map(r => r.path)", "typescript"},
+		{"SQL", "SELECT path, size FROM screenshots\nWHERE size > 1000\nGROUP BY dir", "sql"},
+		{"Not enough", "return x", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := guessLanguage(tt.code); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCodeSearch(t *testing.T) {
+	docs := testDocs()
+	docs[0].Category = CategoryCode
+	indexCode(&docs[0], "func getUserConfigDir() (string, error) {\n\tdir, err := os.UserConfigDir()\n\treturn dir, err\n}\nvar max_retry_count = 3")
+	// only code screenshots get a code field
+	docs[1].Category = CategoryTerminal
+	indexCode(&docs[1], "$ go run ./cmd/getUserConfigDir")
+	idx := newTestIndex(t, docs...)
+
+	if docs[0].Language != "go" || docs[1].Code != "" {
+		t.Fatalf("expected only the code screenshot to be indexed as go code, got %q and %q", docs[0].Language, docs[1].Code)
+	}
+
+	tests := []struct {
+		input string
+		expected int
+	}{
+		{"getUserConfigDir", 1},
+		{"user config dir", 1},
+		{`"max retry count"`, 1},
+		{"code:max_retry_count", 1},
+		{"code:retry", 1},
+		{"lang:Go", 1},
+		{"code:rust", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ids := searchIDs(t, idx, tt.input)
+			if len(ids) != tt.expected || (tt.expected == 1 && ids[0] != docs[0].Path) {
+				t.Errorf("expected %d match, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package screenshots
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+// WriteDiagnostics writes a plain text report to paste into a bug report:
+// the platform, what the index holds and the last lines of the log at
+// logPath, if there is one. The text and images of screenshots are never
+// part of it, the paths in the log are.
+func WriteDiagnostics(w io.Writer, service Service, logPath string, lines int) error {
+	fmt.Fprintln(w, "Glimpse diagnostics")
+	fmt.Fprintf(w, "generated  %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(w, "platform   %s/%s, %s\n", runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+	if service != nil {
+		stats, err := service.Stats()
+		if err != nil {
+			fmt.Fprintf(w, "index      error: %v\n", err)
+		} else {
+			fmt.Fprintf(w, "index      %s, %d bytes\n", stats.IndexPath, stats.IndexBytes)
+			fmt.Fprintf(w, "documents  %d from %d files\n", stats.Documents, stats.Files)
+		}
+		fmt.Fprintf(w, "semantic   %t\n", service.SemanticSearchAvailable())
+	}
+
+	if logPath == "" {
+		fmt.Fprintln(w, "log        stderr only")
+		return nil
+	}
+	fmt.Fprintf(w, "log        %s\n", logPath)
+
+	recent, err := RecentLogs(logPath, lines)
+	if err != nil {
+		fmt.Fprintf(w, "\nerror reading the log: %v\n", err)
+		return nil
+	}
+
+	fmt.Fprintf(w, "\nlast %d log lines:\n", len(recent))
+	for _, line := range recent {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package screenshots
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LogFileName)
+	if err := os.WriteFile(path, []byte("level=INFO msg=\"scan started\" files=2\nlevel=WARN msg=\"file failed to index\"\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := &ScreenshotService{
+		Indexer: &Indexer{
+			appName: "Glimpse",
+			blevePath: "screenshots.bleve",
+			o: &mockOsProvider{userConfigDir: t.TempDir()},
+			idx: newTestIndex(t, ScreenshotDoc{Path: "/home/me/Desktop/one.png", Text: "secret text"}),
+		},
+		ctx: context.Background(),
+	}
+
+	var b strings.Builder
+	if err := WriteDiagnostics(&b, s, path, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report := b.String()
+
+	for _, expected := range []string{"documents  1 from 1 files", "log        " + path, "last 1 log lines:\nlevel=WARN"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected %q in the report, got:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "scan started") || strings.Contains(report, "secret text") {
+		t.Errorf("expected only the last line and no text, got:\n%s", report)
+	}
+
+	b.Reset()
+	WriteDiagnostics(&b, nil, "", 10)
+	if !strings.Contains(b.String(), "stderr only") {
+		t.Errorf("expected no log, got:\n%s", b.String())
+	}
+}
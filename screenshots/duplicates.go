@@ -0,0 +1,569 @@
+package screenshots
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+const (
+	// defaultNearDistance is the hash distance up to which two images count
+	// as near duplicates, recompressed or rescaled copies stay well below it
+	defaultNearDistance = 4
+	maxUndoBatches = 20
+)
+
+const (
+	KeepNewest = "newest"
+	KeepLargest = "largest"
+)
+
+var ErrNoUndoBatch = errors.New("nothing to undo")
+
+// DuplicateOptions tune what counts as a duplicate
+type DuplicateOptions struct {
+	// MaxDistance is the perceptual hash distance of near duplicates,
+	// 0 uses the default of 4
+	MaxDistance int `json:"maxDistance"`
+	// ExactOnly leaves out near duplicates
+	ExactOnly bool `json:"exactOnly"`
+}
+
+type DuplicateFile struct {
+	Path string `json:"path"`
+	Size int64 `json:"size"`
+	Modified time.Time `json:"modified"`
+	ContentHash string `json:"contentHash,omitempty"`
+
+	phash string
+}
+
+// DuplicateGroup is a set of files that are copies of each other, newest
+// first. Exact groups are byte for byte copies, the others look alike.
+type DuplicateGroup struct {
+	Exact bool `json:"exact"`
+	Files []DuplicateFile `json:"files"`
+	// ReclaimableBytes is what trashing all but the largest file frees
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+	// MaxDistance is the perceptual hash distance near duplicates were
+	// found with, ResolveDuplicates checks the files against it again
+	MaxDistance int `json:"maxDistance,omitempty"`
+}
+
+type DuplicateReport struct {
+	Groups []DuplicateGroup `json:"groups"`
+	ReclaimableBytes int64 `json:"reclaimableBytes"`
+}
+
+// TrashedFile records where a duplicate was moved so it can be restored
+type TrashedFile struct {
+	Path string `json:"path"`
+	TrashPath string `json:"trashPath,omitempty"`
+	Size int64 `json:"size"`
+}
+
+// DuplicateResolution describes what ResolveDuplicates or UndoDuplicates did,
+// or in a dry run would have done
+type DuplicateResolution struct {
+	BatchID string `json:"batchId,omitempty"`
+	DryRun bool `json:"dryRun"`
+	Kept []string `json:"kept"`
+	Trashed []TrashedFile `json:"trashed"`
+	Restored []string `json:"restored,omitempty"`
+	FreedBytes int64 `json:"freedBytes"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// DuplicateBatch is one run of ResolveDuplicates in the undo log
+type DuplicateBatch struct {
+	ID string `json:"id"`
+	Time time.Time `json:"time"`
+	Trashed []TrashedFile `json:"trashed"`
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FindDuplicates groups the indexed files that share a content hash, and
+// unless opts.ExactOnly the ones whose perceptual hashes are close. Pages
+// and frames of documents and recordings are left out.
+func (s *ScreenshotService) FindDuplicates(opts DuplicateOptions) (*DuplicateReport, error) {
+	maxDistance := opts.MaxDistance
+	if maxDistance <= 0 {
+		maxDistance = defaultNearDistance
+	}
+	if maxDistance > maxHashDistance {
+		return nil, fmt.Errorf("max distance must be at most %d", maxHashDistance)
+	}
+
+	err := s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	files := make(map[string]*DuplicateFile)
+	err = s.eachDocument([]string{"content_hash", "phash", "size", "modified", "parent"}, func(hit *search.DocumentMatch) error {
+		if parent, ok := hit.Fields["parent"].(string); ok && parent != "" {
+			return nil
+		}
+
+		f := &DuplicateFile{Path: hit.ID}
+		f.ContentHash, _ = hit.Fields["content_hash"].(string)
+		f.phash, _ = hit.Fields["phash"].(string)
+		if size, ok := hit.Fields["size"].(float64); ok {
+			f.Size = int64(size)
+		}
+		if modified, ok := hit.Fields["modified"].(string); ok {
+			f.Modified, _ = time.Parse(time.RFC3339, modified)
+		}
+		files[f.Path] = f
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading documents: %v", err)
+	}
+
+	groups := newUnionFind()
+	byContent := make(map[string]string)
+	tree := bkTree{}
+	hashes := make(map[string]uint64)
+
+	for path, f := range files {
+		if f.ContentHash != "" {
+			if first, ok := byContent[f.ContentHash]; ok {
+				groups.union(first, path)
+			} else {
+				byContent[f.ContentHash] = path
+			}
+		}
+
+		if !opts.ExactOnly && f.phash != "" {
+			if hash, err := parseHash(f.phash); err == nil {
+				hashes[path] = hash
+				tree.insert(hash, path)
+			}
+		}
+	}
+
+	for path, hash := range hashes {
+		for _, m := range tree.search(hash, maxDistance) {
+			groups.union(path, m.id)
+		}
+	}
+
+	return newDuplicateReport(files, groups, maxDistance), nil
+}
+
+func newDuplicateReport(files map[string]*DuplicateFile, groups *unionFind, maxDistance int) *DuplicateReport {
+	members := make(map[string][]DuplicateFile)
+	for path, f := range files {
+		root := groups.find(path)
+		members[root] = append(members[root], *f)
+	}
+
+	report := &DuplicateReport{Groups: make([]DuplicateGroup, 0)}
+	for _, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+
+		sort.Slice(group, func(a, b int) bool {
+			if !group[a].Modified.Equal(group[b].Modified) {
+				return group[a].Modified.After(group[b].Modified)
+			}
+			return group[a].Path < group[b].Path
+		})
+
+		g := DuplicateGroup{Exact: true, Files: group}
+		var total, largest int64
+		for _, f := range group {
+			if f.ContentHash == "" || f.ContentHash != group[0].ContentHash {
+				g.Exact = false
+			}
+			total += f.Size
+			largest = max(largest, f.Size)
+		}
+		g.ReclaimableBytes = total - largest
+		if !g.Exact {
+			g.MaxDistance = maxDistance
+		}
+
+		report.Groups = append(report.Groups, g)
+		report.ReclaimableBytes += g.ReclaimableBytes
+	}
+
+	sort.Slice(report.Groups, func(a, b int) bool {
+		if report.Groups[a].ReclaimableBytes != report.Groups[b].ReclaimableBytes {
+			return report.Groups[a].ReclaimableBytes > report.Groups[b].ReclaimableBytes
+		}
+		return report.Groups[a].Files[0].Path < report.Groups[b].Files[0].Path
+	})
+
+	return report
+}
+
+// ResolveDuplicates keeps the newest or largest file of each group and moves
+// the others to the trash, recording the moves in the undo log. Files are
+// checked on disk first: missing files are skipped, and so are files whose
+// content changed since the report or that are no longer a copy of the kept
+// file, by content hash for exact groups and by perceptual hash for the
+// others. A dry run only reports what would be trashed.
+func (s *ScreenshotService) ResolveDuplicates(groups []DuplicateGroup, keep string, dryRun bool) (*DuplicateResolution, error) {
+	if keep != KeepNewest && keep != KeepLargest {
+		return nil, fmt.Errorf("unknown keep policy %q", keep)
+	}
+
+	err := s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	resolution := &DuplicateResolution{
+		DryRun: dryRun,
+		Kept: make([]string, 0),
+		Trashed: make([]TrashedFile, 0),
+	}
+
+	for _, group := range groups {
+		existing := make([]DuplicateFile, 0, len(group.Files))
+		hashes := make(map[string]uint64)
+		for _, f := range group.Files {
+			info, err := os.Stat(f.Path)
+			if err != nil {
+				continue
+			}
+
+			// only trash files that are still what was reported
+			data, err := os.ReadFile(f.Path)
+			if err != nil {
+				resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: %v", f.Path, err))
+				continue
+			}
+			current := contentHash(data)
+			if (group.Exact || f.ContentHash != "") && current != f.ContentHash {
+				resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: changed since the report, skipped", f.Path))
+				continue
+			}
+			if !group.Exact {
+				hash, ok := perceptualHash(data)
+				if !ok {
+					resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: not an image, skipped", f.Path))
+					continue
+				}
+				hashes[f.Path] = hash
+			}
+
+			existing = append(existing, DuplicateFile{Path: f.Path, Size: info.Size(), Modified: info.ModTime(), ContentHash: current})
+		}
+		if len(existing) < 2 {
+			continue
+		}
+
+		kept := pickKept(existing, keep)
+		resolution.Kept = append(resolution.Kept, kept.Path)
+
+		for _, f := range existing {
+			if f.Path == kept.Path {
+				continue
+			}
+
+			if !isDuplicateOf(group, f, kept, hashes) {
+				resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: not a duplicate of %s, skipped", f.Path, kept.Path))
+				continue
+			}
+
+			trashed := TrashedFile{Path: f.Path, Size: f.Size}
+			if !dryRun {
+				trashed.TrashPath, err = moveToTrash(f.Path)
+				if err != nil {
+					resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: %v", f.Path, err))
+					continue
+				}
+				if err := s.forget(f.Path); err != nil {
+					resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: trashed, but still in the index: %v", f.Path, err))
+				}
+			}
+
+			resolution.Trashed = append(resolution.Trashed, trashed)
+			resolution.FreedBytes += f.Size
+		}
+	}
+
+	if dryRun || len(resolution.Trashed) == 0 {
+		return resolution, nil
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	resolution.BatchID = id
+
+	batch := DuplicateBatch{ID: id, Time: time.Now(), Trashed: resolution.Trashed}
+	if err := s.undo.add(batch); err != nil {
+		// the files are already in the trash, say so rather than fail
+		resolution.Errors = append(resolution.Errors, fmt.Sprintf("error writing undo log: %v", err))
+		s.logger().Error("error writing undo log", "batch", id, "error", err)
+	}
+	s.logger().Info("duplicates moved to the trash", "batch", id, "files", len(resolution.Trashed), "bytes", resolution.FreedBytes, "errors", len(resolution.Errors))
+
+	return resolution, nil
+}
+
+// isDuplicateOf checks f against the kept file of its group again, groups
+// come back from the frontend and near groups can chain files that are
+// further apart than the distance they were found with
+func isDuplicateOf(group DuplicateGroup, f DuplicateFile, kept DuplicateFile, hashes map[string]uint64) bool {
+	if f.ContentHash == kept.ContentHash {
+		return true
+	}
+	if group.Exact {
+		return false
+	}
+
+	maxDistance := group.MaxDistance
+	if maxDistance <= 0 {
+		maxDistance = defaultNearDistance
+	}
+	return hammingDistance(hashes[f.Path], hashes[kept.Path]) <= min(maxDistance, maxHashDistance)
+}
+
+// pickKept returns the file to keep, ties go to the newest file
+func pickKept(files []DuplicateFile, keep string) DuplicateFile {
+	kept := files[0]
+	for _, f := range files[1:] {
+		newer := f.Modified.After(kept.Modified)
+		if keep == KeepLargest && f.Size != kept.Size {
+			if f.Size > kept.Size {
+				kept = f
+			}
+			continue
+		}
+		if newer {
+			kept = f
+		}
+	}
+
+	return kept
+}
+
+// forget removes a trashed file from the index
+func (s *ScreenshotService) forget(path string) error {
+	s.similar.remove(path)
+	return s.Indexer.Delete(path)
+}
+
+// UndoDuplicates moves the files of a ResolveDuplicates batch back from the
+// trash and indexes them again. An empty batchID undoes the latest batch.
+func (s *ScreenshotService) UndoDuplicates(batchID string) (*DuplicateResolution, error) {
+	batch, err := s.undo.get(batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	resolution := &DuplicateResolution{
+		BatchID: batch.ID,
+		Kept: make([]string, 0),
+		Trashed: make([]TrashedFile, 0),
+		Restored: make([]string, 0),
+	}
+
+	remaining := make([]TrashedFile, 0)
+	for _, f := range batch.Trashed {
+		if err := restoreFromTrash(f.TrashPath, f.Path); err != nil {
+			resolution.Errors = append(resolution.Errors, fmt.Sprintf("%s: %v", f.Path, err))
+			remaining = append(remaining, f)
+			continue
+		}
+		resolution.Restored = append(resolution.Restored, f.Path)
+	}
+
+	// restored files are indexed like any other scan, failures are listed
+	// in the summary
+	if len(resolution.Restored) > 0 {
+		summary, err := s.ScanFiles(resolution.Restored)
+		if summary != nil {
+			for _, scanErr := range summary.Errors {
+				resolution.Errors = append(resolution.Errors, scanErr.Error())
+			}
+		}
+		if err != nil && !errors.Is(err, ErrScanFailed) {
+			resolution.Errors = append(resolution.Errors, fmt.Sprintf("error indexing restored files: %v", err))
+		}
+	}
+
+	// files that couldn't be restored stay in the log to try again
+	if err := s.undo.replace(batch.ID, remaining); err != nil {
+		resolution.Errors = append(resolution.Errors, fmt.Sprintf("error writing undo log: %v", err))
+		s.logger().Error("error writing undo log", "batch", batch.ID, "error", err)
+	}
+	s.logger().Info("duplicates restored from the trash", "batch", batch.ID, "files", len(resolution.Restored), "errors", len(resolution.Errors))
+
+	return resolution, nil
+}
+
+// unionFind groups paths that were found to be duplicates of each other
+type unionFind struct {
+	parent map[string]string
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[string]string)}
+}
+
+func (u *unionFind) find(x string) string {
+	root := x
+	for {
+		p, ok := u.parent[root]
+		if !ok || p == root {
+			break
+		}
+		root = p
+	}
+
+	// point everything on the way straight at the root
+	for x != root {
+		next := u.parent[x]
+		u.parent[x] = root
+		x = next
+	}
+
+	return root
+}
+
+func (u *unionFind) union(a string, b string) {
+	rootA, rootB := u.find(a), u.find(b)
+	if rootA != rootB {
+		u.parent[rootB] = rootA
+	}
+}
+
+// undoLog keeps the latest ResolveDuplicates batches in a JSON file next to
+// the index
+type undoLog struct {
+	appName string
+	fileName string
+	o osProvider
+
+	mu sync.Mutex
+}
+
+func newUndoLog() *undoLog {
+	return &undoLog{
+		appName: "Glimpse",
+		fileName: "duplicates_undo.json",
+		o: &realOsProvider{},
+	}
+}
+
+func (l *undoLog) read() ([]DuplicateBatch, string, error) {
+	path, err := appDataPath(l.o, l.appName, l.fileName)
+	if err != nil {
+		return nil, "", fmt.Errorf("error finding undo log: %v", err)
+	}
+
+	batches := make([]DuplicateBatch, 0)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return batches, path, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading undo log: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &batches); err != nil {
+		return nil, "", fmt.Errorf("error parsing undo log: %v", err)
+	}
+
+	return batches, path, nil
+}
+
+func (l *undoLog) write(path string, batches []DuplicateBatch) error {
+	data, err := json.MarshalIndent(batches, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (l *undoLog) add(batch DuplicateBatch) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batches, path, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	batches = append(batches, batch)
+	if len(batches) > maxUndoBatches {
+		batches = batches[len(batches)-maxUndoBatches:]
+	}
+
+	return l.write(path, batches)
+}
+
+// get returns the batch with id, or the latest one if id is empty
+func (l *undoLog) get(id string) (*DuplicateBatch, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batches, _, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(batches) - 1; i >= 0; i-- {
+		if id == "" || batches[i].ID == id {
+			return &batches[i], nil
+		}
+	}
+
+	return nil, ErrNoUndoBatch
+}
+
+// replace sets the files left to restore of a batch, dropping the batch
+// once there are none
+func (l *undoLog) replace(id string, trashed []TrashedFile) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batches, path, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	kept := make([]DuplicateBatch, 0, len(batches))
+	for _, b := range batches {
+		if b.ID == id {
+			if len(trashed) == 0 {
+				continue
+			}
+			b.Trashed = trashed
+		}
+		kept = append(kept, b)
+	}
+
+	return l.write(path, kept)
+}
@@ -0,0 +1,286 @@
+package screenshots
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestUndoLog(t *testing.T) *undoLog {
+	configDir := t.TempDir()
+	// the mock doesn't create directories
+	if err := os.MkdirAll(filepath.Join(configDir, "Glimpse"), 0755); err != nil {
+		t.Fatalf("error creating config dir: %v", err)
+	}
+
+	return &undoLog{
+		appName: "Glimpse",
+		fileName: "duplicates_undo.json",
+		o: &mockOsProvider{userConfigDir: configDir},
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	docs := testDocs()
+	// grafana and notes are the same file, trace only looks like them
+	docs[0].ContentHash = contentHash([]byte("grafana"))
+	docs[0].PHash = formatHash(0xf0f0f0f0f0f0f0f0)
+	docs[0].Size = 3000
+	docs[0].Modified = time.Date(2025, 3, 6, 0, 0, 0, 0, time.UTC)
+	docs[1].ContentHash = docs[0].ContentHash
+	docs[1].PHash = docs[0].PHash
+	docs[1].Size = 3000
+	docs[1].Modified = time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+	docs[2].ContentHash = contentHash([]byte("trace"))
+	docs[2].PHash = formatHash(0xf0f0f0f0f0f0f0f3)
+	docs[2].Size = 1000
+	// pages of a document are never reported
+	docs = append(docs, ScreenshotDoc{
+		Path: "/home/me/Desktop/report.pdf#page=1",
+		Parent: "/home/me/Desktop/report.pdf",
+		PHash: docs[0].PHash,
+		Size: 9000,
+	})
+
+	s := &ScreenshotService{
+		Indexer: &Indexer{idx: newTestIndex(t, docs...)},
+		ctx: context.Background(),
+	}
+
+	t.Run("Exact and near copies", func(t *testing.T) {
+		report, err := s.FindDuplicates(DuplicateOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(report.Groups) != 1 || len(report.Groups[0].Files) != 3 {
+			t.Fatalf("expected one group of three, got %+v", report.Groups)
+		}
+
+		group := report.Groups[0]
+		if group.Exact {
+			t.Error("expected a group with a look alike not to be exact")
+		}
+		if group.ReclaimableBytes != 4000 || report.ReclaimableBytes != 4000 {
+			t.Errorf("expected 4000 reclaimable bytes, got %d and %d", group.ReclaimableBytes, report.ReclaimableBytes)
+		}
+		for _, f := range group.Files {
+			if f.Path == docs[3].Path {
+				t.Errorf("expected pages to be left out, got %s", f.Path)
+			}
+		}
+	})
+
+	t.Run("Exact only", func(t *testing.T) {
+		report, err := s.FindDuplicates(DuplicateOptions{ExactOnly: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(report.Groups) != 1 || !report.Groups[0].Exact || len(report.Groups[0].Files) != 2 {
+			t.Fatalf("expected one exact pair, got %+v", report.Groups)
+		}
+		// newest first
+		if report.Groups[0].Files[0].Path != docs[0].Path {
+			t.Errorf("expected %s first, got %+v", docs[0].Path, report.Groups[0].Files)
+		}
+	})
+
+	t.Run("Closer than the distance", func(t *testing.T) {
+		report, _ := s.FindDuplicates(DuplicateOptions{MaxDistance: 1})
+		if len(report.Groups) != 1 || len(report.Groups[0].Files) != 2 {
+			t.Errorf("expected trace to be too far off, got %+v", report.Groups)
+		}
+	})
+
+	t.Run("Invalid distance", func(t *testing.T) {
+		if _, err := s.FindDuplicates(DuplicateOptions{MaxDistance: 65}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestPickKept(t *testing.T) {
+	now := time.Now()
+	files := []DuplicateFile{
+		{Path: "old-big.png", Size: 500, Modified: now.Add(-time.Hour)},
+		{Path: "new-small.png", Size: 100, Modified: now},
+		{Path: "new-big.png", Size: 500, Modified: now},
+	}
+
+	if kept := pickKept(files, KeepNewest); kept.Modified != now {
+		t.Errorf("expected a newest file, got %s", kept.Path)
+	}
+	if kept := pickKept(files, KeepLargest); kept.Path != "new-big.png" {
+		t.Errorf("expected the newest of the largest files, got %s", kept.Path)
+	}
+}
+
+func TestResolveDuplicates(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only the XDG trash can be moved to a temporary directory")
+	}
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	write := func(name string, data string, modified time.Time) ScreenshotDoc {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+		os.Chtimes(path, modified, modified)
+		return ScreenshotDoc{Path: path, ContentHash: contentHash([]byte(data)), Size: int64(len(data)), Modified: modified}
+	}
+
+	now := time.Now().Truncate(time.Second)
+	original := write("original.png", "pixels", now.Add(-time.Hour))
+	copied := write("copy.png", "pixels", now)
+	edited := write("edited.png", "pixels", now.Add(-2*time.Hour))
+
+	s := &ScreenshotService{
+		OCR: NewMockOCR(nil, "ocr", &mockFileSystem{tempFile: &mockFile{name: "ocr-helper"}}, &mockCmdRunner{output: []byte("build failed")}),
+		Indexer: &Indexer{idx: newTestIndex(t, original, copied, edited)},
+		Saved: newTestSavedSearches(t),
+		ctx: context.Background(),
+		undo: newTestUndoLog(t),
+	}
+
+	report, err := s.FindDuplicates(DuplicateOptions{ExactOnly: true})
+	if err != nil || len(report.Groups) != 1 {
+		t.Fatalf("expected one group, got %+v, %v", report, err)
+	}
+	// changed after the report was made
+	os.WriteFile(edited.Path, []byte("other pixels"), 0644)
+
+	t.Run("Dry run", func(t *testing.T) {
+		resolution, err := s.ResolveDuplicates(report.Groups, KeepNewest, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resolution.Trashed) != 1 || resolution.Trashed[0].Path != original.Path || resolution.BatchID != "" || len(resolution.Errors) != 1 {
+			t.Errorf("expected only the original to be listed, got %+v", resolution)
+		}
+		if _, err := os.Stat(original.Path); err != nil {
+			t.Errorf("expected the file to stay, got %v", err)
+		}
+	})
+
+	t.Run("Move to trash", func(t *testing.T) {
+		resolution, err := s.ResolveDuplicates(report.Groups, KeepNewest, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resolution.Kept) != 1 || resolution.Kept[0] != copied.Path {
+			t.Errorf("expected the copy to be kept, got %v", resolution.Kept)
+		}
+		if len(resolution.Trashed) != 1 || resolution.FreedBytes != 6 || len(resolution.Errors) != 1 {
+			t.Fatalf("expected the original trashed and the edited file skipped, got %+v", resolution)
+		}
+		if _, err := os.Stat(original.Path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected the original to be gone, got %v", err)
+		}
+		if _, err := os.Stat(resolution.Trashed[0].TrashPath); err != nil {
+			t.Errorf("expected the original in the trash, got %v", err)
+		}
+
+		// edited.png stays in the index with its old hash until the next scan
+		report, _ := s.FindDuplicates(DuplicateOptions{ExactOnly: true})
+		for _, f := range report.Groups[0].Files {
+			if f.Path == original.Path {
+				t.Errorf("expected the original to be removed from the index, got %+v", report.Groups)
+			}
+		}
+	})
+
+	t.Run("Undo", func(t *testing.T) {
+		resolution, err := s.UndoDuplicates("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resolution.Restored) != 1 || len(resolution.Errors) != 0 {
+			t.Fatalf("expected the original back, got %+v", resolution)
+		}
+		if data, err := os.ReadFile(original.Path); err != nil || string(data) != "pixels" {
+			t.Errorf("expected the original back in place, got %q, %v", data, err)
+		}
+
+		report, _ := s.FindDuplicates(DuplicateOptions{ExactOnly: true})
+		if len(report.Groups) != 1 || len(report.Groups[0].Files) != 3 {
+			t.Errorf("expected the original to be indexed again, got %+v", report.Groups)
+		}
+
+		if _, err := s.UndoDuplicates(""); !errors.Is(err, ErrNoUndoBatch) {
+			t.Errorf("expected ErrNoUndoBatch, got %v", err)
+		}
+	})
+
+	t.Run("Unknown policy", func(t *testing.T) {
+		if _, err := s.ResolveDuplicates(report.Groups, "oldest", true); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestResolveNearDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte, modified time.Time) DuplicateFile {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+		os.Chtimes(path, modified, modified)
+		return DuplicateFile{Path: path, ContentHash: contentHash(data), Size: int64(len(data)), Modified: modified}
+	}
+
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, newTestDashboard(640, 400, []int{30, 80, 50, 90}), &jpeg.Options{Quality: 60})
+
+	now := time.Now().Truncate(time.Second)
+	original := write("original.png", encodePNG(t, newTestDashboard(320, 200, []int{30, 80, 50, 90})), now)
+	copied := write("copy.jpg", buf.Bytes(), now.Add(-time.Hour))
+	other := write("other.png", encodePNG(t, newTestDashboard(320, 200, []int{90, 10, 95, 5, 70, 20})), now.Add(-2*time.Hour))
+
+	s := &ScreenshotService{
+		Indexer: &Indexer{idx: newTestIndex(t)},
+		ctx: context.Background(),
+	}
+
+	// a group edited by the client, the other screen doesn't look alike
+	groups := []DuplicateGroup{{Files: []DuplicateFile{original, copied, other}, MaxDistance: 4}}
+	resolution, err := s.ResolveDuplicates(groups, KeepNewest, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resolution.Kept) != 1 || resolution.Kept[0] != original.Path {
+		t.Errorf("expected the original to be kept, got %v", resolution.Kept)
+	}
+	if len(resolution.Trashed) != 1 || resolution.Trashed[0].Path != copied.Path {
+		t.Errorf("expected only the copy to be trashed, got %+v", resolution.Trashed)
+	}
+	if len(resolution.Errors) != 1 || !strings.Contains(resolution.Errors[0], "other.png: not a duplicate") {
+		t.Errorf("expected the other screen to be skipped, got %v", resolution.Errors)
+	}
+}
+
+func TestUndoLogKeepsLatestBatches(t *testing.T) {
+	l := newTestUndoLog(t)
+	for i := 0; i < maxUndoBatches+5; i++ {
+		id := string(rune('a' + i))
+		if err := l.add(DuplicateBatch{ID: id, Trashed: []TrashedFile{{Path: id}}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := l.get("a"); !errors.Is(err, ErrNoUndoBatch) {
+		t.Errorf("expected the oldest batch to be dropped, got %v", err)
+	}
+	latest, err := l.get("")
+	if err != nil || latest.ID != string(rune('a'+maxUndoBatches+4)) {
+		t.Errorf("expected the latest batch, got %+v, %v", latest, err)
+	}
+}
@@ -0,0 +1,300 @@
+package screenshots
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// embeddingDims is the size of the vectors in the index, which matches
+	// the common 300 dimension fastText and GloVe models
+	embeddingDims = 300
+	defaultMaxWords = 50000
+	defaultSemanticWeight = 1.0
+)
+
+const (
+	SearchKeyword = "keyword"
+	SearchSemantic = "semantic"
+	SearchHybrid = "hybrid"
+)
+
+var ErrSemanticUnavailable = errors.New("semantic search needs a build with vector support and a word vector model")
+
+// Embedder turns text into a vector whose distance to other vectors says how
+// close their meaning is. A nil vector means the text can't be embedded.
+type Embedder interface {
+	Embed(text string) ([]float32, error)
+}
+
+// NewEmbedder loads the word vectors saved as word-vectors.vec in the app
+// config dir. It returns nil, which turns the embedding stage off, if there
+// is no model or the index can't store vectors.
+func NewEmbedder() Embedder {
+	if !vectorSearch {
+		return nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+
+	path := filepath.Join(configDir, "Glimpse", "word-vectors.vec")
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	return NewWordVectors(path)
+}
+
+// WordVectors embeds text as the weighted average of the vectors of its
+// words, read from a fastText .vec text file. It runs on the CPU and loads
+// the file on first use.
+type WordVectors struct {
+	path string
+	// MaxWords caps how many of the most frequent words are loaded
+	MaxWords int
+
+	once sync.Once
+	loadErr error
+	vectors map[string]wordVector
+}
+
+type wordVector struct {
+	values []float32
+	// rank is the position of the word in the file, which lists words from
+	// most to least frequent
+	rank int
+}
+
+func NewWordVectors(path string) *WordVectors {
+	return &WordVectors{
+		path: path,
+		MaxWords: defaultMaxWords,
+	}
+}
+
+func (w *WordVectors) load() error {
+	w.once.Do(func() {
+		f, err := os.Open(w.path)
+		if err != nil {
+			w.loadErr = fmt.Errorf("error opening word vectors: %v", err)
+			return
+		}
+		defer f.Close()
+
+		w.vectors, w.loadErr = parseWordVectors(f, w.MaxWords)
+	})
+
+	return w.loadErr
+}
+
+// parseWordVectors reads lines of "<word> <v1> ... <v300>", skipping the
+// "<count> <dims>" header fastText puts first
+func parseWordVectors(r io.Reader, maxWords int) (map[string]wordVector, error) {
+	vectors := make(map[string]wordVector)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() && len(vectors) < maxWords {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if line == 1 && len(fields) == 2 {
+			continue
+		}
+		if len(fields) != embeddingDims+1 {
+			return nil, fmt.Errorf("word vectors line %d: expected %d dimensions, got %d", line, embeddingDims, len(fields)-1)
+		}
+
+		values := make([]float32, embeddingDims)
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, fmt.Errorf("word vectors line %d: %v", line, err)
+			}
+			values[i] = float32(v)
+		}
+
+		word := strings.ToLower(fields[0])
+		if _, ok := vectors[word]; !ok {
+			vectors[word] = wordVector{values: values, rank: len(vectors)}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading word vectors: %v", err)
+	}
+
+	return vectors, nil
+}
+
+// Embed averages the vectors of the known words in text. Frequent words like
+// "the" say little about meaning, so words are weighted by the log of their
+// frequency rank.
+func (w *WordVectors) Embed(text string) ([]float32, error) {
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+
+	sum := make([]float64, embeddingDims)
+	known := 0
+	for _, word := range embeddingWords(text) {
+		vec, ok := w.vectors[word]
+		if !ok {
+			continue
+		}
+
+		weight := math.Log(float64(vec.rank) + 2)
+		for i, v := range vec.values {
+			sum[i] += weight * float64(v)
+		}
+		known++
+	}
+
+	if known == 0 {
+		return nil, nil
+	}
+
+	return normalizeVector(sum), nil
+}
+
+func embeddingWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// normalizeVector scales v to unit length so cosine similarity only depends
+// on direction
+func normalizeVector(v []float64) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(x / norm)
+	}
+
+	return out
+}
+
+// embed fills in the embedding of doc when the embedding stage is on. A
+// document is still worth indexing without one.
+func (s *ScreenshotService) embed(doc *ScreenshotDoc) {
+	if s.Embedder == nil {
+		return
+	}
+
+	vec, err := s.Embedder.Embed(doc.Text)
+	if err != nil {
+		s.logger().Debug("indexing without an embedding", "path", doc.Path, "error", err)
+		return
+	}
+	if vec != nil {
+		doc.Embedding = vec
+	}
+}
+
+// SemanticSearchAvailable reports whether the semantic and hybrid search
+// modes can be used
+func (s *ScreenshotService) SemanticSearchAvailable() bool {
+	return vectorSearch && s.Embedder != nil
+}
+
+// addSemantic adds a kNN clause for the free text of the query. Hybrid search
+// adds its similarity to the text score of each hit, semantic search only
+// keeps the field clauses of the query. Both filter the kNN matches with the
+// field clauses and the facet filters, see knnFilter.
+func (s *ScreenshotService) addSemantic(request *bleve.SearchRequest, input string, parser *queryParser, opts SearchOptions) error {
+	if !s.SemanticSearchAvailable() {
+		return ErrSemanticUnavailable
+	}
+
+	text, filter, err := parser.semanticParts(input)
+	if err != nil {
+		return err
+	}
+
+	var vec []float32
+	if text != "" {
+		vec, err = s.Embedder.Embed(text)
+		if err != nil {
+			return fmt.Errorf("error embedding query: %v", err)
+		}
+	}
+
+	if vec == nil {
+		// hybrid search falls back to the text score alone
+		if opts.Mode == SearchHybrid {
+			return nil
+		}
+		return &QueryError{Position: 0, Message: "semantic search needs words it knows"}
+	}
+
+	weight := opts.SemanticWeight
+	if weight <= 0 {
+		weight = defaultSemanticWeight
+	}
+	k := int64(request.From + request.Size)
+
+	filter, err = knnFilter(filter, opts.Filters)
+	if err != nil {
+		return err
+	}
+
+	if opts.Mode == SearchHybrid {
+		addKNN(request, vec, k, weight, filter)
+		return nil
+	}
+
+	// hits are the union of the query and kNN matches, so the query must
+	// not match anything on its own
+	request.Query = bleve.NewMatchNoneQuery()
+	addKNN(request, vec, k, weight, filter)
+
+	return nil
+}
+
+// knnFilter returns the filter of the kNN matches: the field clauses of the
+// query and the facet filters. bleve returns kNN matches next to the query
+// hits, so without it ext:, dir: or an exclusion wouldn't apply to them.
+func knnFilter(clauses query.Query, filters map[string][]string) (query.Query, error) {
+	if clauses == nil {
+		clauses = bleve.NewMatchAllQuery()
+	}
+
+	return filterQuery(clauses, filters)
+}
+
+// isSemantic reports whether mode needs the embedding stage, rejecting
+// unknown modes
+func isSemantic(mode string) (bool, error) {
+	switch mode {
+	case "", SearchKeyword:
+		return false, nil
+	case SearchSemantic, SearchHybrid:
+		return true, nil
+	}
+
+	return false, fmt.Errorf("unknown search mode %q", mode)
+}
@@ -0,0 +1,20 @@
+//go:build !vectors
+
+package screenshots
+
+import (
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// vectorSearch is set when bleve is built with faiss vector indexes
+const vectorSearch = false
+
+// addEmbeddingMapping keeps embeddings out of the index, where they would
+// otherwise be indexed as 300 numeric fields
+func addEmbeddingMapping(doc *mapping.DocumentMapping) {
+	doc.AddSubDocumentMapping("embedding", bleve.NewDocumentDisabledMapping())
+}
+
+func addKNN(request *bleve.SearchRequest, vec []float32, k int64, boost float64, filter query.Query) {}
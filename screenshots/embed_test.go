@@ -0,0 +1,239 @@
+package screenshots
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// writeTestVectors writes a .vec file where each word points along the given
+// dimensions
+func writeTestVectors(t *testing.T, words map[string][]int, order ...string) string {
+	t.Helper()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %d\n", len(order), embeddingDims)
+	for _, word := range order {
+		values := make([]string, embeddingDims)
+		for i := range values {
+			values[i] = "0"
+		}
+		for _, dim := range words[word] {
+			values[dim] = "1"
+		}
+		fmt.Fprintf(&b, "%s %s\n", word, strings.Join(values, " "))
+	}
+
+	path := filepath.Join(t.TempDir(), "word-vectors.vec")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatalf("error writing vectors: %v", err)
+	}
+
+	return path
+}
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestParseWordVectors(t *testing.T) {
+	t.Run("Header and max words", func(t *testing.T) {
+		path := writeTestVectors(t, map[string][]int{"the": {0}, "Database": {1}, "slow": {2}}, "the", "Database", "slow")
+		f, _ := os.Open(path)
+		defer f.Close()
+
+		vectors, err := parseWordVectors(f, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(vectors) != 2 {
+			t.Errorf("expected 2 words, got %d", len(vectors))
+		}
+		if v, ok := vectors["database"]; !ok || v.rank != 1 || v.values[1] != 1 {
+			t.Errorf("expected lowercased database at rank 1, got %+v", v)
+		}
+	})
+
+	t.Run("Wrong dimensions", func(t *testing.T) {
+		_, err := parseWordVectors(strings.NewReader("slow 0.1 0.2 0.3\n"), 10)
+		if err == nil || !strings.Contains(err.Error(), "expected 300 dimensions") {
+			t.Errorf("expected dimension error, got %v", err)
+		}
+	})
+
+	t.Run("Invalid number", func(t *testing.T) {
+		_, err := parseWordVectors(strings.NewReader("slow"+strings.Repeat(" x", embeddingDims)+"\n"), 10)
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestWordVectorsEmbed(t *testing.T) {
+	path := writeTestVectors(t, map[string][]int{
+		"database": {0},
+		"postgres": {0, 1},
+		"slow": {2},
+		"latency": {2, 3},
+		"invoice": {4},
+	}, "database", "postgres", "slow", "latency", "invoice")
+	w := NewWordVectors(path)
+
+	query, err := w.Embed("the database being slow")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	related, _ := w.Embed("Postgres latency spike")
+	unrelated, _ := w.Embed("invoice #42")
+
+	if math.Abs(cosine(query, query)-1) > 1e-5 {
+		t.Errorf("expected a unit vector, got length %f", cosine(query, query))
+	}
+	if cosine(query, related) <= cosine(query, unrelated) {
+		t.Errorf("expected related text to be closer, got %f <= %f", cosine(query, related), cosine(query, unrelated))
+	}
+
+	vec, err := w.Embed("nothing known here")
+	if err != nil || vec != nil {
+		t.Errorf("expected no vector for unknown words, got %v, %v", vec, err)
+	}
+
+	missing := NewWordVectors(filepath.Join(t.TempDir(), "missing.vec"))
+	if _, err := missing.Embed("database"); err == nil {
+		t.Error("expected an error for a missing model")
+	}
+}
+
+func TestSemanticParts(t *testing.T) {
+	p := &queryParser{homeDir: "/home/me"}
+
+	text, filter, err := p.semanticParts(`database "being slow" ext:png -tag:draft`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "database being slow" {
+		t.Errorf("expected free text, got %q", text)
+	}
+	boolQuery, ok := filter.(*query.BooleanQuery)
+	if !ok || boolQuery.Must == nil || boolQuery.MustNot == nil {
+		t.Errorf("expected ext and negated tag clauses, got %#v", filter)
+	}
+
+	text, filter, _ = p.semanticParts("database slow")
+	if text != "database slow" || filter != nil {
+		t.Errorf("expected only free text, got %q and %v", text, filter)
+	}
+
+	if _, _, err := p.semanticParts("width:big"); err == nil {
+		t.Error("expected an error for an invalid field value")
+	}
+
+	// unknown fields are free text to embed
+	text, filter, _ = p.semanticParts("TypeError: undefined")
+	if text != "TypeError: undefined" || filter != nil {
+		t.Errorf("expected only free text, got %q and %v", text, filter)
+	}
+}
+
+func TestKNNFilter(t *testing.T) {
+	idx := newTestIndex(t, testDocs()...)
+	p := &queryParser{homeDir: "/home/me"}
+
+	matches := func(input string, filters map[string][]string) []string {
+		t.Helper()
+
+		_, clauses, err := p.semanticParts(input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		filter, err := knnFilter(clauses, filters)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res, err := idx.Search(bleve.NewSearchRequest(filter))
+		if err != nil {
+			t.Fatalf("unexpected search error: %v", err)
+		}
+
+		var ids []string
+		for _, hit := range res.Hits {
+			ids = append(ids, filepath.Base(hit.ID))
+		}
+		sort.Strings(ids)
+
+		return ids
+	}
+
+	// a hybrid search for ext:png must not get the jpg back as a kNN match
+	if ids := matches("dashboard ext:png", nil); !reflect.DeepEqual(ids, []string{"grafana.png", "trace.png"}) {
+		t.Errorf("expected only the png screenshots, got %v", ids)
+	}
+	if ids := matches("dashboard", map[string][]string{"ext": {"jpg"}}); !reflect.DeepEqual(ids, []string{"notes.jpg"}) {
+		t.Errorf("expected only the jpg screenshot, got %v", ids)
+	}
+	if ids := matches("dashboard", nil); len(ids) != 3 {
+		t.Errorf("expected every screenshot, got %v", ids)
+	}
+}
+
+func TestIsSemantic(t *testing.T) {
+	for mode, expected := range map[string]bool{"": false, SearchKeyword: false, SearchSemantic: true, SearchHybrid: true} {
+		semantic, err := isSemantic(mode)
+		if err != nil || semantic != expected {
+			t.Errorf("mode %q: expected %v, got %v, %v", mode, expected, semantic, err)
+		}
+	}
+
+	if _, err := isSemantic("magic"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestSemanticSearchUnavailable(t *testing.T) {
+	if vectorSearch {
+		t.Skip("built with vector support")
+	}
+
+	s := &ScreenshotService{Embedder: NewWordVectors("unused.vec")}
+	if s.SemanticSearchAvailable() {
+		t.Error("expected semantic search to need vector support")
+	}
+
+	err := s.addSemantic(nil, "database", &queryParser{}, SearchOptions{Mode: SearchSemantic})
+	if !errors.Is(err, ErrSemanticUnavailable) {
+		t.Errorf("expected ErrSemanticUnavailable, got %v", err)
+	}
+}
+
+func TestEmbeddingNotIndexedWithoutVectors(t *testing.T) {
+	if vectorSearch {
+		t.Skip("built with vector support")
+	}
+
+	doc := testDocs()[0]
+	doc.Embedding = []float32{0.6, 0.8}
+	idx := newTestIndex(t, doc)
+
+	fields, err := idx.Fields()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, field := range fields {
+		if strings.HasPrefix(field, "embedding") {
+			t.Errorf("expected embedding to be left out of the index, got field %q", field)
+		}
+	}
+}
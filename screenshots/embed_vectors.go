@@ -0,0 +1,31 @@
+//go:build vectors
+
+package screenshots
+
+import (
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+// vectorSearch is set when bleve is built with faiss vector indexes
+const vectorSearch = true
+
+func addEmbeddingMapping(doc *mapping.DocumentMapping) {
+	embedding := mapping.NewVectorFieldMapping()
+	embedding.Dims = embeddingDims
+	embedding.Similarity = index.CosineSimilarity
+	embedding.VectorIndexOptimizedFor = index.IndexOptimizedForRecall
+
+	doc.AddFieldMappingsAt("embedding", embedding)
+}
+
+func addKNN(request *bleve.SearchRequest, vec []float32, k int64, boost float64, filter query.Query) {
+	if filter != nil {
+		request.AddKNNWithFilter("embedding", vec, k, boost, filter)
+		return
+	}
+
+	request.AddKNN("embedding", vec, k, boost)
+}
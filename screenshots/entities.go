@@ -0,0 +1,331 @@
+package screenshots
+
+import (
+	"net/http"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Entity types, each has a query field of the same name and can be tested
+// with has:, e.g. has:url or ip:10.0.0.5
+const (
+	EntityURL = "url"
+	EntityEmail = "email"
+	EntityIP = "ip"
+	EntityHash = "sha"
+	EntityStatus = "status"
+	EntityUUID = "uuid"
+	EntityTime = "time"
+	EntityTicket = "ticket"
+)
+
+// entityFields maps each entity type to the keyword field holding its values
+var entityFields = map[string]string{
+	EntityURL: "urls",
+	EntityEmail: "emails",
+	EntityIP: "ips",
+	EntityHash: "hashes",
+	EntityStatus: "status_codes",
+	EntityUUID: "uuids",
+	EntityTime: "timestamps",
+	EntityTicket: "tickets",
+}
+
+// Entities are the structured tokens found in the text of a screenshot.
+// Values are normalised so they can be looked up exactly: lower case, and
+// timestamps as YYYY-MM-DD or YYYY-MM-DDTHH:MM:SS in the time shown.
+type Entities struct {
+	URLs []string `json:"urls,omitempty"`
+	Emails []string `json:"emails,omitempty"`
+	IPs []string `json:"ips,omitempty"`
+	// Hashes are hex strings that look like commit SHAs or digests
+	Hashes []string `json:"hashes,omitempty"`
+	// StatusCodes are HTTP status codes, only taken from a context that
+	// makes them one, like "HTTP/1.1 502" or "404 Not Found"
+	StatusCodes []string `json:"status_codes,omitempty"`
+	UUIDs []string `json:"uuids,omitempty"`
+	Timestamps []string `json:"timestamps,omitempty"`
+	// Tickets are issue keys like OPS-1234 or owner/repo#56
+	Tickets []string `json:"tickets,omitempty"`
+	// EntityTypes lists the types found, for has:
+	EntityTypes []string `json:"entity_types,omitempty"`
+}
+
+var (
+	uuidPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	urlPattern = regexp.MustCompile(`(?i)\b(?:(?:https?|ftp|file|wss?)://|www\.)[^\s<>"'\x60]+`)
+	emailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b`)
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// not part of a longer word, so std::vector isn't an address
+	ipv6Pattern = regexp.MustCompile(`(?i)(?:^|[^\w:])((?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4})(?:$|[^\w:])`)
+	hashPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{7,64}\b`)
+	// a code after HTTP/x, status or code, or followed by its reason phrase
+	statusContextPattern = regexp.MustCompile(`(?i)\b(?:HTTP/\d(?:\.\d)?|status(?:[ _-]?code)?|code)[ \t]*[:=]?[ \t]*([1-5]\d\d)\b`)
+	statusReasonPattern = regexp.MustCompile(`\b([1-5]\d\d)[ \t]+([A-Za-z][A-Za-z' -]+)`)
+	jiraPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]{1,9}-\d{1,6}\b`)
+	githubIssuePattern = regexp.MustCompile(`\b[\w.-]+/[\w.-]+#\d+\b`)
+)
+
+// timestampFormats are tried in order, so a date and time isn't also
+// picked up as a bare date
+var timestampFormats = []struct {
+	pattern *regexp.Regexp
+	layout string
+	dateOnly bool
+}{
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}\b`), "2006-01-02T15:04:05", false},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}\b`), "2006-01-02T15:04", false},
+	// common log format, as in nginx and apache access logs
+	{regexp.MustCompile(`\b\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2}\b`), "02/Jan/2006:15:04:05", false},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`), "2006-01-02", true},
+	{regexp.MustCompile(`\b\d{4}/\d{2}/\d{2}\b`), "2006/01/02", true},
+}
+
+// notTickets are prefixes of names like UTF-8 and SHA-256 that look like
+// issue keys
+var notTickets = map[string]struct{}{
+	"UTF": {}, "ISO": {}, "SHA": {}, "MD": {}, "RFC": {}, "CVE": {}, "HTTP": {},
+	"TLS": {}, "SSL": {}, "IPV": {}, "X": {}, "AES": {}, "RSA": {}, "GPT": {},
+}
+
+// ExtractEntities finds the URLs, email addresses, IP addresses, hashes,
+// HTTP status codes, UUIDs, timestamps and ticket IDs in text
+func ExtractEntities(text string) Entities {
+	var e Entities
+
+	e.URLs = findEntities(urlPattern, text, func(url string) string {
+		return strings.ToLower(strings.TrimRight(url, ".,;:!?)]}'\""))
+	})
+	// UUIDs, URLs and emails are blanked out once found, so their parts
+	// aren't read as hashes, IPs or tickets as well
+	e.UUIDs = findEntities(uuidPattern, text, strings.ToLower)
+	text = blankOut(uuidPattern, text)
+
+	// IPs and tickets inside URLs are still worth finding, hashes in paths
+	// aren't, they are mostly cache busters
+	e.IPs = findIPs(text)
+	e.Tickets = findTickets(text)
+	text = blankOut(urlPattern, text)
+
+	e.Emails = findEntities(emailPattern, text, strings.ToLower)
+	text = blankOut(emailPattern, text)
+
+	e.Timestamps, text = findTimestamps(text)
+	e.StatusCodes = findStatusCodes(text)
+	e.Hashes = findHashes(text)
+
+	types := map[string][]string{
+		EntityURL: e.URLs,
+		EntityEmail: e.Emails,
+		EntityIP: e.IPs,
+		EntityHash: e.Hashes,
+		EntityStatus: e.StatusCodes,
+		EntityUUID: e.UUIDs,
+		EntityTime: e.Timestamps,
+		EntityTicket: e.Tickets,
+	}
+	for name, values := range types {
+		if len(values) > 0 {
+			e.EntityTypes = append(e.EntityTypes, name)
+		}
+	}
+	sort.Strings(e.EntityTypes)
+
+	return e
+}
+
+// findEntities returns the distinct matches of pattern in text, normalised
+// by normalize, in the order they first appear
+func findEntities(pattern *regexp.Regexp, text string, normalize func(string) string) []string {
+	return uniqueEntities(pattern.FindAllString(text, -1), normalize)
+}
+
+func uniqueEntities(values []string, normalize func(string) string) []string {
+	seen := make(map[string]struct{}, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		v = normalize(v)
+		if _, ok := seen[v]; ok || v == "" {
+			continue
+		}
+		seen[v] = struct{}{}
+		unique = append(unique, v)
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	return unique
+}
+
+// blankOut replaces the matches of pattern with spaces, keeping the offsets
+// of the rest of the text
+func blankOut(pattern *regexp.Regexp, text string) string {
+	return pattern.ReplaceAllStringFunc(text, func(match string) string {
+		return strings.Repeat(" ", len(match))
+	})
+}
+
+func findIPs(text string) []string {
+	candidates := ipv4Pattern.FindAllString(text, -1)
+	for _, m := range ipv6Pattern.FindAllStringSubmatch(text, -1) {
+		// times like 14:02:11 have too few groups to be an address, so
+		// only full or compressed addresses count
+		c := m[1]
+		if strings.Contains(c, "::") || strings.Count(c, ":") == 7 {
+			candidates = append(candidates, c)
+		}
+	}
+
+	return uniqueEntities(candidates, func(ip string) string {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return ""
+		}
+		return addr.String()
+	})
+}
+
+func findTickets(text string) []string {
+	candidates := make([]string, 0)
+	for _, key := range jiraPattern.FindAllString(text, -1) {
+		project, _, _ := strings.Cut(key, "-")
+		if _, ok := notTickets[strings.TrimRight(project, "0123456789")]; !ok {
+			candidates = append(candidates, key)
+		}
+	}
+	candidates = append(candidates, githubIssuePattern.FindAllString(text, -1)...)
+
+	return uniqueEntities(candidates, strings.ToLower)
+}
+
+// findTimestamps returns the timestamps in text and the text with them
+// blanked out, so their digits aren't read as anything else
+func findTimestamps(text string) ([]string, string) {
+	candidates := make([]string, 0)
+	for _, format := range timestampFormats {
+		text = format.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			t, err := time.Parse(format.layout, strings.Replace(match, " ", "T", 1))
+			if err != nil {
+				// not a valid date, like 2025-13-45
+				return match
+			}
+
+			if format.dateOnly {
+				candidates = append(candidates, t.Format("2006-01-02"))
+			} else {
+				candidates = append(candidates, t.Format("2006-01-02T15:04:05"))
+			}
+			return strings.Repeat(" ", len(match))
+		})
+	}
+
+	return uniqueEntities(candidates, normalizeEntity(EntityTime)), text
+}
+
+func findStatusCodes(text string) []string {
+	candidates := make([]string, 0)
+	for _, m := range statusContextPattern.FindAllStringSubmatch(text, -1) {
+		candidates = append(candidates, m[1])
+	}
+	for _, m := range statusReasonPattern.FindAllStringSubmatch(text, -1) {
+		code, _ := strconv.Atoi(m[1])
+		reason := http.StatusText(code)
+		if reason != "" && strings.HasPrefix(strings.ToLower(m[2]), strings.ToLower(reason)) {
+			candidates = append(candidates, m[1])
+		}
+	}
+
+	return uniqueEntities(candidates, strings.ToLower)
+}
+
+func findHashes(text string) []string {
+	candidates := make([]string, 0)
+	for _, h := range hashPattern.FindAllString(text, -1) {
+		// words like "defaced" are hex too, and long numbers aren't hashes
+		if strings.IndexAny(h, "0123456789") >= 0 && strings.IndexAny(strings.ToLower(h), "abcdef") >= 0 {
+			candidates = append(candidates, h)
+		}
+	}
+
+	return uniqueEntities(candidates, strings.ToLower)
+}
+
+// normalizeEntity returns how values of an entity type are normalised, so
+// query values can be looked up the way they were indexed
+func normalizeEntity(name string) func(string) string {
+	if name == EntityTime {
+		// the T between date and time
+		return strings.ToUpper
+	}
+
+	return strings.ToLower
+}
+
+// entitiesFromFields reads the entities of a search hit from its stored
+// fields
+func entitiesFromFields(fields map[string]interface{}) Entities {
+	return Entities{
+		URLs: storedStrings(fields["urls"]),
+		Emails: storedStrings(fields["emails"]),
+		IPs: storedStrings(fields["ips"]),
+		Hashes: storedStrings(fields["hashes"]),
+		StatusCodes: storedStrings(fields["status_codes"]),
+		UUIDs: storedStrings(fields["uuids"]),
+		Timestamps: storedStrings(fields["timestamps"]),
+		Tickets: storedStrings(fields["tickets"]),
+		EntityTypes: storedStrings(fields["entity_types"]),
+	}
+}
+
+// entityQuery matches documents with an entity of type name. URLs match
+// anywhere, hashes and timestamps by their start so short SHAs and dates
+// find the full value, the rest exactly. * in value is a wildcard, and so
+// is ?, which in a URL still matches itself.
+func entityQuery(name string, value string) query.Query {
+	field := entityFields[name]
+	value = normalizeEntity(name)(value)
+
+	if strings.Contains(value, "*") {
+		q := bleve.NewWildcardQuery(value)
+		q.SetField(field)
+		return q
+	}
+
+	switch name {
+	case EntityURL:
+		q := bleve.NewWildcardQuery("*" + value + "*")
+		q.SetField(field)
+		return q
+	case EntityHash, EntityTime:
+		q := bleve.NewPrefixQuery(value)
+		q.SetField(field)
+		return q
+	case EntityIP:
+		if addr, err := netip.ParseAddr(value); err == nil {
+			value = addr.String()
+		}
+	}
+
+	q := bleve.NewTermQuery(value)
+	q.SetField(field)
+	return q
+}
+
+
+func entityNames() []string {
+	names := make([]string, 0, len(entityFields))
+	for name := range entityFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
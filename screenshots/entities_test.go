@@ -0,0 +1,99 @@
+package screenshots
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const testEntityText = `curl -v https://api.example.com/v1/users?id=7, see www.example.org.
+HTTP/1.1 502 Bad Gateway from 10.0.0.5 and fe80::1ff:fe23:4567:890a
+GET /health 404 Not Found at 2025-03-05 14:02:11, retry 2025-03-06
+[05/Mar/2025:14:02:11 +0000] request 550E8400-E29B-41D4-A716-446655440000
+commit 3f2a9c1 fixes OPS-1234 and acme/api#56, mail Oncall@Example.com
+std::vector uses UTF-8 and SHA-256, defaced 12345678 at 14:02:11`
+
+func TestExtractEntities(t *testing.T) {
+	e := ExtractEntities(testEntityText)
+
+	tests := []struct {
+		name string
+		got []string
+		expected []string
+	}{
+		{"URLs", e.URLs, []string{"https://api.example.com/v1/users?id=7", "www.example.org"}},
+		{"Emails", e.Emails, []string{"oncall@example.com"}},
+		{"IPs", e.IPs, []string{"10.0.0.5", "fe80::1ff:fe23:4567:890a"}},
+		{"Hashes", e.Hashes, []string{"3f2a9c1"}},
+		{"Status codes", e.StatusCodes, []string{"502", "404"}},
+		{"UUIDs", e.UUIDs, []string{"550e8400-e29b-41d4-a716-446655440000"}},
+		{"Timestamps", e.Timestamps, []string{"2025-03-05T14:02:11", "2025-03-06"}},
+		{"Tickets", e.Tickets, []string{"ops-1234", "acme/api#56"}},
+		{"Types", e.EntityTypes, []string{"email", "ip", "sha", "status", "ticket", "time", "url", "uuid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, tt.got)
+			}
+		})
+	}
+
+	t.Run("Nothing found", func(t *testing.T) {
+		if e := ExtractEntities("retry in 5 minutes"); !reflect.DeepEqual(e, Entities{}) {
+			t.Errorf("expected no entities, got %+v", e)
+		}
+	})
+}
+
+func TestEntityQueries(t *testing.T) {
+	docs := testDocs()
+	docs[0].Entities = ExtractEntities(testEntityText)
+	docs[1].Entities = ExtractEntities("ssh 10.0.0.50 on 2025-04-01")
+	idx := newTestIndex(t, docs...)
+
+	tests := []struct {
+		input string
+		expected []string
+	}{
+		{"has:url", []string{docs[0].Path}},
+		{"has:IP", []string{docs[0].Path, docs[1].Path}},
+		{"ip:10.0.0.5", []string{docs[0].Path}},
+		{"ip:10.0.0.*", []string{docs[0].Path, docs[1].Path}},
+		{"url:example.com/v1", []string{docs[0].Path}},
+		{"email:ONCALL@example.com", []string{docs[0].Path}},
+		{"sha:3f2a9c", []string{docs[0].Path}},
+		{"status:502", []string{docs[0].Path}},
+		{"time:2025-03", []string{docs[0].Path}},
+		{"time:2025-03-05t14", []string{docs[0].Path}},
+		{"ticket:OPS-1234", []string{docs[0].Path}},
+		{"uuid:550e8400-e29b-41d4-a716-446655440000", []string{docs[0].Path}},
+		{"-has:time", []string{docs[2].Path}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := searchIDs(t, idx, tt.input); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Unknown entity", func(t *testing.T) {
+		if _, err := (&queryParser{}).parse("has:phone"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("Stored", func(t *testing.T) {
+		s := &ScreenshotService{Indexer: &Indexer{idx: idx}, ctx: context.Background()}
+		doc, err := s.storedDoc(docs[1].Path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(doc.Entities, docs[1].Entities) {
+			t.Errorf("expected %+v, got %+v", docs[1].Entities, doc.Entities)
+		}
+	})
+}
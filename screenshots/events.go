@@ -0,0 +1,52 @@
+package screenshots
+
+// EventSink receives the events the service sends while it scans and
+// searches: result:found, scan:progress, scan:done, search:found,
+// search:error and collection:count. The app forwards them to its frontend,
+// the CLI prints them. Scans emit from several goroutines at once.
+type EventSink interface {
+	Emit(name string, data interface{})
+}
+
+// Event is an event sent to a ChannelEvents
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// ChannelEvents sends every event to C. Emit blocks until the event is
+// received or buffered, so C has to be drained while the service runs.
+type ChannelEvents struct {
+	C chan Event
+}
+
+// NewChannelEvents returns a ChannelEvents buffering size events
+func NewChannelEvents(size int) *ChannelEvents {
+	return &ChannelEvents{C: make(chan Event, size)}
+}
+
+func (c *ChannelEvents) Emit(name string, data interface{}) {
+	c.C <- Event{Name: name, Data: data}
+}
+
+// MultiEvents sends every event to each of its sinks in turn
+type MultiEvents []EventSink
+
+func (m MultiEvents) Emit(name string, data interface{}) {
+	for _, sink := range m {
+		sink.Emit(name, data)
+	}
+}
+
+// NoEvents drops every event
+type NoEvents struct{}
+
+func (NoEvents) Emit(name string, data interface{}) {}
+
+func (s *ScreenshotService) emit(name string, data interface{}) {
+	if s.Events == nil {
+		return
+	}
+
+	s.Events.Emit(name, data)
+}
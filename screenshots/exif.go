@@ -0,0 +1,159 @@
+package screenshots
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	tiffTypeByte = 1
+	tiffTypeASCII = 2
+	tiffTypeShort = 3
+	tiffTypeLong = 4
+	tiffTypeRational = 5
+
+	exifIFDPointer = 0x8769
+)
+
+// exifTags are the tags we keep from IFD0 and the Exif sub-IFD, keyed by the
+// name they are indexed under
+var exifTags = map[uint16]string{
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0112: "Orientation",
+	0x011A: "XResolution",
+	0x011B: "YResolution",
+	0x0128: "ResolutionUnit",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x9003: "DateTimeOriginal",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+}
+
+var errInvalidEXIF = errors.New("invalid exif data")
+
+// parseEXIF reads the tags listed in exifTags out of a TIFF-structured EXIF
+// block, as found in a JPEG APP1 segment or a PNG eXIf chunk
+func parseEXIF(data []byte) (map[string]string, error) {
+	if len(data) < 8 {
+		return nil, errInvalidEXIF
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errInvalidEXIF
+	}
+
+	tags := make(map[string]string)
+
+	offset := order.Uint32(data[4:8])
+	exifOffset, err := readIFD(data, order, offset, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if exifOffset != 0 {
+		if _, err := readIFD(data, order, exifOffset, tags); err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// readIFD reads a single image file directory into tags and returns the
+// offset of the Exif sub-IFD if IFD0 points to one
+func readIFD(data []byte, order binary.ByteOrder, offset uint32, tags map[string]string) (uint32, error) {
+	if int(offset)+2 > len(data) {
+		return 0, errInvalidEXIF
+	}
+
+	count := int(order.Uint16(data[offset:]))
+	start := int(offset) + 2
+	if start+count*12 > len(data) {
+		return 0, errInvalidEXIF
+	}
+
+	var exifOffset uint32
+	for i := 0; i < count; i++ {
+		entry := data[start+i*12 : start+(i+1)*12]
+		tag := order.Uint16(entry[0:2])
+
+		if tag == exifIFDPointer {
+			exifOffset = order.Uint32(entry[8:12])
+			continue
+		}
+
+		name, ok := exifTags[tag]
+		if !ok {
+			continue
+		}
+
+		value, err := readTIFFValue(data, order, entry)
+		if err != nil {
+			continue
+		}
+		if value != "" {
+			tags[name] = value
+		}
+	}
+
+	return exifOffset, nil
+}
+
+func readTIFFValue(data []byte, order binary.ByteOrder, entry []byte) (string, error) {
+	typ := order.Uint16(entry[2:4])
+	count := order.Uint32(entry[4:8])
+
+	var size uint32
+	switch typ {
+	case tiffTypeByte, tiffTypeASCII:
+		size = count
+	case tiffTypeShort:
+		size = count * 2
+	case tiffTypeLong:
+		size = count * 4
+	case tiffTypeRational:
+		size = count * 8
+	default:
+		return "", fmt.Errorf("unsupported tiff type %d", typ)
+	}
+
+	// values of up to four bytes are stored inline in the entry
+	value := entry[8:12]
+	if size > 4 {
+		offset := order.Uint32(entry[8:12])
+		if uint64(offset)+uint64(size) > uint64(len(data)) {
+			return "", errInvalidEXIF
+		}
+		value = data[offset : offset+size]
+	}
+
+	switch typ {
+	case tiffTypeASCII:
+		return strings.TrimSpace(strings.TrimRight(string(value[:count]), "\x00")), nil
+	case tiffTypeByte:
+		return strconv.Itoa(int(value[0])), nil
+	case tiffTypeShort:
+		return strconv.Itoa(int(order.Uint16(value))), nil
+	case tiffTypeLong:
+		return strconv.FormatUint(uint64(order.Uint32(value)), 10), nil
+	default:
+		num := order.Uint32(value[0:4])
+		den := order.Uint32(value[4:8])
+		if den == 0 {
+			return "", errInvalidEXIF
+		}
+		return strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64), nil
+	}
+}
@@ -0,0 +1,129 @@
+package screenshots
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+type testIFDEntry struct {
+	tag uint16
+	typ uint16
+	count uint32
+	value []byte
+}
+
+// buildTestEXIF lays out a little-endian TIFF block with the given IFD0 and
+// Exif sub-IFD entries, storing values over four bytes after the IFDs
+func buildTestEXIF(ifd0, exifIFD []testIFDEntry) []byte {
+	order := binary.LittleEndian
+
+	ifdSize := func(entries []testIFDEntry) int { return 2 + len(entries)*12 + 4 }
+	ifd0Offset := 8
+	exifOffset := ifd0Offset + ifdSize(ifd0) + 12
+	dataOffset := exifOffset + ifdSize(exifIFD)
+
+	if len(exifIFD) > 0 {
+		pointer := make([]byte, 4)
+		order.PutUint32(pointer, uint32(exifOffset))
+		ifd0 = append(ifd0, testIFDEntry{tag: exifIFDPointer, typ: tiffTypeLong, count: 1, value: pointer})
+	}
+
+	buf := make([]byte, dataOffset)
+	copy(buf, "II")
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], uint32(ifd0Offset))
+
+	writeIFD := func(offset int, entries []testIFDEntry) {
+		order.PutUint16(buf[offset:], uint16(len(entries)))
+		for i, e := range entries {
+			entry := buf[offset+2+i*12:]
+			order.PutUint16(entry[0:], e.tag)
+			order.PutUint16(entry[2:], e.typ)
+			order.PutUint32(entry[4:], e.count)
+			if len(e.value) <= 4 {
+				copy(entry[8:12], e.value)
+				continue
+			}
+			order.PutUint32(entry[8:], uint32(len(buf)))
+			buf = append(buf, e.value...)
+		}
+	}
+
+	writeIFD(ifd0Offset, ifd0)
+	if len(exifIFD) > 0 {
+		writeIFD(exifOffset, exifIFD)
+	}
+
+	return buf
+}
+
+func asciiEntry(tag uint16, value string) testIFDEntry {
+	return testIFDEntry{tag: tag, typ: tiffTypeASCII, count: uint32(len(value) + 1), value: append([]byte(value), 0)}
+}
+
+func shortEntry(tag uint16, value uint16) testIFDEntry {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, value)
+	return testIFDEntry{tag: tag, typ: tiffTypeShort, count: 1, value: b}
+}
+
+func rationalEntry(tag uint16, num, den uint32) testIFDEntry {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b[0:], num)
+	binary.LittleEndian.PutUint32(b[4:], den)
+	return testIFDEntry{tag: tag, typ: tiffTypeRational, count: 1, value: b}
+}
+
+func TestParseEXIF(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		data := buildTestEXIF(
+			[]testIFDEntry{
+				asciiEntry(0x010F, "Apple"),
+				shortEntry(0x0112, 1),
+				rationalEntry(0x011A, 144, 1),
+				shortEntry(0x0128, 2),
+				asciiEntry(0x9999, "ignored"),
+			},
+			[]testIFDEntry{
+				asciiEntry(0x9003, "2025:03:01 10:12:33"),
+			},
+		)
+
+		tags, err := parseEXIF(data)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		expected := map[string]string{
+			"Make": "Apple",
+			"Orientation": "1",
+			"XResolution": "144",
+			"ResolutionUnit": "2",
+			"DateTimeOriginal": "2025:03:01 10:12:33",
+		}
+		if len(tags) != len(expected) {
+			t.Errorf("expected %d tags, got: %v", len(expected), tags)
+		}
+		for name, value := range expected {
+			if tags[name] != value {
+				t.Errorf("expected %s to be %q, got: %q", name, value, tags[name])
+			}
+		}
+	})
+
+	t.Run("Invalid byte order", func(t *testing.T) {
+		_, err := parseEXIF([]byte("XX\x2a\x00\x08\x00\x00\x00"))
+		if err != errInvalidEXIF {
+			t.Errorf("expected errInvalidEXIF, got: %v", err)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		data := buildTestEXIF([]testIFDEntry{asciiEntry(0x010F, "Apple")}, nil)
+
+		_, err := parseEXIF(data[:12])
+		if err != errInvalidEXIF {
+			t.Errorf("expected errInvalidEXIF, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package screenshots
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Facet names, each counts the distinct values of the keyword field of the
+// same name
+const (
+	FacetCategory = "category"
+	FacetDir = "dir"
+	FacetExt = "ext"
+	FacetLanguage = "language"
+	FacetMonth = "month"
+	FacetTag = "tag"
+	FacetUserTag = "user_tag"
+)
+
+// facetSizes is how many values each facet returns
+var facetSizes = map[string]int{
+	FacetCategory: 6,
+	FacetDir: 10,
+	FacetExt: 10,
+	FacetLanguage: 10,
+	FacetMonth: 12,
+	FacetTag: 15,
+	FacetUserTag: 15,
+}
+
+// FacetValue is one entry of a facet and the number of hits that have it
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int `json:"count"`
+}
+
+func addFacets(request *bleve.SearchRequest) {
+	for name, size := range facetSizes {
+		request.AddFacet(name, bleve.NewFacetRequest(name, size))
+	}
+}
+
+// filterQuery narrows q to the selected facet values. Values of the same
+// facet are ORed, different facets are ANDed.
+func filterQuery(q query.Query, filters map[string][]string) (query.Query, error) {
+	if len(filters) == 0 {
+		return q, nil
+	}
+
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filtered := bleve.NewConjunctionQuery(q)
+	for _, name := range names {
+		if _, ok := facetSizes[name]; !ok {
+			return nil, fmt.Errorf("unknown facet %q", name)
+		}
+		if len(filters[name]) == 0 {
+			continue
+		}
+
+		values := make([]query.Query, 0, len(filters[name]))
+		for _, value := range filters[name] {
+			term := bleve.NewTermQuery(value)
+			term.SetField(name)
+			values = append(values, term)
+		}
+		filtered.AddQuery(bleve.NewDisjunctionQuery(values...))
+	}
+
+	return filtered, nil
+}
+
+func collectFacets(facets search.FacetResults) map[string][]FacetValue {
+	if len(facets) == 0 {
+		return nil
+	}
+
+	collected := make(map[string][]FacetValue, len(facets))
+	for name, facet := range facets {
+		values := make([]FacetValue, 0)
+		for _, term := range facet.Terms.Terms() {
+			// documents without a value, like screenshots in no language
+			if term.Term == "" {
+				continue
+			}
+			values = append(values, FacetValue{Value: term.Term, Count: term.Count})
+		}
+		collected[name] = values
+	}
+
+	return collected
+}
@@ -0,0 +1,91 @@
+package screenshots
+
+import (
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+func facetCount(values []FacetValue, value string) int {
+	for _, v := range values {
+		if v.Value == value {
+			return v.Count
+		}
+	}
+	return 0
+}
+
+func TestFacets(t *testing.T) {
+	idx := newTestIndex(t,
+		ScreenshotDoc{Path: "/d/work/a.png", Text: "error", Dir: "/d/work", Ext: "png", Month: "2025-03", Tags: []string{"stack trace", "error"}},
+		ScreenshotDoc{Path: "/d/work/b.jpg", Text: "error", Dir: "/d/work", Ext: "jpg", Month: "2025-02", Tags: []string{"stack trace"}},
+		ScreenshotDoc{Path: "/d/home/c.png", Text: "error", Dir: "/d/home", Ext: "png", Month: "2025-03", Tags: []string{"invoice"}},
+		ScreenshotDoc{Path: "/d/home/d.png", Text: "lunch", Dir: "/d/home", Ext: "png", Month: "2025-03", Tags: []string{"menu"}},
+	)
+
+	search := func(opts SearchOptions) *SearchResults {
+		t.Helper()
+
+		p := &queryParser{}
+		q, err := p.parse("error")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		req, err := newSearchRequest(q, opts)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		res, err := idx.Search(req)
+		if err != nil {
+			t.Fatalf("expected no search error, got: %v", err)
+		}
+
+		return newSearchResults(res, req)
+	}
+
+	t.Run("Counts", func(t *testing.T) {
+		results := search(SearchOptions{})
+
+		if results.Total != 3 {
+			t.Errorf("expected 3 hits, got: %d", results.Total)
+		}
+		checks := []struct {
+			facet string
+			value string
+			count int
+		}{
+			{facet: FacetDir, value: "/d/work", count: 2},
+			{facet: FacetDir, value: "/d/home", count: 1},
+			{facet: FacetExt, value: "png", count: 2},
+			{facet: FacetMonth, value: "2025-03", count: 2},
+			{facet: FacetTag, value: "stack trace", count: 2},
+			{facet: FacetTag, value: "menu", count: 0},
+		}
+		for _, c := range checks {
+			if got := facetCount(results.Facets[c.facet], c.value); got != c.count {
+				t.Errorf("expected %s %q to count %d, got: %d", c.facet, c.value, c.count, got)
+			}
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		results := search(SearchOptions{Filters: map[string][]string{
+			FacetExt: {"png"},
+			FacetTag: {"stack trace", "invoice"},
+		}})
+
+		if results.Total != 2 {
+			t.Errorf("expected 2 hits, got: %d", results.Total)
+		}
+		if got := facetCount(results.Facets[FacetExt], "jpg"); got != 0 {
+			t.Errorf("expected jpg to be filtered out, got: %d", got)
+		}
+	})
+
+	t.Run("Unknown facet", func(t *testing.T) {
+		_, err := newSearchRequest(bleve.NewMatchAllQuery(), SearchOptions{Filters: map[string][]string{"colour": {"red"}}})
+		if err == nil || err.Error() != `unknown facet "colour"` {
+			t.Errorf("expected 'unknown facet \"colour\"', got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package screenshots
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// bleve does not allow edit distances above 2
+	maxFuzziness = 2
+
+	exactBoost = 4.0
+	confusionBoost = 3.0
+	prefixBoost = 2.0
+	fuzzyBoost = 1.0
+
+	// shorter words match too many terms when used as a prefix
+	minPrefixLength = 3
+	maxConfusionVariants = 16
+)
+
+// ocrConfusions are character sequences OCR engines commonly mistake for
+// one another, each pair is applied in both directions
+var ocrConfusions = [][2]string{
+	{"rn", "m"},
+	{"0", "o"},
+	{"1", "l"},
+	{"1", "i"},
+	{"l", "i"},
+	{"cl", "d"},
+	{"vv", "w"},
+	{"5", "s"},
+	{"8", "b"},
+}
+
+// isSingleTerm reports whether value is a single word the analyzer won't
+// split, so term level queries can be used on it
+func isSingleTerm(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ocrVariants returns the spellings of word that OCR could have produced,
+// or that the user may have typed for a misread word, excluding word itself
+func ocrVariants(word string) []string {
+	word = strings.ToLower(word)
+
+	seen := map[string]struct{}{word: {}}
+	variants := make([]string, 0)
+
+	add := func(v string) {
+		if _, ok := seen[v]; ok || len(variants) >= maxConfusionVariants {
+			return
+		}
+		seen[v] = struct{}{}
+		variants = append(variants, v)
+	}
+
+	for _, pair := range ocrConfusions {
+		for _, dir := range [][2]string{{pair[0], pair[1]}, {pair[1], pair[0]}} {
+			from, to := dir[0], dir[1]
+			if !strings.Contains(word, from) {
+				continue
+			}
+
+			// every single substitution, then all of them at once
+			for i := 0; i+len(from) <= len(word); i++ {
+				if word[i:i+len(from)] == from {
+					add(word[:i] + to + word[i+len(from):])
+				}
+			}
+			add(strings.ReplaceAll(word, from, to))
+		}
+	}
+
+	return variants
+}
+
+// fuzzyTextQuery matches word exactly, as a prefix, within the edit distance
+// and through OCR confusions, weighting exact matches highest
+func fuzzyTextQuery(word string, field string, fuzziness int) query.Query {
+	if fuzziness > maxFuzziness {
+		fuzziness = maxFuzziness
+	}
+	term := strings.ToLower(word)
+
+	exact := bleve.NewMatchQuery(word)
+	exact.SetField(field)
+	exact.SetBoost(exactBoost)
+	queries := []query.Query{exact}
+
+	if len([]rune(term)) >= minPrefixLength {
+		prefix := bleve.NewPrefixQuery(term)
+		prefix.SetField(field)
+		prefix.SetBoost(prefixBoost)
+		queries = append(queries, prefix)
+	}
+
+	fuzzy := bleve.NewFuzzyQuery(term)
+	fuzzy.SetField(field)
+	fuzzy.SetFuzziness(fuzziness)
+	fuzzy.SetBoost(fuzzyBoost)
+	queries = append(queries, fuzzy)
+
+	for _, variant := range ocrVariants(term) {
+		q := bleve.NewTermQuery(variant)
+		q.SetField(field)
+		q.SetBoost(confusionBoost)
+		queries = append(queries, q)
+	}
+
+	return bleve.NewDisjunctionQuery(queries...)
+}
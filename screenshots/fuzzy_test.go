@@ -0,0 +1,134 @@
+package screenshots
+
+import (
+	"testing"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+func TestOCRVariants(t *testing.T) {
+	t.Run("Confusions", func(t *testing.T) {
+		variants := ocrVariants("rnetrics")
+
+		found := false
+		for _, v := range variants {
+			if v == "metrics" {
+				found = true
+			}
+			if v == "rnetrics" {
+				t.Errorf("expected the word itself to be excluded")
+			}
+		}
+		if !found {
+			t.Errorf("expected 'metrics' in variants, got: %v", variants)
+		}
+	})
+
+	t.Run("Both directions", func(t *testing.T) {
+		variants := ocrVariants("P0d")
+
+		expected := map[string]bool{"pod": false, "p0cl": false}
+		for _, v := range variants {
+			if _, ok := expected[v]; ok {
+				expected[v] = true
+			}
+		}
+		for v, ok := range expected {
+			if !ok {
+				t.Errorf("expected %q in variants, got: %v", v, variants)
+			}
+		}
+	})
+
+	t.Run("Capped", func(t *testing.T) {
+		variants := ocrVariants("l1l1l1l1l1l1o0o0o0")
+		if len(variants) > maxConfusionVariants {
+			t.Errorf("expected at most %d variants, got: %d", maxConfusionVariants, len(variants))
+		}
+	})
+}
+
+func TestIsSingleTerm(t *testing.T) {
+	tests := map[string]bool{
+		"metrics": true,
+		"k8s": true,
+		"": false,
+		"error-rate": false,
+		"foo.bar": false,
+	}
+
+	for value, expected := range tests {
+		if isSingleTerm(value) != expected {
+			t.Errorf("isSingleTerm(%q): expected %v", value, expected)
+		}
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	idx := newTestIndex(t,
+		ScreenshotDoc{Path: "/shots/metrics.png", Text: "Request metrics dashboard"},
+		ScreenshotDoc{Path: "/shots/ocr.png", Text: "rnemory usage 1ogs"},
+		ScreenshotDoc{Path: "/shots/other.png", Text: "unrelated lunch menu"},
+	)
+
+	tests := []struct {
+		input string
+		fuzziness int
+		expected string
+	}{
+		// the user types it right, OCR got it wrong
+		{input: "memory", fuzziness: 1, expected: "/shots/ocr.png"},
+		{input: "logs", fuzziness: 1, expected: "/shots/ocr.png"},
+		// the user copies the OCR mistake
+		{input: "rnetrics", fuzziness: 1, expected: "/shots/metrics.png"},
+		{input: "dashbaord", fuzziness: 2, expected: "/shots/metrics.png"},
+		{input: "metr", fuzziness: 1, expected: "/shots/metrics.png"},
+	}
+
+	for _, tt := range tests {
+		p := &queryParser{fuzziness: tt.fuzziness}
+		q, err := p.parse(tt.input)
+		if err != nil {
+			t.Fatalf("%q: expected no error, got: %v", tt.input, err)
+		}
+
+		res, err := idx.Search(bleve.NewSearchRequest(q))
+		if err != nil {
+			t.Fatalf("%q: expected no search error, got: %v", tt.input, err)
+		}
+		if len(res.Hits) == 0 || res.Hits[0].ID != tt.expected {
+			t.Errorf("%q: expected top hit %s, got: %v", tt.input, tt.expected, res.Hits)
+		}
+	}
+
+	t.Run("Exact ranks first", func(t *testing.T) {
+		idx := newTestIndex(t,
+			ScreenshotDoc{Path: "/shots/exact.png", Text: "metrics"},
+			ScreenshotDoc{Path: "/shots/fuzzy.png", Text: "metric"},
+		)
+
+		p := &queryParser{fuzziness: 1}
+		q, _ := p.parse("metrics")
+
+		res, err := idx.Search(bleve.NewSearchRequest(q))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(res.Hits) != 2 || res.Hits[0].ID != "/shots/exact.png" {
+			t.Errorf("expected exact match ranked first, got: %v", res.Hits)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		p := &queryParser{}
+		q, _ := p.parse("rnetrics")
+
+		res, err := idx.Search(bleve.NewSearchRequest(q))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(res.Hits) != 0 {
+			t.Errorf("expected no hits without fuzziness, got: %v", res.Hits)
+		}
+	})
+}
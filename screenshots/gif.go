@@ -0,0 +1,145 @@
+package screenshots
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+)
+
+const defaultGIFFrames = 5
+
+// keyFrameIndices picks n frame indices spread evenly across total frames,
+// always including the first and last frame
+func keyFrameIndices(total, n int) []int {
+	if total <= 0 || n <= 0 {
+		return nil
+	}
+	if n >= total {
+		n = total
+	}
+	if n == 1 {
+		return []int{0}
+	}
+
+	indices := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		idx := i * (total - 1) / (n - 1)
+		if len(indices) > 0 && indices[len(indices)-1] == idx {
+			continue
+		}
+		indices = append(indices, idx)
+	}
+
+	return indices
+}
+
+// compositeFrames renders every frame of the GIF onto the logical screen,
+// since frames after the first usually only hold the pixels that changed,
+// and returns a copy of the frames at indices. Only those are kept, a long
+// recording would otherwise hold every frame in memory.
+func compositeFrames(g *gif.GIF, indices []int) []image.Image {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+
+	keep := make(map[int]bool, len(indices))
+	for _, idx := range indices {
+		keep[idx] = true
+	}
+
+	canvas := image.NewRGBA(bounds)
+	frames := make([]image.Image, 0, len(indices))
+
+	for i, frame := range g.Image {
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		if keep[i] {
+			snapshot := image.NewRGBA(bounds)
+			draw.Draw(snapshot, bounds, canvas, bounds.Min, draw.Src)
+			frames = append(frames, snapshot)
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return frames
+}
+
+func (o *OCR) extractGIFText(path string) (string, error) {
+	file, err := o.fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	g, err := gif.DecodeAll(file)
+	if err != nil {
+		return "", fmt.Errorf("error decoding gif: %v", err)
+	}
+
+	// a still GIF is just an image, hand it to the helper as is
+	if len(g.Image) <= 1 {
+		return o.runOCR(path)
+	}
+
+	n := o.GIFFrames
+	if n <= 0 {
+		n = defaultGIFFrames
+	}
+	indices := keyFrameIndices(len(g.Image), n)
+	frames := compositeFrames(g, indices)
+	texts := make([]string, 0, len(frames))
+
+	for i, frame := range frames {
+		text, err := o.ocrFrame(frame)
+		if err != nil {
+			return "", fmt.Errorf("error extracting text from frame %d: %v", indices[i], err)
+		}
+		texts = append(texts, text)
+	}
+
+	return mergeText(texts), nil
+}
+
+// ocrFrame writes a single frame to a temporary PNG for the OCR helper
+func (o *OCR) ocrFrame(frame image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, frame); err != nil {
+		return "", err
+	}
+
+	tmpFile, err := o.fs.CreateTemp("", "glimpse-frame-*.png")
+	if err != nil {
+		return "", err
+	}
+	defer o.fs.Remove(tmpFile.Name())
+
+	if _, err := o.fs.WriteFile(tmpFile, buf.Bytes()); err != nil {
+		tmpFile.Close()
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", err
+	}
+
+	return o.runOCR(tmpFile.Name())
+}
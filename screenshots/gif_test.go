@@ -0,0 +1,160 @@
+package screenshots
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+)
+
+func newTestGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+
+	palette := color.Palette{color.White, color.Black}
+	g := &gif.GIF{
+		Config: image.Config{Width: 4, Height: 4, ColorModel: palette},
+	}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+		frame.SetColorIndex(i%4, 0, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+		g.Disposal = append(g.Disposal, gif.DisposalNone)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("error encoding test gif: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestKeyFrameIndices(t *testing.T) {
+	tests := []struct {
+		total int
+		n int
+		expected []int
+	}{
+		{total: 10, n: 3, expected: []int{0, 4, 9}},
+		{total: 10, n: 1, expected: []int{0}},
+		{total: 3, n: 5, expected: []int{0, 1, 2}},
+		{total: 0, n: 5, expected: nil},
+		{total: 5, n: 0, expected: nil},
+	}
+
+	for _, tt := range tests {
+		got := keyFrameIndices(tt.total, tt.n)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("keyFrameIndices(%d, %d): expected %v, got %v", tt.total, tt.n, tt.expected, got)
+		}
+	}
+}
+
+func TestCompositeFrames(t *testing.T) {
+	g, err := gif.DecodeAll(bytes.NewReader(newTestGIF(t, 6)))
+	if err != nil {
+		t.Fatalf("error decoding test gif: %v", err)
+	}
+
+	frames := compositeFrames(g, []int{0, 5})
+	if len(frames) != 2 {
+		t.Fatalf("expected only the 2 key frames, got %d", len(frames))
+	}
+	// frame i has a black pixel at i%4 on the first row
+	for i, x := range map[int]int{0: 0, 1: 1} {
+		if r, _, _, _ := frames[i].At(x, 0).RGBA(); r != 0 {
+			t.Errorf("expected key frame %d to have its pixel at %d, got %v", i, x, frames[i].At(x, 0))
+		}
+	}
+}
+
+func TestExtractGIFText(t *testing.T) {
+	t.Run("Animated", func(t *testing.T) {
+		fs := &mockFileSystem{
+			files: map[string][]byte{"/shots/demo.gif": newTestGIF(t, 6)},
+			tempFile: &mockFile{name: "/tmp/glimpse-frame.png"},
+		}
+		calls := 0
+		cmdRunner := &mockCmdRunner{
+			commandFn: func(name string, arg ...string) ([]byte, error) {
+				calls++
+				return []byte(fmt.Sprintf("Terminal\nstep %d", calls)), nil
+			},
+		}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+		ocr.GIFFrames = 3
+
+		text, err := ocr.ExtractText("/shots/demo.gif")
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("expected 3 frames to be OCR'd, got: %d", calls)
+		}
+		if text != "Terminal\nstep 1\nstep 2\nstep 3" {
+			t.Errorf("expected merged frame text, got: %q", text)
+		}
+	})
+
+	t.Run("No frame count", func(t *testing.T) {
+		fs := &mockFileSystem{
+			files: map[string][]byte{"/shots/demo.gif": newTestGIF(t, 8)},
+			tempFile: &mockFile{name: "/tmp/glimpse-frame.png"},
+		}
+		calls := 0
+		cmdRunner := &mockCmdRunner{
+			commandFn: func(name string, arg ...string) ([]byte, error) {
+				calls++
+				return []byte("frame"), nil
+			},
+		}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+
+		if _, err := ocr.ExtractText("/shots/demo.gif"); err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if calls != defaultGIFFrames {
+			t.Errorf("expected the default %d frames to be OCR'd, got: %d", defaultGIFFrames, calls)
+		}
+	})
+
+	t.Run("Still", func(t *testing.T) {
+		fs := &mockFileSystem{
+			files: map[string][]byte{"/shots/still.gif": newTestGIF(t, 1)},
+		}
+		cmdRunner := &mockCmdRunner{
+			commandFn: func(name string, arg ...string) ([]byte, error) {
+				if arg[0] != "/shots/still.gif" {
+					t.Errorf("expected the gif itself to be OCR'd, got: %s", arg[0])
+				}
+				return []byte("still text"), nil
+			},
+		}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+		ocr.GIFFrames = 3
+
+		text, err := ocr.ExtractText("/shots/still.gif")
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if text != "still text" {
+			t.Errorf("expected 'still text', got: %q", text)
+		}
+	})
+
+	t.Run("Decode error", func(t *testing.T) {
+		fs := &mockFileSystem{
+			files: map[string][]byte{"/shots/broken.gif": []byte("not a gif")},
+		}
+		ocr := NewMockOCR(nil, "", fs, nil)
+
+		_, err := ocr.ExtractText("/shots/broken.gif")
+		if err == nil {
+			t.Errorf("expected decode error, got nil")
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package screenshots
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
+)
+
+const tesseractBinary = "tesseract"
+
+// tesseract TSV rows at this level are single words
+const tesseractWordLevel = "5"
+
+// highlightFields are the fields snippets are taken from
+var highlightFields = []string{"text", "tags"}
+
+// WordBox is a word found by OCR and its position in image pixels
+type WordBox struct {
+	Text string `json:"text"`
+	X int `json:"x"`
+	Y int `json:"y"`
+	Width int `json:"width"`
+	Height int `json:"height"`
+	// Confidence is how sure tesseract is of the word (0-100), it is
+	// only known right after OCR and not stored
+	Confidence float64 `json:"-"`
+}
+
+// parseTesseractTSV reads the word rows of `tesseract <image> stdout tsv`
+func parseTesseractTSV(out []byte) []WordBox {
+	words := make([]WordBox, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 12 || cols[0] != tesseractWordLevel {
+			continue
+		}
+
+		text := strings.TrimSpace(cols[11])
+		if text == "" {
+			continue
+		}
+
+		nums := make([]int, 4)
+		valid := true
+		for i := range nums {
+			n, err := strconv.Atoi(cols[6+i])
+			if err != nil {
+				valid = false
+				break
+			}
+			nums[i] = n
+		}
+		if !valid {
+			continue
+		}
+
+		// tesseract writes -1 for rows that aren't words, keep those
+		confidence, err := strconv.ParseFloat(cols[10], 64)
+		if err != nil {
+			confidence = -1
+		}
+
+		words = append(words, WordBox{Text: text, X: nums[0], Y: nums[1], Width: nums[2], Height: nums[3], Confidence: confidence})
+	}
+
+	return words
+}
+
+// encodeWordBoxes packs word boxes into the stored-only word_boxes field,
+// one "x y w h text" line per word
+func encodeWordBoxes(words []WordBox) string {
+	var b strings.Builder
+	for _, w := range words {
+		fmt.Fprintf(&b, "%d %d %d %d %s\n", w.X, w.Y, w.Width, w.Height, w.Text)
+	}
+
+	return b.String()
+}
+
+func decodeWordBoxes(encoded string) []WordBox {
+	words := make([]WordBox, 0)
+
+	for _, line := range strings.Split(encoded, "\n") {
+		parts := strings.SplitN(line, " ", 5)
+		if len(parts) != 5 {
+			continue
+		}
+
+		w := WordBox{Text: parts[4]}
+		var err error
+		if w.X, err = strconv.Atoi(parts[0]); err != nil {
+			continue
+		}
+		if w.Y, err = strconv.Atoi(parts[1]); err != nil {
+			continue
+		}
+		if w.Width, err = strconv.Atoi(parts[2]); err != nil {
+			continue
+		}
+		if w.Height, err = strconv.Atoi(parts[3]); err != nil {
+			continue
+		}
+
+		words = append(words, w)
+	}
+
+	return words
+}
+
+// normalizeWord lowercases a word and strips surrounding punctuation so it
+// compares equal to the analyzed term
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
+
+// matchedRegions returns the boxes of words that matched a term in the OCR
+// text of the hit
+func matchedRegions(words []WordBox, locations search.FieldTermLocationMap) []WordBox {
+	terms := locations["text"]
+	if len(terms) == 0 || len(words) == 0 {
+		return nil
+	}
+
+	regions := make([]WordBox, 0)
+	for _, w := range words {
+		if _, ok := terms[normalizeWord(w.Text)]; ok {
+			regions = append(regions, w)
+		}
+	}
+
+	return regions
+}
+
+func newHighlight() *bleve.HighlightRequest {
+	h := bleve.NewHighlightWithStyle(html.Name)
+	for _, field := range highlightFields {
+		h.AddField(field)
+	}
+
+	return h
+}
@@ -0,0 +1,119 @@
+package screenshots
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testTesseractTSV = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t800\t600\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t10\t20\t300\t18\t-1\t\n" +
+	"5\t1\t1\t1\t1\t1\t10\t20\t90\t18\t96.1\tRequest\n" +
+	"5\t1\t1\t1\t1\t2\t110\t20\t80\t18\t95.3\tmetrics:\n" +
+	"5\t1\t1\t1\t1\t3\t200\t20\t10\t18\t12.0\t \n"
+
+func TestParseTesseractTSV(t *testing.T) {
+	words := parseTesseractTSV([]byte(testTesseractTSV))
+
+	expected := []WordBox{
+		{Text: "Request", X: 10, Y: 20, Width: 90, Height: 18, Confidence: 96.1},
+		{Text: "metrics:", X: 110, Y: 20, Width: 80, Height: 18, Confidence: 95.3},
+	}
+	if !reflect.DeepEqual(words, expected) {
+		t.Errorf("expected %v, got %v", expected, words)
+	}
+}
+
+func TestWordBoxesRoundTrip(t *testing.T) {
+	words := []WordBox{
+		{Text: "p99 latency", X: 1, Y: 2, Width: 3, Height: 4},
+		{Text: "ok", X: 10, Y: 20, Width: 30, Height: 40},
+	}
+
+	decoded := decodeWordBoxes(encodeWordBoxes(words))
+	if !reflect.DeepEqual(decoded, words) {
+		t.Errorf("expected %v, got %v", words, decoded)
+	}
+}
+
+func TestExtractWords(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		cmdRunner := &mockCmdRunner{cmdError: errors.New("should not be called")}
+		ocr := NewMockOCR(nil, "", nil, cmdRunner)
+
+		words, err := ocr.ExtractWords("/shots/a.png")
+		if err != nil || words != nil {
+			t.Errorf("expected no words and no error, got: %v, %v", words, err)
+		}
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		cmdRunner := &mockCmdRunner{output: []byte(testTesseractTSV)}
+		ocr := NewMockOCR(nil, "", nil, cmdRunner)
+		ocr.WordBoxes = true
+
+		words, err := ocr.ExtractWords("/shots/a.png")
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if len(words) != 2 {
+			t.Errorf("expected 2 words, got: %v", words)
+		}
+	})
+}
+
+func TestHighlighting(t *testing.T) {
+	idx := newTestIndex(t, ScreenshotDoc{
+		Path: "/shots/metrics.png",
+		Text: "Request metrics: <script>alert(1)</script> for the API",
+		Tags: []string{"request metrics"},
+		WordBoxes: encodeWordBoxes(parseTesseractTSV([]byte(testTesseractTSV))),
+	})
+
+	p := &queryParser{}
+	q, err := p.parse("metrics")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	req, err := newSearchRequest(q, SearchOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	res, err := idx.Search(req)
+	if err != nil {
+		t.Fatalf("expected no search error, got: %v", err)
+	}
+	if len(res.Hits) != 1 {
+		t.Fatalf("expected 1 hit, got: %v", res.Hits)
+	}
+	hit := res.Hits[0]
+
+	snippets := hit.Fragments["text"]
+	if len(snippets) == 0 || !strings.Contains(snippets[0], "<mark>metrics</mark>") {
+		t.Errorf("expected marked snippet, got: %v", snippets)
+	}
+	if strings.Contains(snippets[0], "<script>") {
+		t.Errorf("expected snippet to be escaped, got: %s", snippets[0])
+	}
+	if len(hit.Fragments["tags"]) == 0 {
+		t.Errorf("expected tag snippet, got: %v", hit.Fragments)
+	}
+
+	boxes, _ := hit.Fields["word_boxes"].(string)
+	regions := matchedRegions(decodeWordBoxes(boxes), hit.Locations)
+	expected := []WordBox{{Text: "metrics:", X: 110, Y: 20, Width: 80, Height: 18}}
+	if !reflect.DeepEqual(regions, expected) {
+		t.Errorf("expected regions %v, got: %v", expected, regions)
+	}
+}
+
+func TestMatchedRegionsNoLocations(t *testing.T) {
+	regions := matchedRegions([]WordBox{{Text: "metrics"}}, nil)
+	if regions != nil {
+		t.Errorf("expected no regions, got: %v", regions)
+	}
+}
+
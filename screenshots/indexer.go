@@ -1,12 +1,16 @@
 package screenshots
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/mapping"
+	index "github.com/blevesearch/bleve_index_api"
+	"go.etcd.io/bbolt"
 )
 
 type osProvider interface {
@@ -31,8 +35,20 @@ type bleveProvider interface {
 
 type realBleveProvider struct {}
 
+// openTimeout is how long opening waits for the index to be unlocked, so a
+// second process (the app and the CLI) fails instead of hanging
+const openTimeout = "3s"
+
+// ErrIndexLocked is returned when another process has the index open
+var ErrIndexLocked = errors.New("the index is in use by another process, like the Glimpse app")
+
 func (r *realBleveProvider) Open(indexPath string) (indexer, error) {
-    return bleve.Open(indexPath)
+    idx, err := bleve.OpenUsing(indexPath, map[string]interface{}{"bolt_timeout": openTimeout})
+    if errors.Is(err, bbolt.ErrTimeout) {
+        return nil, ErrIndexLocked
+    }
+
+    return idx, err
 }
 
 func (r *realBleveProvider) New(path string, mapping mapping.IndexMapping) (indexer, error) {
@@ -41,7 +57,13 @@ func (r *realBleveProvider) New(path string, mapping mapping.IndexMapping) (inde
 
 type indexer interface {
 	Index(id string, data interface{}) error
-    Search(req *bleve.SearchRequest) (*bleve.SearchResult, error)
+	Delete(id string) error
+    SearchInContext(ctx context.Context, req *bleve.SearchRequest) (*bleve.SearchResult, error)
+    FieldDictPrefix(field string, termPrefix []byte) (index.FieldDict, error)
+    FieldDictRange(field string, startTerm []byte, endTerm []byte) (index.FieldDict, error)
+    DocCount() (uint64, error)
+    GetInternal(key []byte) ([]byte, error)
+    SetInternal(key []byte, val []byte) error
     Close() error
 }
 
@@ -49,15 +71,31 @@ type IndexerProvider interface {
 	Open() error
 	Close() error
 	Index(path string, doc *ScreenshotDoc) error
-	Search(searchRequest *bleve.SearchRequest) (*bleve.SearchResult, error)
+	Delete(path string) error
+	Search(ctx context.Context, searchRequest *bleve.SearchRequest) (*bleve.SearchResult, error)
+	Terms(field string, prefix string) ([]index.DictEntry, error)
+	DocCount() (uint64, error)
+	DocFrequency(field string, term string) (uint64, error)
 	GetIndexPath() (string, error)
+	Reset() error
+	Outdated() bool
 }
+
+// mappingVersion is stored in every index made with newIndexMapping. Raise
+// it whenever the mapping changes, indexes of another version are rebuilt
+// by ReindexIfOutdated. Indexes from before the mapping have none.
+const mappingVersion = "1"
+
+var mappingVersionKey = []byte("mapping_version")
+
 type Indexer struct {
 	appName string
 	blevePath string
 	idx indexer
 	o osProvider
 	b bleveProvider
+	// outdated is set when the open index was made with another mapping
+	outdated bool
 }
 
 func NewIndexer() *Indexer {
@@ -70,18 +108,104 @@ func NewIndexer() *Indexer {
 	}
 }
 
+// newIndexMapping maps the metadata of ScreenshotDoc to typed fields so they
+// can be used in range queries and sorting. The base64 image is stored for
+// display but not indexed.
+func newIndexMapping() mapping.IndexMapping {
+	numeric := bleve.NewNumericFieldMapping()
+	datetime := bleve.NewDateTimeFieldMapping()
+	text := bleve.NewTextFieldMapping()
+
+	stored := bleve.NewTextFieldMapping()
+	stored.Index = false
+	stored.IncludeInAll = false
+	stored.IncludeTermVectors = false
+
+	keyword := bleve.NewKeywordFieldMapping()
+
+	// hashes and the like are only looked up by value, never matched by
+	// free text
+	lookup := bleve.NewKeywordFieldMapping()
+	lookup.IncludeInAll = false
+
+	// tags are searched word by word, but faceted and filtered on as whole
+	// phrases through the "tag" field
+	tag := bleve.NewKeywordFieldMapping()
+	tag.Name = "tag"
+	tag.IncludeInAll = false
+
+	userTag := bleve.NewKeywordFieldMapping()
+	userTag.Name = "user_tag"
+	userTag.IncludeInAll = false
+
+	doc := bleve.NewDocumentMapping()
+	doc.AddFieldMappingsAt("text", text)
+	// only set on screenshots of code, searched through _all like text
+	code := bleve.NewTextFieldMapping()
+	code.Analyzer = codeAnalyzer
+	doc.AddFieldMappingsAt("code", code)
+	doc.AddFieldMappingsAt("tags", text, tag)
+	doc.AddFieldMappingsAt("user_tags", text, userTag)
+	doc.AddFieldMappingsAt("note", text)
+	doc.AddFieldMappingsAt("tag_extractor", lookup)
+	doc.AddFieldMappingsAt("url", stored)
+	doc.AddFieldMappingsAt("word_boxes", stored)
+	doc.AddFieldMappingsAt("parent", keyword)
+	doc.AddFieldMappingsAt("dir", keyword)
+	doc.AddFieldMappingsAt("ext", keyword)
+	doc.AddFieldMappingsAt("month", keyword)
+	doc.AddFieldMappingsAt("page", numeric)
+	doc.AddFieldMappingsAt("timestamp", numeric)
+	doc.AddFieldMappingsAt("size", numeric)
+	doc.AddFieldMappingsAt("created", datetime)
+	doc.AddFieldMappingsAt("modified", datetime)
+	doc.AddFieldMappingsAt("width", numeric)
+	doc.AddFieldMappingsAt("height", numeric)
+	doc.AddFieldMappingsAt("dpi", numeric)
+	doc.AddFieldMappingsAt("scale", numeric)
+	doc.AddFieldMappingsAt("source_app", text)
+	doc.AddFieldMappingsAt("window_title", text)
+	doc.AddFieldMappingsAt("category", lookup)
+	doc.AddFieldMappingsAt("language", lookup)
+	doc.AddFieldMappingsAt("phash", lookup)
+	doc.AddFieldMappingsAt("content_hash", lookup)
+	// entities are in the text already, these are for exact lookups
+	for _, field := range entityFields {
+		doc.AddFieldMappingsAt(field, lookup)
+	}
+	doc.AddFieldMappingsAt("entity_types", lookup)
+	doc.AddFieldMappingsAt("last_error", stored)
+	doc.AddFieldMappingsAt("error_stage", lookup)
+	addEmbeddingMapping(doc)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = doc
+	// the analyzer is fixed, it can only fail if its definition is broken
+	if err := addCodeAnalyzer(indexMapping); err != nil {
+		panic(err)
+	}
+
+	return indexMapping
+}
+
 func (i *Indexer) GetIndexPath() (string, error) {
-	userDataDir, err := i.o.getUserConfigDir()
+	return appDataPath(i.o, i.appName, i.blevePath)
+}
+
+// appDataPath returns the path of name in the app's folder of the user
+// config dir, creating the folder if needed
+func appDataPath(o osProvider, appName string, name string) (string, error) {
+	userDataDir, err := o.getUserConfigDir()
 	if err != nil {
 		return "", err
 	}
 
-	appDataDir := filepath.Join(userDataDir, i.appName)
-	if err := i.o.mkdirAll(appDataDir, 0755); err != nil {
+	appDataDir := filepath.Join(userDataDir, appName)
+	if err := o.mkdirAll(appDataDir, 0755); err != nil {
 		return "", err
 	}
 
-	return filepath.Join(appDataDir, i.blevePath), nil
+	return filepath.Join(appDataDir, name), nil
 }
 
 func (i *Indexer) Open() error {
@@ -97,31 +221,78 @@ func (i *Indexer) Open() error {
 	idx, err := i.b.Open(indexPath)
 	if err != nil {
 		if err == bleve.ErrorIndexPathDoesNotExist {
-			mapping := bleve.NewIndexMapping()
-			idx, err = i.b.New(indexPath, mapping)
+			idx, err = i.b.New(indexPath, newIndexMapping())
 			if err != nil {
 				return err
 			}
+			if err := idx.SetInternal(mappingVersionKey, []byte(mappingVersion)); err != nil {
+				idx.Close()
+				return fmt.Errorf("error writing mapping version: %v", err)
+			}
 		} else {
 			return err
 		}
 	}
 
+	version, err := idx.GetInternal(mappingVersionKey)
+	if err != nil {
+		idx.Close()
+		return fmt.Errorf("error reading mapping version: %v", err)
+	}
+
 	i.idx = idx
+	i.outdated = string(version) != mappingVersion
 
 	return nil
 }
 
+// Outdated reports whether the open index was made with an older mapping,
+// its fields can't be searched the way the current mapping expects until
+// it is rebuilt
+func (i *Indexer) Outdated() bool {
+	return i.outdated
+}
+
 func (i *Indexer) Close() error {
-	return i.idx.Close()
+	if i.idx == nil {
+		return nil
+	}
+
+	err := i.idx.Close()
+	i.idx = nil
+
+	return err
+}
+
+// Reset deletes the index from disk and opens a new empty one, with the
+// current mapping
+func (i *Indexer) Reset() error {
+	if err := i.Close(); err != nil {
+		return fmt.Errorf("error closing index: %v", err)
+	}
+
+	indexPath, err := i.GetIndexPath()
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(indexPath); err != nil {
+		return fmt.Errorf("error removing index: %v", err)
+	}
+
+	return i.Open()
 }
 
 func (i *Indexer) Index(path string, doc *ScreenshotDoc) error {
 	return i.idx.Index(path, doc)
 }
 
-func (i *Indexer) Search(request *bleve.SearchRequest) (*bleve.SearchResult, error) {
-	searchResult, err := i.idx.Search(request)
+func (i *Indexer) Delete(path string) error {
+	return i.idx.Delete(path)
+}
+
+// Search runs the request until it completes or ctx is cancelled
+func (i *Indexer) Search(ctx context.Context, request *bleve.SearchRequest) (*bleve.SearchResult, error) {
+	searchResult, err := i.idx.SearchInContext(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -129,3 +300,46 @@ func (i *Indexer) Search(request *bleve.SearchRequest) (*bleve.SearchResult, err
 	return searchResult, nil
 }
 
+// Terms lists the indexed terms of field that start with prefix, with the
+// number of documents each appears in
+func (i *Indexer) Terms(field string, prefix string) ([]index.DictEntry, error) {
+	dict, err := i.idx.FieldDictPrefix(field, []byte(prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer dict.Close()
+
+	terms := make([]index.DictEntry, 0)
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+		terms = append(terms, *entry)
+	}
+
+	return terms, nil
+}
+
+func (i *Indexer) DocCount() (uint64, error) {
+	return i.idx.DocCount()
+}
+
+// DocFrequency returns the number of documents term appears in
+func (i *Indexer) DocFrequency(field string, term string) (uint64, error) {
+	dict, err := i.idx.FieldDictRange(field, []byte(term), []byte(term))
+	if err != nil {
+		return 0, err
+	}
+	defer dict.Close()
+
+	entry, err := dict.Next()
+	if err != nil || entry == nil {
+		return 0, err
+	}
+
+	return entry.Count, nil
+}
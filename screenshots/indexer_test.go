@@ -1,6 +1,7 @@
 package screenshots
 
 import (
+	"context"
 	"errors"
 	"os"
 	"testing"
@@ -8,6 +9,7 @@ import (
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/mapping"
 	"github.com/blevesearch/bleve/v2/search"
+	index "github.com/blevesearch/bleve_index_api"
 )
 
 func NewMockIndexer(appName, blevePath string, o *mockOsProvider, b *mockBleveProvider, i *mockIndexer) *Indexer {
@@ -43,10 +45,41 @@ func (m *mockOsProvider) mkdirAll(path string, perm os.FileMode) error {
 
 type mockIndexer struct {
 	indexError error
+	deleteError error
 	searchError error
 	closeError error
 
+	dictError error
+	docCount uint64
+	internal map[string][]byte
+
 	searchFn func(req *bleve.SearchRequest) (*bleve.SearchResult, error)
+	terms map[string][]index.DictEntry
+}
+
+type mockFieldDict struct {
+	entries []index.DictEntry
+}
+
+func (m *mockFieldDict) Next() (*index.DictEntry, error) {
+	if len(m.entries) == 0 {
+		return nil, nil
+	}
+	entry := m.entries[0]
+	m.entries = m.entries[1:]
+	return &entry, nil
+}
+
+func (m *mockFieldDict) Cardinality() int {
+	return len(m.entries)
+}
+
+func (m *mockFieldDict) BytesRead() uint64 {
+	return 0
+}
+
+func (m *mockFieldDict) Close() error {
+	return nil
 }
 
 func (m *mockIndexer) Index(id string, data interface{}) error {
@@ -56,13 +89,57 @@ func (m *mockIndexer) Index(id string, data interface{}) error {
 	return nil
 }
 
-func (m *mockIndexer) Search(req *bleve.SearchRequest) (*bleve.SearchResult, error) {
+func (m *mockIndexer) Delete(id string) error {
+	if m.deleteError != nil {
+		return m.deleteError
+	}
+	return nil
+}
+
+func (m *mockIndexer) SearchInContext(ctx context.Context, req *bleve.SearchRequest) (*bleve.SearchResult, error) {
 	if m.searchError != nil {
 		return nil, m.searchError
 	}
 	return m.searchFn(req)
 }
 
+func (m *mockIndexer) FieldDictPrefix(field string, termPrefix []byte) (index.FieldDict, error) {
+	if m.dictError != nil {
+		return nil, m.dictError
+	}
+	return &mockFieldDict{entries: m.terms[field]}, nil
+}
+
+func (m *mockIndexer) FieldDictRange(field string, startTerm []byte, endTerm []byte) (index.FieldDict, error) {
+	if m.dictError != nil {
+		return nil, m.dictError
+	}
+
+	entries := make([]index.DictEntry, 0)
+	for _, entry := range m.terms[field] {
+		if entry.Term >= string(startTerm) && entry.Term <= string(endTerm) {
+			entries = append(entries, entry)
+		}
+	}
+	return &mockFieldDict{entries: entries}, nil
+}
+
+func (m *mockIndexer) DocCount() (uint64, error) {
+	return m.docCount, nil
+}
+
+func (m *mockIndexer) GetInternal(key []byte) ([]byte, error) {
+	return m.internal[string(key)], nil
+}
+
+func (m *mockIndexer) SetInternal(key []byte, val []byte) error {
+	if m.internal == nil {
+		m.internal = make(map[string][]byte)
+	}
+	m.internal[string(key)] = val
+	return nil
+}
+
 func (m *mockIndexer) Close() error {
 	if m.closeError != nil {
 		return m.closeError
@@ -73,13 +150,15 @@ func (m *mockIndexer) Close() error {
 type mockBleveProvider struct {
 	openError error
 	newError error
+	// internal is what the opened index holds besides documents
+	internal map[string][]byte
 }
 
 func (m *mockBleveProvider) Open(indexPath string) (indexer, error) {
 	if m.openError != nil {
 		return nil, m.openError
 	}
-	return &mockIndexer{}, nil
+	return &mockIndexer{internal: m.internal}, nil
 }
 
 func (m *mockBleveProvider) New(path string, mapping mapping.IndexMapping) (indexer, error) {
@@ -161,6 +240,29 @@ func TestOpen(t *testing.T) {
 		}
 	})
 
+	t.Run("Mapping version", func(t *testing.T) {
+		o := &mockOsProvider{userConfigDir: "user/config/dir"}
+
+		created := NewMockIndexer("glimpse-test", "test.bleve", o, &mockBleveProvider{openError: bleve.ErrorIndexPathDoesNotExist}, nil)
+		created.idx = nil
+		if err := created.Open(); err != nil || created.Outdated() {
+			t.Errorf("expected a new index to be current, got %t, %v", created.Outdated(), err)
+		}
+
+		current := NewMockIndexer("glimpse-test", "test.bleve", o, &mockBleveProvider{internal: map[string][]byte{"mapping_version": []byte(mappingVersion)}}, nil)
+		current.idx = nil
+		if err := current.Open(); err != nil || current.Outdated() {
+			t.Errorf("expected the index to be current, got %t, %v", current.Outdated(), err)
+		}
+
+		// indexes from before the mapping have no version
+		old := NewMockIndexer("glimpse-test", "test.bleve", o, &mockBleveProvider{}, nil)
+		old.idx = nil
+		if err := old.Open(); err != nil || !old.Outdated() {
+			t.Errorf("expected the index to be outdated, got %t, %v", old.Outdated(), err)
+		}
+	})
+
 	t.Run("BleveNew error", func(t *testing.T) {
 		b := &mockBleveProvider{
 			openError: bleve.ErrorIndexPathDoesNotExist,
@@ -194,7 +296,7 @@ func TestSearch(t *testing.T) {
 
 		i := NewMockIndexer("", "", nil, nil, mockIdx)
 
-		searchResult, err := i.Search(nil)
+		searchResult, err := i.Search(context.Background(), nil)
 		if err != nil {
 			t.Errorf("expected no error, got %v", err)
 		}
@@ -210,13 +312,66 @@ func TestSearch(t *testing.T) {
 
 		i := NewMockIndexer("", "", nil, nil, mockIdx)
 
-		_, err := i.Search(nil)
+		_, err := i.Search(context.Background(), nil)
 		if err == nil || err.Error() != "search error" {
 			t.Errorf("expected 'search error', got: %v", err)
 		}
 	})
 }
 
+func TestTerms(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockIdx := &mockIndexer{
+			terms: map[string][]index.DictEntry{
+				"text": {{Term: "grafana", Count: 3}, {Term: "graph", Count: 1}},
+			},
+		}
+
+		i := NewMockIndexer("", "", nil, nil, mockIdx)
+
+		terms, err := i.Terms("text", "gra")
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if len(terms) != 2 || terms[0].Term != "grafana" || terms[0].Count != 3 {
+			t.Errorf("expected grafana and graph, got %v", terms)
+		}
+	})
+
+	t.Run("Dictionary error", func(t *testing.T) {
+		mockIdx := &mockIndexer{
+			dictError: errors.New("dict error"),
+		}
+
+		i := NewMockIndexer("", "", nil, nil, mockIdx)
+
+		_, err := i.Terms("text", "gra")
+		if err == nil || err.Error() != "dict error" {
+			t.Errorf("expected 'dict error', got: %v", err)
+		}
+	})
+}
+
+func TestDocFrequency(t *testing.T) {
+	mockIdx := &mockIndexer{
+		terms: map[string][]index.DictEntry{
+			"text": {{Term: "grafana", Count: 3}, {Term: "graph", Count: 1}},
+		},
+	}
+
+	i := NewMockIndexer("", "", nil, nil, mockIdx)
+
+	count, err := i.DocFrequency("text", "graph")
+	if err != nil || count != 1 {
+		t.Errorf("expected graph in 1 document, got %d, %v", count, err)
+	}
+
+	count, err = i.DocFrequency("text", "gra")
+	if err != nil || count != 0 {
+		t.Errorf("expected an unknown term in no documents, got %d, %v", count, err)
+	}
+}
+
 func TestIndex(t *testing.T){
 	t.Run("Success", func(t *testing.T) {
 		mockIdx := &mockIndexer{}
@@ -239,4 +394,35 @@ func TestIndex(t *testing.T){
 			t.Errorf("expected 'index error', got: %v", err)
 		}
 	})
-}
\ No newline at end of file
+}
+func TestDelete(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockIdx := &mockIndexer{}
+		i := NewMockIndexer("", "", nil, nil, mockIdx)
+
+		err := i.Delete("path/to/somewhere")
+		if err != nil {
+			t.Errorf("expected no error, got :%v", err)
+		}
+	})
+
+	t.Run("Delete error", func(t *testing.T) {
+		mockIdx := &mockIndexer{
+			deleteError: errors.New("delete error"),
+		}
+		i := NewMockIndexer("", "", nil, nil, mockIdx)
+
+		err := i.Delete("path/to/somewhere")
+		if err == nil || err.Error() != "delete error" {
+			t.Errorf("expected 'delete error', got: %v", err)
+		}
+	})
+}
+
+func TestNewIndexMapping(t *testing.T) {
+	m := newIndexMapping()
+
+	if err := m.Validate(); err != nil {
+		t.Errorf("expected valid mapping, got: %v", err)
+	}
+}
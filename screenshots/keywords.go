@@ -0,0 +1,463 @@
+package screenshots
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	rake "github.com/afjoseph/RAKE.go"
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+// Keyword extractor names, recorded on every document as tag_extractor
+const (
+	ExtractorRAKE = "rake"
+	ExtractorTFIDF = "tfidf"
+	ExtractorYAKE = "yake"
+)
+
+const (
+	defaultMaxTags = 20
+	// yakeMaxWords is the longest phrase YAKE proposes
+	yakeMaxWords = 3
+)
+
+// Keyword is a word or phrase that describes a text. Higher scores are
+// better, but scores are only comparable between keywords of the same
+// extractor.
+type Keyword struct {
+	Text string `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// KeywordExtractor picks the keywords of a text that become its tags
+type KeywordExtractor interface {
+	// Name identifies the extractor so documents tagged by another one
+	// can be found and tagged again
+	Name() string
+	// Extract returns the keywords of text, best first
+	Extract(text string) []Keyword
+}
+
+// KeywordOptions decide which of the extracted keywords become tags
+type KeywordOptions struct {
+	// MaxTags caps the number of tags per document, 0 means 20
+	MaxTags int `json:"maxTags"`
+	// MinScore drops keywords scoring below it
+	MinScore float64 `json:"minScore"`
+}
+
+// NewKeywordExtractor returns the extractor called name. TF-IDF reads how
+// common words are from corpus.
+func NewKeywordExtractor(name string, corpus CorpusStats) (KeywordExtractor, error) {
+	switch name {
+	case ExtractorRAKE:
+		return &RAKE{}, nil
+	case ExtractorTFIDF:
+		return &TFIDF{Corpus: corpus}, nil
+	case ExtractorYAKE:
+		return &YAKE{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown keyword extractor %q", name)
+}
+
+// SetKeywordExtractor switches the extractor that tags screenshots to the
+// one called name, with opts deciding which keywords become tags. Documents
+// already indexed keep their tags until RecomputeTags or the next scan.
+func (s *ScreenshotService) SetKeywordExtractor(name string, opts KeywordOptions) error {
+	if opts.MaxTags < 0 {
+		return fmt.Errorf("max tags must not be negative, got %d", opts.MaxTags)
+	}
+
+	extractor, err := NewKeywordExtractor(name, s.Indexer)
+	if err != nil {
+		return err
+	}
+
+	s.keywordsMu.Lock()
+	defer s.keywordsMu.Unlock()
+	s.Keywords = extractor
+	s.KeywordOptions = opts
+
+	return nil
+}
+
+// keywords returns the extractor and options to tag with, RAKE when no
+// extractor is set
+func (s *ScreenshotService) keywords() (KeywordExtractor, KeywordOptions) {
+	s.keywordsMu.RLock()
+	defer s.keywordsMu.RUnlock()
+
+	if s.Keywords == nil {
+		return &RAKE{}, s.KeywordOptions
+	}
+	return s.Keywords, s.KeywordOptions
+}
+
+// tagDoc sets the tags of doc from its text, leaving out the normalizer's
+// stop words
+func (s *ScreenshotService) tagDoc(doc *ScreenshotDoc) {
+	extractor, opts := s.keywords()
+
+	text := doc.Text
+	if s.Normalizer != nil {
+		text = s.Normalizer.KeywordText(text)
+	}
+
+	doc.Tags = selectTags(extractor.Extract(text), opts)
+	doc.TagExtractor = extractor.Name()
+}
+
+func selectTags(keywords []Keyword, opts KeywordOptions) []string {
+	maxTags := opts.MaxTags
+	if maxTags <= 0 {
+		maxTags = defaultMaxTags
+	}
+
+	tags := make([]string, 0, min(maxTags, len(keywords)))
+	for _, k := range keywords {
+		if len(tags) == maxTags {
+			break
+		}
+		if k.Score >= opts.MinScore {
+			tags = append(tags, k.Text)
+		}
+	}
+
+	return tags
+}
+
+// RAKE scores phrases between stop words by how often their words appear
+// and how many other words they appear with
+type RAKE struct{}
+
+func (r *RAKE) Name() string {
+	return ExtractorRAKE
+}
+
+func (r *RAKE) Extract(text string) []Keyword {
+	candidates := rake.RunRake(text)
+
+	keywords := make([]Keyword, 0, len(candidates))
+	for _, c := range candidates {
+		keywords = append(keywords, Keyword{Text: c.Key, Score: c.Value})
+	}
+
+	return keywords
+}
+
+// CorpusStats tells how common a word is across the indexed text
+type CorpusStats interface {
+	DocCount() (uint64, error)
+	DocFrequency(field string, term string) (uint64, error)
+}
+
+// TFIDF scores single words by how often they appear in the text, weighed
+// down by how many indexed screenshots they appear in, so the words that set
+// a screenshot apart win over ones on every screen (menu bars, app names).
+// Without corpus statistics it falls back to plain term frequency.
+type TFIDF struct {
+	Corpus CorpusStats
+}
+
+func (t *TFIDF) Name() string {
+	return ExtractorTFIDF
+}
+
+func (t *TFIDF) Extract(text string) []Keyword {
+	counts := make(map[string]int)
+	total := 0
+	for _, sentence := range splitSentences(text) {
+		for _, word := range sentence {
+			w := strings.ToLower(word)
+			if isKeywordWord(w) {
+				counts[w]++
+				total++
+			}
+		}
+	}
+
+	var docs uint64
+	if t.Corpus != nil {
+		docs, _ = t.Corpus.DocCount()
+	}
+
+	keywords := make([]Keyword, 0, len(counts))
+	for word, count := range counts {
+		idf := 1.0
+		if docs > 0 {
+			df, err := t.Corpus.DocFrequency("text", word)
+			if err == nil {
+				// smoothed so words not indexed yet still get a finite weight
+				idf = math.Log(float64(docs+1)/float64(df+1)) + 1
+			}
+		}
+		keywords = append(keywords, Keyword{Text: word, Score: float64(count) / float64(total) * idf})
+	}
+
+	sortKeywords(keywords)
+
+	return keywords
+}
+
+// YAKE implements Yet Another Keyword Extractor (Campos et al., 2020), which
+// scores phrases of up to three words from statistics of the text alone:
+// casing, position, frequency, how many different words surround a word and
+// how many sentences it appears in. Unlike RAKE it favours words that stand
+// out over words that are merely repeated.
+type YAKE struct{}
+
+func (y *YAKE) Name() string {
+	return ExtractorYAKE
+}
+
+// yakeTerm gathers the statistics of a word over the whole text
+type yakeTerm struct {
+	count int
+	upper int
+	acronym int
+	sentences []int
+	left map[string]int
+	right map[string]int
+	stop bool
+}
+
+func (y *YAKE) Extract(text string) []Keyword {
+	sentences := splitSentences(text)
+	terms := make(map[string]*yakeTerm)
+
+	for i, sentence := range sentences {
+		for j, word := range sentence {
+			w := strings.ToLower(word)
+			t, ok := terms[w]
+			if !ok {
+				t = &yakeTerm{left: make(map[string]int), right: make(map[string]int), stop: !isKeywordWord(w)}
+				terms[w] = t
+			}
+
+			t.count++
+			if len(t.sentences) == 0 || t.sentences[len(t.sentences)-1] != i {
+				t.sentences = append(t.sentences, i)
+			}
+			if utf8.RuneCountInString(word) > 1 && strings.ToUpper(word) == word && strings.ToLower(word) != word {
+				t.acronym++
+			} else if first, _ := utf8.DecodeRuneInString(word); j > 0 && unicode.IsUpper(first) {
+				t.upper++
+			}
+
+			if j > 0 {
+				t.left[strings.ToLower(sentence[j-1])]++
+			}
+			if j < len(sentence)-1 {
+				t.right[strings.ToLower(sentence[j+1])]++
+			}
+		}
+	}
+
+	scores := yakeTermScores(terms, len(sentences))
+
+	// candidates are phrases of up to three words that don't start or end
+	// with a stop word
+	phraseCounts := make(map[string]int)
+	phraseWords := make(map[string][]string)
+	for _, sentence := range sentences {
+		for i := range sentence {
+			for n := 1; n <= yakeMaxWords && i+n <= len(sentence); n++ {
+				words := make([]string, n)
+				for k := range words {
+					words[k] = strings.ToLower(sentence[i+k])
+				}
+				if terms[words[0]].stop || terms[words[n-1]].stop {
+					continue
+				}
+
+				phrase := strings.Join(words, " ")
+				phraseCounts[phrase]++
+				phraseWords[phrase] = words
+			}
+		}
+	}
+
+	keywords := make([]Keyword, 0, len(phraseCounts))
+	for phrase, count := range phraseCounts {
+		product, sum := 1.0, 0.0
+		for _, w := range phraseWords[phrase] {
+			if terms[w].stop {
+				continue
+			}
+			product *= scores[w]
+			sum += scores[w]
+		}
+
+		// YAKE scores are better the lower they are, flip them so higher
+		// is better like the other extractors
+		score := product / (float64(count) * (1 + sum))
+		keywords = append(keywords, Keyword{Text: phrase, Score: 1 / (1 + score)})
+	}
+
+	sortKeywords(keywords)
+
+	return keywords
+}
+
+// yakeTermScores computes the YAKE score of every word that isn't a stop word
+func yakeTermScores(terms map[string]*yakeTerm, sentenceCount int) map[string]float64 {
+	counts := make([]float64, 0, len(terms))
+	maxCount := 0.0
+	for _, t := range terms {
+		if !t.stop {
+			counts = append(counts, float64(t.count))
+			maxCount = max(maxCount, float64(t.count))
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+
+	var mean, variance float64
+	for _, c := range counts {
+		mean += c
+	}
+	mean /= float64(len(counts))
+	for _, c := range counts {
+		variance += (c - mean) * (c - mean)
+	}
+	std := math.Sqrt(variance / float64(len(counts)))
+
+	scores := make(map[string]float64, len(counts))
+	for w, t := range terms {
+		if t.stop {
+			continue
+		}
+
+		tf := float64(t.count)
+		casing := float64(max(t.upper, t.acronym)) / (1 + math.Log(tf))
+		position := math.Log(math.Log(3 + medianInt(t.sentences)))
+		frequency := tf / (mean + std)
+		relatedness := 1 + (spread(t.left)+spread(t.right))*tf/maxCount
+		different := float64(len(t.sentences)) / float64(sentenceCount)
+
+		scores[w] = relatedness * position / (casing + frequency/relatedness + different/relatedness)
+	}
+
+	return scores
+}
+
+// spread is the share of distinct words among the neighbours of a word
+func spread(neighbours map[string]int) float64 {
+	total := 0
+	for _, n := range neighbours {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+
+	return float64(len(neighbours)) / float64(total)
+}
+
+func medianInt(values []int) float64 {
+	n := len(values)
+	if n%2 == 1 {
+		return float64(values[n/2])
+	}
+
+	return float64(values[n/2-1]+values[n/2]) / 2
+}
+
+var (
+	// words may hold dots between letters or digits, like 2.3s or v4.2
+	wordPattern = regexp.MustCompile(`[\p{L}\p{N}](?:[\p{L}\p{N}'_-]|\.[\p{L}\p{N}])*`)
+	stopWords = func() map[string]struct{} {
+		words := make(map[string]struct{}, len(rake.StopWordsSlice))
+		for _, w := range rake.StopWordsSlice {
+			words[w] = struct{}{}
+		}
+		return words
+	}()
+)
+
+// splitSentences splits text into sentences of words the way RAKE does, so
+// OCR'd lines and punctuation end a sentence
+func splitSentences(text string) [][]string {
+	sentences := make([][]string, 0)
+	for _, s := range rake.SplitSentences(text) {
+		if words := wordPattern.FindAllString(s, -1); len(words) > 0 {
+			sentences = append(sentences, words)
+		}
+	}
+
+	return sentences
+}
+
+// isKeywordWord reports whether a lowercased word may be part of a keyword:
+// no stop words, numbers or single letters
+func isKeywordWord(word string) bool {
+	if _, ok := stopWords[word]; ok {
+		return false
+	}
+	if utf8.RuneCountInString(word) < 2 {
+		return false
+	}
+
+	return strings.IndexFunc(word, unicode.IsLetter) >= 0
+}
+
+func sortKeywords(keywords []Keyword) {
+	sort.Slice(keywords, func(a, b int) bool {
+		if keywords[a].Score != keywords[b].Score {
+			return keywords[a].Score > keywords[b].Score
+		}
+		return keywords[a].Text < keywords[b].Text
+	})
+}
+
+// RecomputeTags extracts the tags of indexed documents again from their
+// stored text, without running OCR. Unless all is set only documents tagged
+// by another extractor than the current one are updated. It returns the
+// number of documents updated.
+func (s *ScreenshotService) RecomputeTags(all bool) (int, error) {
+	err := s.Indexer.Open()
+	if err != nil {
+		return 0, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	extractor, _ := s.keywords()
+	name := extractor.Name()
+
+	updated := 0
+	err = s.eachDocument([]string{"*"}, func(hit *search.DocumentMatch) error {
+		if extractor, _ := hit.Fields["tag_extractor"].(string); !all && extractor == name {
+			return nil
+		}
+
+		doc, err := docFromFields(hit)
+		if err != nil {
+			return err
+		}
+		s.tagDoc(doc)
+		s.embed(doc)
+
+		if err := s.Indexer.Index(doc.Path, doc); err != nil {
+			return fmt.Errorf("error indexing %s: %v", doc.Path, err)
+		}
+		updated++
+
+		return nil
+	})
+	if err != nil {
+		return updated, err
+	}
+	s.logger().Info("tags extracted again", "extractor", name, "updated", updated)
+
+	if updated > 0 {
+		s.refreshCollections()
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,180 @@
+package screenshots
+
+import (
+	"context"
+	"testing"
+
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+const testKeywordText = `Grafana - API Latency Dashboard
+The checkout service p99 latency spiked to 2.3s after the deploy of version 4.2.
+Error rate for checkout service rose to 5%. Postgres connection pool exhausted.
+Settings Help Logout`
+
+func keywordTexts(keywords []Keyword) []string {
+	texts := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		texts = append(texts, k.Text)
+	}
+	return texts
+}
+
+func containsText(texts []string, text string) bool {
+	for _, t := range texts {
+		if t == text {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRAKE(t *testing.T) {
+	keywords := (&RAKE{}).Extract(testKeywordText)
+	if len(keywords) == 0 || keywords[0].Text != "checkout service p99 latency spiked" {
+		t.Errorf("expected the longest connected phrase first, got %v", keywords)
+	}
+}
+
+func TestTFIDF(t *testing.T) {
+	t.Run("Term frequency only", func(t *testing.T) {
+		keywords := (&TFIDF{}).Extract(testKeywordText)
+		top := keywordTexts(keywords[:3])
+		for _, word := range []string{"checkout", "latency", "service"} {
+			if !containsText(top, word) {
+				t.Errorf("expected %q among the most frequent words, got %v", word, top)
+			}
+		}
+		if containsText(keywordTexts(keywords), "the") || containsText(keywordTexts(keywords), "4.2") {
+			t.Errorf("expected stop words and numbers to be skipped, got %v", keywords)
+		}
+	})
+
+	t.Run("Common words weighed down", func(t *testing.T) {
+		corpus := &Indexer{idx: &mockIndexer{
+			docCount: 1000,
+			terms: map[string][]index.DictEntry{
+				"text": {{Term: "checkout", Count: 900}, {Term: "latency", Count: 3}, {Term: "service", Count: 950}},
+			},
+		}}
+
+		keywords := (&TFIDF{Corpus: corpus}).Extract(testKeywordText)
+		if keywords[0].Text != "latency" {
+			t.Errorf("expected the rare word first, got %v", keywords[:3])
+		}
+	})
+}
+
+func TestYAKE(t *testing.T) {
+	keywords := (&YAKE{}).Extract(testKeywordText)
+	texts := keywordTexts(keywords)
+
+	if len(texts) < 5 || !containsText(texts[:5], "api latency dashboard") || !containsText(texts[:5], "grafana") {
+		t.Errorf("expected the capitalised title words near the top, got %v", texts)
+	}
+	for _, text := range texts {
+		words := splitSentences(text)[0]
+		if !isKeywordWord(words[0]) || !isKeywordWord(words[len(words)-1]) {
+			t.Errorf("expected phrases not to start or end with a stop word, got %q", text)
+		}
+		if len(words) > yakeMaxWords {
+			t.Errorf("expected at most %d words, got %q", yakeMaxWords, text)
+		}
+	}
+
+	if keywords := (&YAKE{}).Extract("the of and"); len(keywords) != 0 {
+		t.Errorf("expected no keywords from stop words, got %v", keywords)
+	}
+}
+
+func TestSelectTags(t *testing.T) {
+	keywords := []Keyword{{Text: "a", Score: 3}, {Text: "b", Score: 2}, {Text: "c", Score: 1}}
+
+	if tags := selectTags(keywords, KeywordOptions{MaxTags: 2}); len(tags) != 2 || tags[1] != "b" {
+		t.Errorf("expected the two best, got %v", tags)
+	}
+	if tags := selectTags(keywords, KeywordOptions{MinScore: 2}); len(tags) != 2 {
+		t.Errorf("expected keywords below 2 to be dropped, got %v", tags)
+	}
+	if tags := selectTags(nil, KeywordOptions{}); tags == nil || len(tags) != 0 {
+		t.Errorf("expected an empty list, got %v", tags)
+	}
+}
+
+func TestNewKeywordExtractor(t *testing.T) {
+	for _, name := range []string{ExtractorRAKE, ExtractorTFIDF, ExtractorYAKE} {
+		e, err := NewKeywordExtractor(name, nil)
+		if err != nil || e.Name() != name {
+			t.Errorf("%s: expected the extractor, got %v, %v", name, e, err)
+		}
+	}
+
+	if _, err := NewKeywordExtractor("magic", nil); err == nil {
+		t.Error("expected an error for an unknown extractor")
+	}
+}
+
+func TestSetKeywordExtractor(t *testing.T) {
+	s := &ScreenshotService{Indexer: &Indexer{idx: newTestIndex(t, testDocs()...)}}
+
+	if err := s.SetKeywordExtractor(ExtractorTFIDF, KeywordOptions{MaxTags: 5, MinScore: 0.1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	extractor, opts := s.keywords()
+	if tfidf, ok := extractor.(*TFIDF); !ok || tfidf.Corpus == nil || opts.MaxTags != 5 || opts.MinScore != 0.1 {
+		t.Errorf("expected TF-IDF over the index with the options, got %#v, %+v", extractor, opts)
+	}
+
+	if err := s.SetKeywordExtractor("magic", KeywordOptions{}); err == nil {
+		t.Error("expected an error for an unknown extractor")
+	}
+	if err := s.SetKeywordExtractor(ExtractorYAKE, KeywordOptions{MaxTags: -1}); err == nil {
+		t.Error("expected an error for a negative tag count")
+	}
+	if extractor, _ := s.keywords(); extractor.Name() != ExtractorTFIDF {
+		t.Errorf("expected a failed change to keep TF-IDF, got %s", extractor.Name())
+	}
+}
+
+func TestRecomputeTags(t *testing.T) {
+	docs := testDocs()
+	docs[0].Text = testKeywordText
+	docs[0].TagExtractor = ExtractorRAKE
+	docs[1].Text = "Meeting notes: Error budget review"
+	docs[1].TagExtractor = ExtractorYAKE
+
+	s := &ScreenshotService{
+		Indexer: &Indexer{idx: newTestIndex(t, docs...)},
+		Saved: newTestSavedSearches(t),
+		Keywords: &YAKE{},
+		KeywordOptions: KeywordOptions{MaxTags: 3},
+		ctx: context.Background(),
+	}
+
+	updated, err := s.RecomputeTags(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// grafana was tagged by RAKE and trace by nothing recorded
+	if updated != 2 {
+		t.Errorf("expected 2 documents to be tagged again, got %d", updated)
+	}
+
+	doc, err := s.storedDoc(docs[0].Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.TagExtractor != ExtractorYAKE || len(doc.Tags) != 3 || doc.Tags[0] != "api latency dashboard" {
+		t.Errorf("expected three YAKE tags, got %v by %q", doc.Tags, doc.TagExtractor)
+	}
+	if doc.Text != testKeywordText {
+		t.Errorf("expected the text to be kept, got %q", doc.Text)
+	}
+
+	if updated, _ := s.RecomputeTags(false); updated != 0 {
+		t.Errorf("expected nothing left to update, got %d", updated)
+	}
+	if updated, _ := s.RecomputeTags(true); updated != 3 {
+		t.Errorf("expected all documents to be updated, got %d", updated)
+	}
+}
@@ -0,0 +1,219 @@
+package screenshots
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Defaults of the app's log, glimpse.log in the logs folder of the app
+// data dir
+const (
+	LogFileName = "glimpse.log"
+	DefaultLogMaxBytes = 5 << 20
+	DefaultLogMaxFiles = 3
+	DefaultRecentLogs = 200
+)
+
+// ParseLogLevel parses debug, info, warn or error, an empty level is info
+func ParseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if strings.TrimSpace(level) == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", level)
+	}
+
+	return l, nil
+}
+
+// LogDir returns the folder the app writes its logs to
+func LogDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting user config dir: %v", err)
+	}
+
+	return filepath.Join(configDir, "Glimpse", "logs"), nil
+}
+
+// LogFile is a log file that is rotated once it grows past maxBytes: the
+// current log becomes glimpse.log.1, glimpse.log.1 becomes glimpse.log.2
+// and so on, keeping maxFiles old logs. It is safe for concurrent writes.
+type LogFile struct {
+	mu sync.Mutex
+	path string
+	maxBytes int64
+	maxFiles int
+	file *os.File
+	size int64
+}
+
+// OpenLogFile opens the log at path for appending, creating its folder
+func OpenLogFile(path string, maxBytes int64, maxFiles int) (*LogFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating log dir: %v", err)
+	}
+
+	f := &LogFile{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *LogFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error opening log file: %v", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// Path returns the path of the current log
+func (f *LogFile) Path() string {
+	return f.path
+}
+
+// Write appends p to the log, rotating it first if p would take it past
+// its size. A single record bigger than the limit is still written whole,
+// and so is p when rotating fails, the log then grows until it can rotate.
+func (f *LogFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxBytes {
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current log aside and opens a new one. The current
+// path is opened again whatever fails, so logging never stops.
+func (f *LogFile) rotate() error {
+	rotateErr := f.file.Close()
+	f.file = nil
+
+	if rotateErr == nil {
+		rotateErr = f.shift()
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	if rotateErr != nil {
+		return fmt.Errorf("error rotating log file: %v", rotateErr)
+	}
+
+	return nil
+}
+
+// shift renames every log to the next number, the oldest log is
+// overwritten by the one before it
+func (f *LogFile) shift() error {
+	for i := f.maxFiles - 1; i > 0; i-- {
+		os.Rename(rotatedLog(f.path, i), rotatedLog(f.path, i+1))
+	}
+	if f.maxFiles > 0 {
+		return os.Rename(f.path, rotatedLog(f.path, 1))
+	}
+
+	return os.Remove(f.path)
+}
+
+func (f *LogFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func rotatedLog(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// RecentLogs returns the last n lines of the log at path, oldest first. The
+// rotated log is read too when the current one is shorter, a missing log
+// has no lines.
+func RecentLogs(path string, n int) ([]string, error) {
+	if n <= 0 {
+		n = DefaultRecentLogs
+	}
+
+	var lines []string
+	for _, file := range []string{path, rotatedLog(path, 1)} {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading log file: %v", err)
+		}
+
+		older := splitLines(data)
+		lines = append(older, lines...)
+		if len(lines) >= n {
+			break
+		}
+	}
+
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if lines == nil {
+		lines = make([]string, 0)
+	}
+
+	return lines, nil
+}
+
+func splitLines(data []byte) []string {
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return nil
+	}
+
+	return strings.Split(string(data), "\n")
+}
+
+// NewLogger returns a logger writing text records of level and above to w
+func NewLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// logger returns the logger of the service, slog.Default() unless Log is
+// set
+func (s *ScreenshotService) logger() *slog.Logger {
+	if s.Log == nil {
+		return slog.Default()
+	}
+
+	return s.Log
+}
@@ -0,0 +1,102 @@
+package screenshots
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input string
+		expected slog.Level
+		err bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"debug", slog.LevelDebug, false},
+		{" WARN ", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"loud", slog.LevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		level, err := ParseLogLevel(tt.input)
+		if level != tt.expected || (err != nil) != tt.err {
+			t.Errorf("expected %v for %q, got %v, %v", tt.expected, tt.input, level, err)
+		}
+	}
+}
+
+func TestLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", LogFileName)
+	f, err := OpenLogFile(path, 100, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 40 bytes a line, two lines fit in a log
+	for i := 0; i < 7; i++ {
+		fmt.Fprintf(f, "line %d %s\n", i, strings.Repeat("x", 32))
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 rotated logs, got %v", err)
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected %s, got %v", filepath.Base(name), err)
+		}
+	}
+	if _, err := f.Write([]byte("closed\n")); err == nil {
+		t.Error("expected an error writing to a closed log")
+	}
+
+	// the current log has line 6, the rotated ones lines 2 to 5
+	lines, err := RecentLogs(path, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := make([]string, 0, len(lines))
+	for _, line := range lines {
+		got = append(got, strings.Fields(line)[1])
+	}
+	if expected := []string{"4", "5", "6"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected lines %v, got %v", expected, got)
+	}
+
+	lines, err = RecentLogs(filepath.Join(t.TempDir(), LogFileName), 10)
+	if err != nil || lines == nil || len(lines) != 0 {
+		t.Errorf("expected no lines without a log, got %v, %v", lines, err)
+	}
+}
+
+func TestLogFileRotateFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LogFileName)
+	// a folder where the rotated log goes can't be replaced by a file
+	if err := os.MkdirAll(filepath.Join(path+".1", "keep"), 0755); err != nil {
+		t.Fatalf("error creating folder: %v", err)
+	}
+	f, err := OpenLogFile(path, 50, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := fmt.Fprintf(f, "line %d %s\n", i, strings.Repeat("x", 32)); err != nil {
+			t.Fatalf("expected logging to go on, got %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || strings.Count(string(data), "\n") != 4 {
+		t.Errorf("expected every line in the current log, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,276 @@
+package screenshots
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	inchesPerMeter = 0.0254
+	cmPerInch = 2.54
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	jpegSignature = []byte{0xFF, 0xD8}
+	exifHeader = []byte("Exif\x00\x00")
+)
+
+var (
+	// "Screenshot 2025-03-01 at 10.12.33@2x" as saved by CleanShot and others
+	scaleSuffix = regexp.MustCompile(`@(\d)x$`)
+	// ShareX saves captures as "<process>_<10 random chars>"
+	shareXName = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9.\-]*)_[A-Za-z0-9]{10}$`)
+	// Greenshot saves captures as "<yyyy-MM-dd HH_mm_ss>-<window title>"
+	greenshotName = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}_\d{2}_\d{2}-(.+)$`)
+)
+
+// applyMetadata fills in the file, image and source metadata of doc from the
+// file's stat info and raw bytes
+func applyMetadata(doc *ScreenshotDoc, path string, info os.FileInfo, data []byte) {
+	doc.Dir = filepath.Dir(path)
+	doc.Ext = strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	doc.Size = info.Size()
+	doc.Modified = info.ModTime()
+	doc.Created = fileCreated(path, info)
+	doc.Month = doc.Created.Format("2006-01")
+
+	applyImageMetadata(doc, data)
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if m := scaleSuffix.FindStringSubmatch(name); m != nil {
+		doc.Scale, _ = strconv.ParseFloat(m[1], 64)
+	}
+
+	doc.SourceApp, doc.WindowTitle = sourceFromXattr(path)
+	if doc.SourceApp == "" && doc.WindowTitle == "" {
+		doc.SourceApp, doc.WindowTitle = sourceFromFilename(name)
+	}
+}
+
+// applyImageMetadata reads pixel dimensions, DPI and EXIF tags from the
+// encoded image without decoding the pixels
+func applyImageMetadata(doc *ScreenshotDoc, data []byte) {
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		doc.Width = cfg.Width
+		doc.Height = cfg.Height
+	}
+
+	var exifData []byte
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		doc.DPI, exifData = readPNGChunks(data)
+	case bytes.HasPrefix(data, jpegSignature):
+		doc.DPI, exifData = readJPEGSegments(data)
+	}
+
+	if exifData != nil {
+		if tags, err := parseEXIF(exifData); err == nil && len(tags) > 0 {
+			doc.EXIF = tags
+			if doc.DPI == 0 {
+				doc.DPI = exifDPI(tags)
+			}
+		}
+	}
+
+	if doc.DPI > 0 && doc.Scale == 0 {
+		doc.Scale = doc.DPI / baseDPI
+	}
+}
+
+// readPNGChunks returns the DPI from the pHYs chunk and the raw eXIf chunk
+func readPNGChunks(data []byte) (float64, []byte) {
+	var dpi float64
+	var exifData []byte
+
+	offset := len(pngSignature)
+	for offset+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		typ := string(data[offset+4 : offset+8])
+		start := offset + 8
+		if length < 0 || start+length > len(data) {
+			break
+		}
+		chunk := data[start : start+length]
+
+		switch typ {
+		case "pHYs":
+			// unit 1 is pixels per meter, 0 only gives the aspect ratio
+			if len(chunk) == 9 && chunk[8] == 1 {
+				dpi = float64(binary.BigEndian.Uint32(chunk[0:4])) * inchesPerMeter
+			}
+		case "eXIf":
+			exifData = chunk
+		case "IDAT", "IEND":
+			// metadata chunks must come before the image data
+			return roundDPI(dpi), exifData
+		}
+
+		// skip the chunk data and its CRC
+		offset = start + length + 4
+	}
+
+	return roundDPI(dpi), exifData
+}
+
+// readJPEGSegments returns the DPI from the JFIF header and the TIFF data of
+// the Exif APP1 segment
+func readJPEGSegments(data []byte) (float64, []byte) {
+	var dpi float64
+	var exifData []byte
+
+	offset := len(jpegSignature)
+	for offset+4 <= len(data) && data[offset] == 0xFF {
+		marker := data[offset+1]
+		// start of scan, no more metadata segments
+		if marker == 0xDA {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		start := offset + 4
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		segment := data[start:end]
+
+		switch {
+		case marker == 0xE0 && bytes.HasPrefix(segment, []byte("JFIF\x00")) && len(segment) >= 12:
+			density := float64(binary.BigEndian.Uint16(segment[8:10]))
+			switch segment[7] {
+			case 1:
+				dpi = density
+			case 2:
+				dpi = density * cmPerInch
+			}
+		case marker == 0xE1 && bytes.HasPrefix(segment, exifHeader):
+			exifData = segment[len(exifHeader):]
+		}
+
+		offset = end
+	}
+
+	return roundDPI(dpi), exifData
+}
+
+func exifDPI(tags map[string]string) float64 {
+	res, err := strconv.ParseFloat(tags["XResolution"], 64)
+	if err != nil {
+		return 0
+	}
+
+	// ResolutionUnit 3 is centimeters, anything else is inches
+	if tags["ResolutionUnit"] == "3" {
+		res *= cmPerInch
+	}
+
+	return roundDPI(res)
+}
+
+// roundDPI hides the rounding error of the pixels per meter conversion,
+// 5669 px/m should read as 144 DPI
+func roundDPI(dpi float64) float64 {
+	return float64(int(dpi + 0.5))
+}
+
+// sourceFromFilename guesses the application and window title from the
+// naming schemes of common capture tools
+func sourceFromFilename(name string) (string, string) {
+	name = scaleSuffix.ReplaceAllString(name, "")
+
+	if m := greenshotName.FindStringSubmatch(name); m != nil {
+		title := strings.TrimSpace(m[1])
+
+		// window titles usually end with " - <application>"
+		app := ""
+		if idx := strings.LastIndex(title, " - "); idx != -1 {
+			app = strings.TrimSpace(title[idx+3:])
+		}
+
+		return app, title
+	}
+
+	if m := shareXName.FindStringSubmatch(name); m != nil {
+		return m[1], ""
+	}
+
+	return "", ""
+}
+
+var errInvalidPlist = errors.New("invalid binary plist")
+
+// parseBinaryPlistString reads a binary plist whose top object is a string,
+// which is how macOS stores kMDItem* extended attributes
+func parseBinaryPlistString(data []byte) (string, error) {
+	if len(data) < 8+32 || !bytes.HasPrefix(data, []byte("bplist00")) {
+		return "", errInvalidPlist
+	}
+
+	trailer := data[len(data)-32:]
+	offsetSize := int(trailer[6])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	offsetTable := binary.BigEndian.Uint64(trailer[24:32])
+
+	entry := offsetTable + topObject*uint64(offsetSize)
+	if offsetSize == 0 || offsetSize > 8 || entry+uint64(offsetSize) > uint64(len(data)) {
+		return "", errInvalidPlist
+	}
+
+	var offset uint64
+	for _, b := range data[entry : entry+uint64(offsetSize)] {
+		offset = offset<<8 | uint64(b)
+	}
+	if offset >= uint64(len(data)) {
+		return "", errInvalidPlist
+	}
+
+	marker := data[offset]
+	length := uint64(marker & 0x0F)
+	start := offset + 1
+
+	// lengths of 15 and over follow the marker as an int object
+	if length == 0x0F {
+		if start >= uint64(len(data)) || data[start]&0xF0 != 0x10 {
+			return "", errInvalidPlist
+		}
+		size := uint64(1) << (data[start] & 0x0F)
+		start++
+		if start+size > uint64(len(data)) {
+			return "", errInvalidPlist
+		}
+		length = 0
+		for _, b := range data[start : start+size] {
+			length = length<<8 | uint64(b)
+		}
+		start += size
+	}
+
+	switch marker & 0xF0 {
+	case 0x50: // ASCII
+		if start+length > uint64(len(data)) {
+			return "", errInvalidPlist
+		}
+		return string(data[start : start+length]), nil
+	case 0x60: // UTF-16BE
+		if start+length*2 > uint64(len(data)) {
+			return "", errInvalidPlist
+		}
+		units := make([]uint16, length)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(data[start+uint64(i)*2:])
+		}
+		return string(utf16.Decode(units)), nil
+	}
+
+	return "", errInvalidPlist
+}
@@ -0,0 +1,43 @@
+//go:build darwin
+
+package screenshots
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// macOS renders screenshots at 72 points per inch, so a 144 DPI capture was
+// taken on a 2x display
+const baseDPI = 72.0
+
+// creatorXattr is set by apps that save files through the standard save
+// panel and by several capture tools
+const creatorXattr = "com.apple.metadata:kMDItemCreator"
+
+func fileCreated(path string, info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Birthtimespec.Unix())
+	}
+
+	return info.ModTime()
+}
+
+func sourceFromXattr(path string) (string, string) {
+	buf := make([]byte, 1024)
+
+	n, err := unix.Getxattr(path, creatorXattr, buf)
+	if err != nil {
+		return "", ""
+	}
+
+	app, err := parseBinaryPlistString(buf[:n])
+	if err != nil {
+		return "", ""
+	}
+
+	return app, ""
+}
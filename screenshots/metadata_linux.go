@@ -0,0 +1,28 @@
+//go:build linux
+
+package screenshots
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const baseDPI = 96.0
+
+func fileCreated(path string, info os.FileInfo) time.Time {
+	var stx unix.Statx_t
+
+	err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &stx)
+	if err != nil || stx.Mask&unix.STATX_BTIME == 0 {
+		return info.ModTime()
+	}
+
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))
+}
+
+// Linux desktops don't record the capturing application on the file
+func sourceFromXattr(path string) (string, string) {
+	return "", ""
+}
@@ -0,0 +1,18 @@
+//go:build !darwin && !linux && !windows
+
+package screenshots
+
+import (
+	"os"
+	"time"
+)
+
+const baseDPI = 96.0
+
+func fileCreated(path string, info os.FileInfo) time.Time {
+	return info.ModTime()
+}
+
+func sourceFromXattr(path string) (string, string) {
+	return "", ""
+}
@@ -0,0 +1,192 @@
+package screenshots
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+type mockFileInfo struct {
+	name string
+	size int64
+	modTime time.Time
+}
+
+func (m *mockFileInfo) Name() string { return m.name }
+func (m *mockFileInfo) Size() int64 { return m.size }
+func (m *mockFileInfo) Mode() fs.FileMode { return 0644 }
+func (m *mockFileInfo) ModTime() time.Time { return m.modTime }
+func (m *mockFileInfo) IsDir() bool { return false }
+func (m *mockFileInfo) Sys() any { return nil }
+
+func pngChunk(typ string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], typ)
+	chunk = append(chunk, data...)
+
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+
+	return append(chunk, crc...)
+}
+
+// newTestPNG encodes a PNG and inserts the given chunks right after IHDR
+func newTestPNG(t *testing.T, width, height int, chunks ...[]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("error encoding test png: %v", err)
+	}
+	data := buf.Bytes()
+
+	// signature + IHDR (length, type, 13 bytes of data, crc)
+	ihdrEnd := len(pngSignature) + 8 + 13 + 4
+
+	out := append([]byte{}, data[:ihdrEnd]...)
+	for _, chunk := range chunks {
+		out = append(out, chunk...)
+	}
+
+	return append(out, data[ihdrEnd:]...)
+}
+
+func physChunk(ppm uint32) []byte {
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint32(data[0:], ppm)
+	binary.BigEndian.PutUint32(data[4:], ppm)
+	data[8] = 1
+
+	return pngChunk("pHYs", data)
+}
+
+func TestApplyImageMetadata(t *testing.T) {
+	t.Run("PNG with pHYs and eXIf", func(t *testing.T) {
+		exif := buildTestEXIF([]testIFDEntry{asciiEntry(0x0131, "macOS 15.3")}, nil)
+		data := newTestPNG(t, 32, 16, physChunk(5669), pngChunk("eXIf", exif))
+
+		doc := ScreenshotDoc{}
+		applyImageMetadata(&doc, data)
+
+		if doc.Width != 32 || doc.Height != 16 {
+			t.Errorf("expected 32x16, got: %dx%d", doc.Width, doc.Height)
+		}
+		if doc.DPI != 144 {
+			t.Errorf("expected 144 DPI, got: %v", doc.DPI)
+		}
+		if doc.Scale != 144/baseDPI {
+			t.Errorf("expected scale %v, got: %v", 144/baseDPI, doc.Scale)
+		}
+		if doc.EXIF["Software"] != "macOS 15.3" {
+			t.Errorf("expected exif software 'macOS 15.3', got: %v", doc.EXIF)
+		}
+	})
+
+	t.Run("JPEG with JFIF density", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 4)), nil); err != nil {
+			t.Fatalf("error encoding test jpeg: %v", err)
+		}
+		data := buf.Bytes()
+
+		// the encoder writes no APP0, add a JFIF header at 72 DPI
+		jfif := []byte{0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0x01, 0x01, 0x00, 0x48, 0x00, 0x48, 0x00, 0x00}
+		data = append(append(append([]byte{}, data[:2]...), jfif...), data[2:]...)
+
+		doc := ScreenshotDoc{}
+		applyImageMetadata(&doc, data)
+
+		if doc.Width != 8 || doc.Height != 4 {
+			t.Errorf("expected 8x4, got: %dx%d", doc.Width, doc.Height)
+		}
+		if doc.DPI != 72 {
+			t.Errorf("expected 72 DPI, got: %v", doc.DPI)
+		}
+	})
+
+	t.Run("Not an image", func(t *testing.T) {
+		doc := ScreenshotDoc{}
+		applyImageMetadata(&doc, []byte("%PDF-1.7"))
+
+		if doc.Width != 0 || doc.DPI != 0 || doc.EXIF != nil {
+			t.Errorf("expected no metadata, got: %+v", doc)
+		}
+	})
+}
+
+func TestApplyMetadata(t *testing.T) {
+	modTime := time.Date(2025, 3, 1, 10, 12, 33, 0, time.UTC)
+	info := &mockFileInfo{name: "capture.png", size: 2048, modTime: modTime}
+
+	doc := ScreenshotDoc{}
+	applyMetadata(&doc, "/does/not/exist/CleanShot 2025-03-01 at 10.12.33@2x.png", info, newTestPNG(t, 4, 4))
+
+	if doc.Size != 2048 {
+		t.Errorf("expected size 2048, got: %d", doc.Size)
+	}
+	if !doc.Modified.Equal(modTime) || !doc.Created.Equal(modTime) {
+		t.Errorf("expected created and modified to fall back to %v, got: %v, %v", modTime, doc.Created, doc.Modified)
+	}
+	if doc.Scale != 2 {
+		t.Errorf("expected scale 2 from filename, got: %v", doc.Scale)
+	}
+}
+
+func TestSourceFromFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		app string
+		title string
+	}{
+		{name: "2025-03-01 10_12_33-Grafana - Google Chrome", app: "Google Chrome", title: "Grafana - Google Chrome"},
+		{name: "2025-03-01 10_12_33-Terminal", app: "", title: "Terminal"},
+		{name: "chrome_Xy7Pq2LmNa", app: "chrome", title: ""},
+		{name: "WindowsTerminal_Ab12Cd34Ef@2x", app: "WindowsTerminal", title: ""},
+		{name: "Screenshot 2025-03-01 at 10.12.33", app: "", title: ""},
+	}
+
+	for _, tt := range tests {
+		app, title := sourceFromFilename(tt.name)
+		if app != tt.app || title != tt.title {
+			t.Errorf("sourceFromFilename(%q): expected (%q, %q), got (%q, %q)", tt.name, tt.app, tt.title, app, title)
+		}
+	}
+}
+
+func TestParseBinaryPlistString(t *testing.T) {
+	// bplist00 holding the single ASCII string "Xcode"
+	plist := []byte("bplist00")
+	plist = append(plist, 0x55, 'X', 'c', 'o', 'd', 'e')
+	plist = append(plist, 0x08) // offset table: object 0 at offset 8
+	trailer := make([]byte, 32)
+	trailer[6] = 1
+	trailer[7] = 1
+	binary.BigEndian.PutUint64(trailer[8:], 1)
+	binary.BigEndian.PutUint64(trailer[16:], 0)
+	binary.BigEndian.PutUint64(trailer[24:], 14)
+	plist = append(plist, trailer...)
+
+	t.Run("Success", func(t *testing.T) {
+		s, err := parseBinaryPlistString(plist)
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if s != "Xcode" {
+			t.Errorf("expected 'Xcode', got: %q", s)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := parseBinaryPlistString([]byte("not a plist"))
+		if err != errInvalidPlist {
+			t.Errorf("expected errInvalidPlist, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package screenshots
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Windows renders at 96 DPI at 100% scaling
+const baseDPI = 96.0
+
+func fileCreated(path string, info os.FileInfo) time.Time {
+	if attr, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, attr.CreationTime.Nanoseconds())
+	}
+
+	return info.ModTime()
+}
+
+func sourceFromXattr(path string) (string, string) {
+	return "", ""
+}
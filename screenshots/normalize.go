@@ -0,0 +1,428 @@
+package screenshots
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	defaultLanguage = "en"
+	// defaultMinConfidence is the tesseract confidence (0-100) below which a
+	// word is dropped, misreads of icons and borders usually score far less
+	defaultMinConfidence = 40
+	// minCorrectionLength keeps short words, which are often abbreviations
+	// and codes, from being corrected
+	minCorrectionLength = 4
+)
+
+// defaultUIWords are menu and button labels that appear on screenshot after
+// screenshot and say nothing about what they show
+var defaultUIWords = []string{
+	"file", "edit", "view", "window", "help", "history", "bookmarks", "profiles",
+	"tools", "selection", "go", "run", "terminal", "format", "insert",
+	"settings", "preferences", "new", "open", "save", "close", "cancel", "ok",
+	"apply", "back", "forward", "reload", "share", "copy", "paste", "cut",
+	"undo", "redo", "zoom", "minimize", "maximize", "menu", "tab", "tabs",
+	"sign", "login", "logout", "home", "search", "more",
+}
+
+var (
+	// a word broken across lines by a hyphen, continued in lower case
+	hyphenatedBreak = regexp.MustCompile(`(\p{L}+)-[ \t]*\n[ \t]*(\p{Ll}+)`)
+	// an identifier with a code operator, like C++, C# or i++, that isn't
+	// noise even though it is half symbols
+	codeToken = regexp.MustCompile(`^(?:(?:\+\+|--)\p{L}[\p{L}\p{N}_]*|\p{L}[\p{L}\p{N}_]*(?:\+\+|--|#))$`)
+)
+
+// maxRepeatedRun is the most times a character repeats in a row in real
+// words, longer runs like |||| or ____ are borders and input fields.
+// Numbers repeat digits freely, 100000 or 0x80000005.
+const maxRepeatedRun = 3
+
+// enclosing are the brackets, quotes and punctuation around a word, like
+// (x), that don't make it noise
+const enclosing = `()[]{}<>"'.,:;!?`
+
+// normalize cleans up OCR'd text with the service's normalizer, if any
+func (s *ScreenshotService) normalize(text string, words []WordBox) string {
+	if s.Normalizer == nil {
+		return text
+	}
+
+	return s.Normalizer.Normalize(text, words)
+}
+
+// TextNormalizer cleans up OCR output before it is indexed and tagged
+type TextNormalizer struct {
+	// StopWords are left out of keyword extraction on top of each
+	// extractor's own list. They default to common UI labels, which are
+	// still indexed and searchable.
+	StopWords []string
+	// MinConfidence drops words tesseract read with a lower confidence
+	// (0-100). It only applies when word boxes are extracted.
+	MinConfidence float64
+	// Language picks the spell correction dictionary, a word list named
+	// <language>.txt in the dictionaries folder of the Glimpse config
+	// folder. Without one words are left as read.
+	Language string
+
+	appName string
+	o osProvider
+
+	dictOnce sync.Once
+	dict *Dictionary
+	stopOnce sync.Once
+	stop map[string]struct{}
+}
+
+func NewTextNormalizer() *TextNormalizer {
+	return &TextNormalizer{
+		StopWords: defaultUIWords,
+		MinConfidence: defaultMinConfidence,
+		Language: defaultLanguage,
+		appName: "Glimpse",
+		o: &realOsProvider{},
+	}
+}
+
+// Normalize cleans up OCR'd text: Unicode is normalised to NFKC so
+// ligatures and full width characters match what is typed, words hyphenated
+// across lines are put back on one line (see joinHyphenated), symbol noise
+// and low confidence words are dropped, runs of whitespace are collapsed and
+// misread words are corrected against the dictionary. Line breaks are kept since they end sentences for the
+// keyword extractors. words are the word boxes of the text, or nil.
+func (n *TextNormalizer) Normalize(text string, words []WordBox) string {
+	text = norm.NFKC.String(text)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, text)
+
+	unsure := n.lowConfidence(words)
+	dict := n.dictionary()
+	text = joinHyphenated(text, dict)
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		tokens := make([]string, 0)
+		for _, token := range strings.Fields(line) {
+			if isNoise(token) {
+				continue
+			}
+			if _, ok := unsure[token]; ok {
+				continue
+			}
+			if dict != nil {
+				token = dict.correctToken(token)
+			}
+			tokens = append(tokens, token)
+		}
+
+		if len(tokens) > 0 {
+			lines = append(lines, strings.Join(tokens, " "))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// KeywordText returns text with the stop words replaced by line breaks, so
+// keyword extractors neither pick them nor join the words around them into
+// one phrase
+func (n *TextNormalizer) KeywordText(text string) string {
+	n.stopOnce.Do(func() {
+		n.stop = make(map[string]struct{}, len(n.StopWords))
+		for _, w := range n.StopWords {
+			n.stop[strings.ToLower(w)] = struct{}{}
+		}
+	})
+	if len(n.stop) == 0 {
+		return text
+	}
+
+	return wordPattern.ReplaceAllStringFunc(text, func(word string) string {
+		if _, ok := n.stop[strings.ToLower(word)]; ok {
+			return "\n"
+		}
+		return word
+	})
+}
+
+// joinHyphenated puts words hyphenated across lines back on one line. The
+// hyphen is dropped when the dictionary tells a split word, like deploy-ment,
+// from a compound, like well-known, and kept without one.
+func joinHyphenated(text string, dict *Dictionary) string {
+	return hyphenatedBreak.ReplaceAllStringFunc(text, func(match string) string {
+		parts := hyphenatedBreak.FindStringSubmatch(match)
+		first, second := parts[1], parts[2]
+		if dict == nil || (!dict.known(first+second) && dict.known(first) && dict.known(second)) {
+			return first + "-" + second + "\n"
+		}
+		return first + second + "\n"
+	})
+}
+
+// lowConfidence returns the words that were only ever read with a confidence
+// below MinConfidence
+func (n *TextNormalizer) lowConfidence(words []WordBox) map[string]struct{} {
+	unsure := make(map[string]struct{})
+	if n.MinConfidence <= 0 {
+		return unsure
+	}
+
+	sure := make(map[string]struct{})
+	for _, w := range words {
+		if w.Confidence < 0 {
+			continue
+		}
+		text := norm.NFKC.String(w.Text)
+		if w.Confidence < n.MinConfidence {
+			unsure[text] = struct{}{}
+		} else {
+			sure[text] = struct{}{}
+		}
+	}
+	for w := range sure {
+		delete(unsure, w)
+	}
+
+	return unsure
+}
+
+// isNoise reports whether a token is more likely a misread border, icon or
+// separator than text
+func isNoise(token string) bool {
+	core := strings.Trim(token, enclosing)
+	if codeToken.MatchString(core) {
+		return false
+	}
+
+	alnum, digits, other := 0, 0, 0
+	for _, r := range core {
+		switch {
+		case unicode.IsDigit(r):
+			alnum++
+			digits++
+		case unicode.IsLetter(r):
+			alnum++
+		default:
+			other++
+		}
+	}
+
+	if alnum == 0 {
+		return true
+	}
+	if digits == 0 && longestRun(token) > maxRepeatedRun {
+		return true
+	}
+
+	// URLs and paths are mostly letters, a$#% isn't
+	return other > alnum
+}
+
+// longestRun is the most times a character of token repeats in a row
+func longestRun(token string) int {
+	longest, run := 0, 0
+	var previous rune
+	for i, r := range token {
+		if i > 0 && r == previous {
+			run++
+		} else {
+			run = 1
+		}
+		previous = r
+		longest = max(longest, run)
+	}
+
+	return longest
+}
+
+// dictionary loads the dictionary of Language once, a missing or broken
+// one disables spell correction
+func (n *TextNormalizer) dictionary() *Dictionary {
+	n.dictOnce.Do(func() {
+		if n.Language == "" || n.o == nil {
+			return
+		}
+
+		path, err := appDataPath(n.o, n.appName, filepath.Join("dictionaries", n.Language+".txt"))
+		if err != nil {
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+
+		if dict, err := NewDictionary(f); err == nil {
+			n.dict = dict
+		}
+	})
+
+	return n.dict
+}
+
+// Dictionary corrects words that are one OCR confusion or one edit away
+// from a known word
+type Dictionary struct {
+	// words maps each known word to how common it is
+	words map[string]int
+}
+
+// NewDictionary reads a word list with one word per line, optionally
+// followed by its frequency, like the frequency dictionaries of SymSpell.
+// Words without a frequency count as 1. Lines starting with # are skipped.
+func NewDictionary(r io.Reader) (*Dictionary, error) {
+	words := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		count := 1
+		if len(fields) > 1 {
+			c, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid frequency for %q: %v", fields[0], err)
+			}
+			count = c
+		}
+		words[strings.ToLower(norm.NFKC.String(fields[0]))] += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, errors.New("empty dictionary")
+	}
+
+	return &Dictionary{words: words}, nil
+}
+
+// known reports whether word is in the dictionary
+func (d *Dictionary) known(word string) bool {
+	_, ok := d.words[strings.ToLower(word)]
+	return ok
+}
+
+// Correct returns the most common known word that OCR could have misread as
+// word, preferring OCR confusions over other single edits, or word itself
+// if it is known or nothing close is
+func (d *Dictionary) Correct(word string) string {
+	lower := strings.ToLower(word)
+	if _, ok := d.words[lower]; ok || utf8.RuneCountInString(lower) < minCorrectionLength {
+		return word
+	}
+	if strings.IndexFunc(lower, unicode.IsLetter) < 0 {
+		return word
+	}
+
+	best, bestCount := "", 0
+	for _, v := range ocrVariants(lower) {
+		if count := d.words[v]; count > bestCount {
+			best, bestCount = v, count
+		}
+	}
+	if best == "" {
+		for _, v := range singleEdits(lower) {
+			if count := d.words[v]; count > bestCount {
+				best, bestCount = v, count
+			}
+		}
+	}
+	if best == "" {
+		return word
+	}
+
+	return matchCase(word, best)
+}
+
+// correctToken corrects the word inside a token, leaving punctuation around
+// it alone and tokens with digits or symbols inside (versions, paths, IDs)
+// as they are
+func (d *Dictionary) correctToken(token string) string {
+	start := strings.IndexFunc(token, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+	end := strings.LastIndexFunc(token, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+	if start < 0 {
+		return token
+	}
+	_, size := utf8.DecodeRuneInString(token[end:])
+	core := token[start : end+size]
+
+	letters := 0
+	for _, r := range core {
+		if unicode.IsLetter(r) {
+			letters++
+		} else if !unicode.IsDigit(r) {
+			return token
+		}
+	}
+	// mostly digits is a number or ID, not a misread word
+	if letters*2 < utf8.RuneCountInString(core) {
+		return token
+	}
+
+	return token[:start] + d.Correct(core) + token[end+size:]
+}
+
+// singleEdits returns every string one deletion, transposition, substitution
+// or insertion of a lower case letter away from word
+func singleEdits(word string) []string {
+	runes := []rune(word)
+	edits := make([]string, 0, 54*len(runes)+25)
+
+	for i := range runes {
+		edits = append(edits, string(runes[:i])+string(runes[i+1:]))
+		if i+1 < len(runes) {
+			swapped := append([]rune(nil), runes...)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			edits = append(edits, string(swapped))
+		}
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		for i := 0; i <= len(runes); i++ {
+			edits = append(edits, string(runes[:i])+string(c)+string(runes[i:]))
+			if i < len(runes) && runes[i] != c {
+				edits = append(edits, string(runes[:i])+string(c)+string(runes[i+1:]))
+			}
+		}
+	}
+
+	return edits
+}
+
+// matchCase gives correction the capitalisation of word
+func matchCase(word string, correction string) string {
+	if strings.ToUpper(word) == word {
+		return strings.ToUpper(correction)
+	}
+	if first, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(first) {
+		r, size := utf8.DecodeRuneInString(correction)
+		return string(unicode.ToUpper(r)) + correction[size:]
+	}
+
+	return correction
+}
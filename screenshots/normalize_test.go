@@ -0,0 +1,134 @@
+package screenshots
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestNormalizer(t *testing.T, dictionary string) *TextNormalizer {
+	configDir := t.TempDir()
+	dir := filepath.Join(configDir, "Glimpse", "dictionaries")
+	// the mock doesn't create directories
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("error creating config dir: %v", err)
+	}
+	if dictionary != "" {
+		if err := os.WriteFile(filepath.Join(dir, "en.txt"), []byte(dictionary), 0644); err != nil {
+			t.Fatalf("error writing dictionary: %v", err)
+		}
+	}
+
+	n := NewTextNormalizer()
+	n.o = &mockOsProvider{userConfigDir: configDir}
+
+	return n
+}
+
+func TestNormalize(t *testing.T) {
+	n := newTestNormalizer(t, "")
+
+	tests := []struct {
+		name string
+		text string
+		expected string
+	}{
+		{"Ligatures", "ﬁle ｓｅｒｖｅｒ", "file server"},
+		{"Hyphenated line break", "the deploy-\nment failed", "the deploy-ment\nfailed"},
+		{"Hyphenated compounds stay", "a well-\nknown bug", "a well-known\nbug"},
+		{"Hyphenated names stay", "Pre-\nRelease", "Pre-\nRelease"},
+		{"Whitespace", "  request   \t timed out \r\n\n\n retry ", "request timed out\nretry"},
+		{"Noise", "|||| Settings ___ » — a$#% ok", "Settings ok"},
+		{"Repeated letters", "Zzzzz sleep", "sleep"},
+		{"Numbers", "timeout 100000 ms, exit 0x80000005", "timeout 100000 ms, exit 0x80000005"},
+		{"UUIDs", "id 00000000-0000-0000-0000-000000000000", "id 00000000-0000-0000-0000-000000000000"},
+		{"Code operators", "C++ and C# devs, i++ --n", "C++ and C# devs, i++ --n"},
+		{"Enclosed words", `(x) [ERROR] "done", (!)`, `(x) [ERROR] "done",`},
+		{"Paths and URLs", "see https://example.com/a-b ~/notes.txt", "see https://example.com/a-b ~/notes.txt"},
+		{"Invisible characters", "zero​width", "zerowidth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Normalize(tt.text, nil); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Low confidence", func(t *testing.T) {
+		words := []WordBox{
+			{Text: "deploy", Confidence: 91},
+			{Text: "@f", Confidence: 12},
+			{Text: "ok", Confidence: 20},
+			{Text: "ok", Confidence: 88},
+			{Text: "status", Confidence: -1},
+		}
+		if got := n.Normalize("deploy @f ok status", words); got != "deploy ok status" {
+			t.Errorf("expected only the unsure word to be dropped, got %q", got)
+		}
+	})
+}
+
+func TestDictionaryCorrection(t *testing.T) {
+	n := newTestNormalizer(t, "# words\nconfig 50\nconfirm 10\nrequest 30\nlatency\n")
+
+	got := n.Normalize("C0nfig the requset, LATENCV v1.2.3 ab12cd34", nil)
+	if expected := "Config the request, LATENCY v1.2.3 ab12cd34"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	t.Run("No dictionary", func(t *testing.T) {
+		n := newTestNormalizer(t, "")
+		if got := n.Normalize("c0nfig", nil); got != "c0nfig" {
+			t.Errorf("expected the word to be left as read, got %q", got)
+		}
+	})
+
+	t.Run("Hyphenated line breaks", func(t *testing.T) {
+		n := newTestNormalizer(t, "deploy\nwell\nknown\nmake\nfile\nmakefile\n")
+		got := n.Normalize("the deploy-\nment of a well-\nknown make-\nfile", nil)
+		if expected := "the deployment\nof a well-known\nmakefile"; got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+	})
+}
+
+func TestNewDictionary(t *testing.T) {
+	if _, err := NewDictionary(strings.NewReader("config many\n")); err == nil {
+		t.Error("expected an error for an invalid frequency")
+	}
+	if _, err := NewDictionary(strings.NewReader("# only comments\n\n")); err == nil {
+		t.Error("expected an error for an empty dictionary")
+	}
+
+	d, err := NewDictionary(strings.NewReader("Deploy 3\ndeploy 2\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.words["deploy"] != 5 {
+		t.Errorf("expected counts to add up in lower case, got %v", d.words)
+	}
+}
+
+func TestKeywordText(t *testing.T) {
+	n := NewTextNormalizer()
+
+	got := n.KeywordText("File Edit View kubernetes rollout Help")
+	if strings.Contains(strings.ToLower(got), "file") || !strings.Contains(got, "kubernetes rollout") {
+		t.Errorf("expected UI words to be removed, got %q", got)
+	}
+
+	s := &ScreenshotService{Normalizer: n}
+	doc := &ScreenshotDoc{Text: "File Edit View Window Help\nkubernetes rollout stuck"}
+	s.tagDoc(doc)
+	for _, tag := range doc.Tags {
+		if strings.Contains(tag, "file") || strings.Contains(tag, "window") {
+			t.Errorf("expected no UI words in tags, got %v", doc.Tags)
+		}
+	}
+	if len(doc.Tags) == 0 {
+		t.Error("expected tags")
+	}
+}
@@ -2,16 +2,29 @@ package screenshots
 
 import (
 	_ "embed"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
 type OCRProvider interface {
 	ExtractText(path string) (string, error)
+	ExtractPages(path string) ([]Page, error)
+	ExtractWords(path string) ([]WordBox, error)
 	WriteOCRHelper() (string, error)
 }
 
+// Page is a single page of a document or a sampled frame of a recording
+type Page struct {
+	Number int
+	Timestamp float64
+	Text string
+	Image []byte
+}
+
 type File interface {
 	Name() string
 	Write(b []byte) (int, error)
@@ -22,6 +35,8 @@ type fileSystem interface {
 	CreateTemp(dir string, pattern string) (File, error)
 	WriteFile(file File, b []byte) (int, error)
 	Chmod(name string, mode os.FileMode) error
+	Open(name string) (io.ReadCloser, error)
+	Remove(name string) error
 }
 
 type commandRunner interface {
@@ -29,6 +44,20 @@ type commandRunner interface {
 }
 
 type OCR struct {
+	// GIFFrames is the number of key frames OCR'd for animated GIFs
+	GIFFrames int
+	// RasterizeSVG also renders SVGs to PNG and OCRs them, on top of
+	// reading their <text> elements
+	RasterizeSVG bool
+	// SceneThreshold is the ffmpeg scene score above which a video frame
+	// counts as a new scene
+	SceneThreshold float64
+	// MaxVideoFrames caps the number of frames sampled per recording
+	MaxVideoFrames int
+	// WordBoxes runs a second tesseract pass to record where each word is
+	// on the image, so search hits can be outlined on the thumbnail
+	WordBoxes bool
+
 	ocrBinary []byte
 	ocrBinaryPath string
 	fs fileSystem
@@ -49,6 +78,14 @@ func (r *realFileSystem) Chmod(name string, mode os.FileMode) error {
 	return os.Chmod(name, mode)
 }
 
+func (r *realFileSystem) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (r *realFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
 type realCommandRunner struct {}
 
 func (c *realCommandRunner) Command(name string, arg ...string) ([]byte, error) {
@@ -57,6 +94,9 @@ func (c *realCommandRunner) Command(name string, arg ...string) ([]byte, error)
 
 func NewOCRProvider(ocrBinary []byte) *OCR {
 	return &OCR{
+		GIFFrames: defaultGIFFrames,
+		SceneThreshold: defaultSceneThreshold,
+		MaxVideoFrames: defaultMaxVideoFrames,
 		ocrBinary: ocrBinary,
 		fs: &realFileSystem{},
 		cmdRunner: &realCommandRunner{},
@@ -64,6 +104,47 @@ func NewOCRProvider(ocrBinary []byte) *OCR {
 }
 
 func (o *OCR) ExtractText(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".svg":
+		return o.extractSVGText(path)
+	case ".gif":
+		return o.extractGIFText(path)
+	}
+
+	return o.runOCR(path)
+}
+
+// ExtractPages splits multi-page documents and recordings into pages, each
+// with its own text and a rendered image
+func (o *OCR) ExtractPages(path string) ([]Page, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if _, ok := supportedDocumentExts[ext]; ok {
+		return o.extractPDFPages(path)
+	}
+	if _, ok := supportedVideoExts[ext]; ok {
+		return o.extractVideoFrames(path)
+	}
+
+	return nil, fmt.Errorf("unsupported file type: %s", ext)
+}
+
+// ExtractWords returns the bounding box of every word on the image, or nil
+// if word boxes are disabled
+func (o *OCR) ExtractWords(path string) ([]WordBox, error) {
+	if !o.WordBoxes {
+		return nil, nil
+	}
+
+	out, err := o.cmdRunner.Command(tesseractBinary, path, "stdout", "tsv")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTesseractTSV(out), nil
+}
+
+func (o *OCR) runOCR(path string) (string, error) {
 	out, err := o.cmdRunner.Command(o.ocrBinaryPath, path)
 	if err != nil {
 		return "", err
@@ -72,6 +153,31 @@ func (o *OCR) ExtractText(path string) (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
+// mergeText joins the text of several OCR passes, dropping lines that were
+// already seen so static content in GIF frames is only indexed once
+func mergeText(texts []string) string {
+	seen := make(map[string]struct{})
+	lines := make([]string, 0)
+
+	for _, text := range texts {
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			key := strings.ToLower(strings.Join(strings.Fields(line), " "))
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 func (o *OCR) WriteOCRHelper() (string, error) {
 	tmpFile, err := o.fs.CreateTemp("", "ocr-helper-*")
 	if err != nil {
@@ -91,3 +197,17 @@ func (o *OCR) WriteOCRHelper() (string, error) {
 
 	return o.ocrBinaryPath, nil
 }
+
+// readTempImage reads back an image written by an external tool and
+// removes it
+func (o *OCR) readTempImage(path string) ([]byte, error) {
+	defer o.fs.Remove(path)
+
+	file, err := o.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
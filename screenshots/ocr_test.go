@@ -1,7 +1,9 @@
 package screenshots
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"os"
 	"testing"
 )
@@ -9,6 +11,8 @@ import (
 type mockCmdRunner struct {
 	output []byte
 	cmdError error
+
+	commandFn func(name string, arg ...string) ([]byte, error)
 }
 
 // mockFile implements the File interface
@@ -20,7 +24,9 @@ type mockFileSystem struct {
 	createTempError error
 	writeFileError error
 	chmodError error
+	openError error
 	tempFile File
+	files map[string][]byte
 }
 
 func (m *mockFileSystem) CreateTemp(dir string, pattern string) (File, error) {
@@ -46,6 +52,17 @@ func (m *mockFileSystem) Chmod(name string, mode os.FileMode) error {
 	return nil
 }
 
+func (m *mockFileSystem) Open(name string) (io.ReadCloser, error) {
+	if m.openError != nil {
+		return nil, m.openError
+	}
+	return io.NopCloser(bytes.NewReader(m.files[name])), nil
+}
+
+func (m *mockFileSystem) Remove(name string) error {
+	return nil
+}
+
 func NewMockOCR(ocrBinary []byte, ocrBinaryPath string, fs fileSystem, cmdRunner commandRunner) *OCR {
 	return &OCR{
 		ocrBinary: ocrBinary,
@@ -131,6 +148,9 @@ func (m *mockCmdRunner) Command(name string, arg ...string) ([]byte, error) {
 	if m.cmdError != nil {
 		return nil, m.cmdError
 	}
+	if m.commandFn != nil {
+		return m.commandFn(name, arg...)
+	}
 
 	return m.output, nil
 }
@@ -168,4 +188,14 @@ func TestExtractText (t *testing.T) {
 	})
 }
 
+func TestMergeText(t *testing.T) {
+	merged := mergeText([]string{
+		"Grafana dashboard\nCPU 80%",
+		"grafana  dashboard\nCPU 95%",
+		"",
+	})
 
+	if merged != "Grafana dashboard\nCPU 80%\nCPU 95%" {
+		t.Errorf("expected deduplicated lines, got: %q", merged)
+	}
+}
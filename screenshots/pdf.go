@@ -0,0 +1,102 @@
+package screenshots
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PDFs are read with the poppler command line tools, which ship with most
+// package managers (brew install poppler)
+const (
+	pdfInfoBinary = "pdfinfo"
+	pdfToTextBinary = "pdftotext"
+	pdfToPPMBinary = "pdftoppm"
+
+	pdfRenderDPI = "100"
+)
+
+var supportedDocumentExts = map[string]struct{}{
+	".pdf": {},
+}
+
+// parsePDFPageCount reads the "Pages:" line of pdfinfo's output
+func parsePDFPageCount(out []byte) (int, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Pages:") {
+			continue
+		}
+
+		return strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Pages:")))
+	}
+
+	return 0, fmt.Errorf("page count not found in pdfinfo output")
+}
+
+func (o *OCR) extractPDFPages(path string) ([]Page, error) {
+	out, err := o.cmdRunner.Command(pdfInfoBinary, path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pdf info: %v", err)
+	}
+
+	count, err := parsePDFPageCount(out)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]Page, 0, count)
+	for n := 1; n <= count; n++ {
+		page, err := o.extractPDFPage(path, n)
+		if err != nil {
+			return nil, fmt.Errorf("error extracting page %d: %v", n, err)
+		}
+		pages = append(pages, page)
+	}
+
+	return pages, nil
+}
+
+func (o *OCR) extractPDFPage(path string, n int) (Page, error) {
+	page := Page{Number: n}
+	num := strconv.Itoa(n)
+
+	out, err := o.cmdRunner.Command(pdfToTextBinary, "-f", num, "-l", num, "-layout", path, "-")
+	if err != nil {
+		return page, err
+	}
+	page.Text = strings.TrimSpace(string(out))
+
+	tmpFile, err := o.fs.CreateTemp("", "glimpse-page-*")
+	if err != nil {
+		return page, err
+	}
+	tmpFile.Close()
+	defer o.fs.Remove(tmpFile.Name())
+
+	// pdftoppm appends the extension to the output prefix itself
+	if _, err := o.cmdRunner.Command(pdfToPPMBinary, "-f", num, "-l", num, "-r", pdfRenderDPI, "-png", "-singlefile", path, tmpFile.Name()); err != nil {
+		return page, err
+	}
+	imagePath := tmpFile.Name() + ".png"
+
+	// scanned pages have no embedded text, fall back to OCR on the render
+	if page.Text == "" {
+		text, err := o.runOCR(imagePath)
+		if err != nil {
+			o.fs.Remove(imagePath)
+			return page, err
+		}
+		page.Text = text
+	}
+
+	page.Image, err = o.readTempImage(imagePath)
+	if err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
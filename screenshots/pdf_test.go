@@ -0,0 +1,99 @@
+package screenshots
+
+import (
+	"errors"
+	"testing"
+)
+
+const testPDFInfo = `Title:          Incident report
+Producer:       Skia/PDF m120
+Pages:          2
+Encrypted:      no
+`
+
+func TestParsePDFPageCount(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		count, err := parsePDFPageCount([]byte(testPDFInfo))
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if count != 2 {
+			t.Errorf("expected 2 pages, got: %d", count)
+		}
+	})
+
+	t.Run("Missing pages", func(t *testing.T) {
+		_, err := parsePDFPageCount([]byte("Title: nothing"))
+		if err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
+func TestExtractPDFPages(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		fs := &mockFileSystem{
+			tempFile: &mockFile{name: "/tmp/glimpse-page"},
+			files: map[string][]byte{"/tmp/glimpse-page.png": []byte("png")},
+		}
+		ocrCalls := 0
+		cmdRunner := &mockCmdRunner{
+			commandFn: func(name string, arg ...string) ([]byte, error) {
+				switch name {
+				case pdfInfoBinary:
+					return []byte(testPDFInfo), nil
+				case pdfToTextBinary:
+					// the second page is a scan without embedded text
+					if arg[1] == "1" {
+						return []byte("  embedded text\n"), nil
+					}
+					return nil, nil
+				case pdfToPPMBinary:
+					return nil, nil
+				}
+				ocrCalls++
+				return []byte("scanned text"), nil
+			},
+		}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+
+		pages, err := ocr.ExtractPages("/docs/report.pdf")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(pages) != 2 {
+			t.Fatalf("expected 2 pages, got: %d", len(pages))
+		}
+		if pages[0].Number != 1 || pages[0].Text != "embedded text" {
+			t.Errorf("expected page 1 with embedded text, got: %+v", pages[0])
+		}
+		if pages[1].Number != 2 || pages[1].Text != "scanned text" {
+			t.Errorf("expected page 2 with OCR text, got: %+v", pages[1])
+		}
+		if ocrCalls != 1 {
+			t.Errorf("expected OCR to run once, got: %d", ocrCalls)
+		}
+		if string(pages[0].Image) != "png" {
+			t.Errorf("expected rendered page image, got: %q", pages[0].Image)
+		}
+	})
+
+	t.Run("Pdfinfo error", func(t *testing.T) {
+		cmdRunner := &mockCmdRunner{cmdError: errors.New("pdfinfo not found")}
+		ocr := NewMockOCR(nil, "", nil, cmdRunner)
+
+		_, err := ocr.ExtractPages("/docs/report.pdf")
+		if err == nil || err.Error() != "error reading pdf info: pdfinfo not found" {
+			t.Errorf("expected 'error reading pdf info: pdfinfo not found', got: %v", err)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		ocr := NewMockOCR(nil, "", nil, nil)
+
+		_, err := ocr.ExtractPages("/docs/notes.txt")
+		if err == nil || err.Error() != "unsupported file type: .txt" {
+			t.Errorf("expected 'unsupported file type: .txt', got: %v", err)
+		}
+	})
+}
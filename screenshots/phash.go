@@ -0,0 +1,167 @@
+package screenshots
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	// hashWidth is one more than the 8 columns compared so every row gives
+	// 8 bits of the 64 bit hash
+	hashWidth = 9
+	hashHeight = 8
+)
+
+// perceptualHash computes the difference hash (dHash) of an encoded image:
+// the image is shrunk to 9x8 gray pixels and each bit says whether a pixel
+// is brighter than its right neighbour. Rescaled, recompressed or slightly
+// edited copies of an image end up a few bits apart. It returns false when
+// the image can't be decoded.
+func perceptualHash(data []byte) (uint64, bool) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, false
+	}
+
+	gray := shrinkGray(img, hashWidth, hashHeight)
+
+	var hash uint64
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth-1; x++ {
+			hash <<= 1
+			if gray[y*hashWidth+x] > gray[y*hashWidth+x+1] {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash, true
+}
+
+// shrinkGray averages the luminance of the block of pixels behind each of the
+// width x height output pixels
+func shrinkGray(img image.Image, width int, height int) []float64 {
+	bounds := img.Bounds()
+	out := make([]float64, width*height)
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return out
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+
+			var sum float64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, b, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+				}
+			}
+			out[y*width+x] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+
+	return out
+}
+
+func hammingDistance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// formatHash and parseHash convert hashes to the hex strings stored in the
+// index
+func formatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func parseHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
+
+// bkTree finds hashes within a Hamming distance of a hash without comparing
+// against all of them. Each child sits at its distance from its parent, so
+// by the triangle inequality only children within maxDistance of the
+// query's distance to the parent can hold matches.
+type bkTree struct {
+	root *bkNode
+}
+
+type bkNode struct {
+	hash uint64
+	ids []string
+	children map[int]*bkNode
+}
+
+// bkMatch is an ID found near the searched hash
+type bkMatch struct {
+	id string
+	hash uint64
+	distance int
+}
+
+func (t *bkTree) insert(hash uint64, id string) {
+	if t.root == nil {
+		t.root = &bkNode{hash: hash, ids: []string{id}}
+		return
+	}
+
+	node := t.root
+	for {
+		d := hammingDistance(hash, node.hash)
+		if d == 0 {
+			for _, existing := range node.ids {
+				if existing == id {
+					return
+				}
+			}
+			node.ids = append(node.ids, id)
+			return
+		}
+
+		child, ok := node.children[d]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[d] = &bkNode{hash: hash, ids: []string{id}}
+			return
+		}
+		node = child
+	}
+}
+
+func (t *bkTree) search(hash uint64, maxDistance int) []bkMatch {
+	matches := make([]bkMatch, 0)
+	if t.root == nil {
+		return matches
+	}
+
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		d := hammingDistance(hash, node.hash)
+		if d <= maxDistance {
+			for _, id := range node.ids {
+				matches = append(matches, bkMatch{id: id, hash: node.hash, distance: d})
+			}
+		}
+
+		for childDistance, child := range node.children {
+			if childDistance >= d-maxDistance && childDistance <= d+maxDistance {
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	return matches
+}
@@ -0,0 +1,147 @@
+package screenshots
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// newTestDashboard draws a light page with a dark sidebar and bars of the
+// given heights
+func newTestDashboard(width int, height int, bars []int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{240, 240, 240, 255}
+			if x < width/5 {
+				c = color.RGBA{30, 30, 60, 255}
+			}
+			for i, bar := range bars {
+				left := width/5 + (i+1)*width/(2*len(bars)+2)
+				if x >= left && x < left+width/20 && y > height-bar*height/100 {
+					c = color.RGBA{40, 120, 220, 255}
+				}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("error encoding png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPerceptualHash(t *testing.T) {
+	original := newTestDashboard(320, 200, []int{30, 80, 50, 90})
+	originalHash, ok := perceptualHash(encodePNG(t, original))
+	if !ok {
+		t.Fatal("expected the png to be hashed")
+	}
+
+	t.Run("Rescaled and recompressed copy", func(t *testing.T) {
+		var buf bytes.Buffer
+		jpeg.Encode(&buf, newTestDashboard(640, 400, []int{30, 80, 50, 90}), &jpeg.Options{Quality: 60})
+
+		hash, ok := perceptualHash(buf.Bytes())
+		if !ok {
+			t.Fatal("expected the jpeg to be hashed")
+		}
+		if d := hammingDistance(originalHash, hash); d > 4 {
+			t.Errorf("expected a copy to be within 4 bits, got %d", d)
+		}
+	})
+
+	t.Run("Different screen", func(t *testing.T) {
+		hash, _ := perceptualHash(encodePNG(t, newTestDashboard(320, 200, []int{90, 10, 95, 5, 70, 20})))
+		if d := hammingDistance(originalHash, hash); d < 8 {
+			t.Errorf("expected a different screen to be far away, got %d", d)
+		}
+	})
+
+	t.Run("Not an image", func(t *testing.T) {
+		if _, ok := perceptualHash([]byte("<svg/>")); ok {
+			t.Error("expected undecodable data not to be hashed")
+		}
+	})
+}
+
+func TestHashFormat(t *testing.T) {
+	hash := uint64(0x00ff00ff00ff00ff)
+	s := formatHash(hash)
+	if s != "00ff00ff00ff00ff" {
+		t.Errorf("expected 16 hex digits, got %q", s)
+	}
+	parsed, err := parseHash(s)
+	if err != nil || parsed != hash {
+		t.Errorf("expected %x back, got %x, %v", hash, parsed, err)
+	}
+}
+
+func TestBKTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	hashes := make(map[string]uint64)
+	tree := bkTree{}
+	var previous uint64
+	for i := 0; i < 2000; i++ {
+		hash := rng.Uint64()
+		// every tenth is a near copy so small distances have matches
+		if i%10 == 9 {
+			hash = previous ^ 1<<(i%64)
+		}
+		id := fmt.Sprintf("img%d", i)
+		hashes[id] = hash
+		tree.insert(hash, id)
+		previous = hash
+	}
+	// inserting the same ID again is a no-op
+	tree.insert(hashes["img8"], "img8")
+
+	for _, maxDistance := range []int{0, 3, 20} {
+		query := hashes["img8"]
+
+		expected := make([]string, 0)
+		for id, hash := range hashes {
+			if hammingDistance(query, hash) <= maxDistance {
+				expected = append(expected, id)
+			}
+		}
+
+		got := make([]string, 0)
+		for _, m := range tree.search(query, maxDistance) {
+			if m.distance != hammingDistance(query, m.hash) {
+				t.Errorf("wrong distance for %s", m.id)
+			}
+			got = append(got, m.id)
+		}
+
+		sort.Strings(expected)
+		sort.Strings(got)
+		if len(got) != len(expected) {
+			t.Fatalf("distance %d: expected %d matches, got %d", maxDistance, len(expected), len(got))
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("distance %d: expected %q, got %q", maxDistance, expected, got)
+				break
+			}
+		}
+	}
+
+	empty := bkTree{}
+	if matches := empty.search(0, 64); len(matches) != 0 {
+		t.Errorf("expected no matches in an empty tree, got %v", matches)
+	}
+}
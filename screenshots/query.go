@@ -0,0 +1,470 @@
+package screenshots
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// QueryError is returned when the search input can't be parsed. Position is
+// the 0-based character offset of the offending token.
+type QueryError struct {
+	Position int `json:"position"`
+	Message string `json:"message"`
+	// QueryID is the search session ID of the query, see SearchOptions
+	QueryID int64 `json:"queryId,omitempty"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Position, e.Message)
+}
+
+// clause is a single term of a glimpse query, e.g. -tag:"stack trace"
+type clause struct {
+	pos int
+	valuePos int
+	negate bool
+	field string
+	op string
+	value string
+	quoted bool
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01",
+	time.RFC3339,
+}
+
+// queryParser holds what a query needs to be resolved against the index
+type queryParser struct {
+	homeDir string
+	// fuzziness is the edit distance allowed on free text and tags, 0
+	// disables fuzzy matching
+	fuzziness int
+	// prefixLast matches the last word as a prefix unless the input ends
+	// in whitespace, for search-as-you-type
+	prefixLast bool
+}
+
+// parse turns glimpse query syntax into a bleve query. Clauses are
+// ANDed together:
+//
+//	grafana "error rate"        free text and phrases
+//	tag:grafana path:report     field matches, tag: includes the user's tags
+//	note:"follow up"            the user's notes
+//	ext:png dir:~/Desktop/work  exact extension, directory and below (in: works too)
+//	category:terminal is:code   what the screenshot shows, see Classifier
+//	code:getUserConfigDir lang:go  identifiers in screenshots of code
+//	after:2025-03-01 before:2025-04-01
+//	width>1920 height<=1080
+//	has:url ip:10.0.0.5         entities found in the text, see ExtractEntities
+//	has:error error:ocr         files that failed to index, by stage
+//	-tag:draft -"lorem ipsum"   negation
+func (p *queryParser) parse(input string) (query.Query, error) {
+	clauses, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(clauses) == 0 {
+		return nil, &QueryError{Position: 0, Message: "empty query"}
+	}
+
+	boolQuery := bleve.NewBooleanQuery()
+	positives := 0
+
+	// the user is still typing the last word unless it was followed by a space
+	typing := p.prefixLast && len(strings.TrimRightFunc(input, unicode.IsSpace)) == len(input)
+
+	for i, c := range clauses {
+		var q query.Query
+		if typing && i == len(clauses)-1 && c.field == "" && !c.quoted && isSingleTerm(c.value) {
+			q = partialWordQuery(c.value, p.textQuery(c.value, false, ""))
+		} else {
+			q, err = p.clauseQuery(c)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if c.negate {
+			boolQuery.AddMustNot(q)
+			continue
+		}
+		boolQuery.AddMust(q)
+		positives++
+	}
+
+	// a query of only exclusions matches everything else
+	if positives == 0 {
+		boolQuery.AddMust(bleve.NewMatchAllQuery())
+	}
+
+	return boolQuery, nil
+}
+
+// semanticParts splits the input into the free text to embed and a query of
+// the remaining clauses, nil if there are none. Excluded phrases stay in the
+// query since there is no way to embed a negation.
+func (p *queryParser) semanticParts(input string) (string, query.Query, error) {
+	clauses, err := lexQuery(input)
+	if err != nil {
+		return "", nil, err
+	}
+
+	words := make([]string, 0, len(clauses))
+	boolQuery := bleve.NewBooleanQuery()
+	positives, negatives := 0, 0
+
+	for _, c := range clauses {
+		if c.field == "" && !c.negate {
+			words = append(words, c.value)
+			continue
+		}
+
+		q, err := p.clauseQuery(c)
+		if err != nil {
+			return "", nil, err
+		}
+		if c.negate {
+			boolQuery.AddMustNot(q)
+			negatives++
+			continue
+		}
+		boolQuery.AddMust(q)
+		positives++
+	}
+
+	if positives == 0 && negatives == 0 {
+		return strings.Join(words, " "), nil, nil
+	}
+	if positives == 0 {
+		boolQuery.AddMust(bleve.NewMatchAllQuery())
+	}
+
+	return strings.Join(words, " "), boolQuery, nil
+}
+
+// queryFields are the fields clauseQuery handles besides the entity types
+var queryFields = map[string]bool{
+	"width": true, "height": true,
+	"tag": true, "note": true, "path": true, "ext": true,
+	"category": true, "is": true, "lang": true, "language": true,
+	"error": true, "code": true, "dir": true, "in": true,
+	"after": true, "before": true, "has": true,
+}
+
+func isQueryField(name string) bool {
+	if queryFields[name] {
+		return true
+	}
+	_, ok := entityFields[name]
+	return ok
+}
+
+func isOpChar(r rune) bool {
+	return r == ':' || r == '>' || r == '<'
+}
+
+// lexQuery splits the input into clauses, tracking rune offsets so errors can
+// point at the offending token
+func lexQuery(input string) ([]clause, error) {
+	runes := []rune(input)
+	clauses := make([]clause, 0)
+
+	i := 0
+	for i < len(runes) {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		c := clause{pos: i}
+
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			c.negate = true
+			i++
+		}
+
+		if runes[i] == '"' {
+			value, next, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			c.valuePos, c.value, c.quoted = i, value, true
+			clauses = append(clauses, c)
+			i = next
+			continue
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && !isOpChar(runes[i]) {
+			i++
+		}
+		word := string(runes[start:i])
+
+		// plain word
+		if i >= len(runes) || unicode.IsSpace(runes[i]) {
+			c.valuePos, c.value = start, word
+			clauses = append(clauses, c)
+			continue
+		}
+
+		// only known fields take an operator, the rest of the token is free
+		// text like in "TypeError: undefined", localhost:8080 or a URL
+		if !isQueryField(strings.ToLower(word)) {
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			c.valuePos, c.value = start, string(runes[start:i])
+			clauses = append(clauses, c)
+			continue
+		}
+
+		op := string(runes[i])
+		i++
+		if op != ":" && i < len(runes) && runes[i] == '=' {
+			op += "="
+			i++
+		}
+
+		// a field without a value is text too, like "Error: connection
+		// refused" pasted from a log
+		if i >= len(runes) || unicode.IsSpace(runes[i]) {
+			c.valuePos, c.value = start, string(runes[start:i])
+			clauses = append(clauses, c)
+			continue
+		}
+		c.field, c.op = strings.ToLower(word), op
+
+		c.valuePos = i
+		if runes[i] == '"' {
+			value, next, err := readQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			c.value, c.quoted = value, true
+			i = next
+		} else {
+			valueStart := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			c.value = string(runes[valueStart:i])
+		}
+
+		clauses = append(clauses, c)
+	}
+
+	return clauses, nil
+}
+
+// readQuoted reads a quoted phrase starting at the opening quote and returns
+// its content and the offset after the closing quote
+func readQuoted(runes []rune, start int) (string, int, error) {
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] == '"' {
+			value := strings.TrimSpace(string(runes[start+1 : i]))
+			if value == "" {
+				return "", 0, &QueryError{Position: start, Message: "empty phrase"}
+			}
+			return value, i + 1, nil
+		}
+	}
+
+	return "", 0, &QueryError{Position: start, Message: "unterminated quote"}
+}
+
+func (c clause) errorf(format string, args ...interface{}) error {
+	return &QueryError{Position: c.valuePos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) clauseQuery(c clause) (query.Query, error) {
+	if c.field == "" {
+		return p.textQuery(c.value, c.quoted, ""), nil
+	}
+
+	switch c.field {
+	case "width", "height":
+		return c.numericQuery()
+	}
+
+	if c.op != ":" {
+		return nil, &QueryError{Position: c.pos, Message: fmt.Sprintf("%s does not support %s", c.field, c.op)}
+	}
+
+	switch c.field {
+	case "tag":
+		// hand-added tags count as tags too
+		return bleve.NewDisjunctionQuery(
+			p.textQuery(c.value, c.quoted, "tags"),
+			p.textQuery(c.value, c.quoted, "user_tags"),
+		), nil
+	case "note":
+		return p.textQuery(c.value, c.quoted, "note"), nil
+	case "path":
+		return exactTextQuery(c.value, c.quoted, "path"), nil
+	case "ext":
+		ext := strings.ToLower(strings.TrimPrefix(c.value, "."))
+		q := bleve.NewTermQuery(ext)
+		q.SetField("ext")
+		return q, nil
+	case "category", "is":
+		q := bleve.NewTermQuery(strings.ToLower(c.value))
+		q.SetField("category")
+		return q, nil
+	case "lang", "language":
+		q := bleve.NewTermQuery(strings.ToLower(c.value))
+		q.SetField("language")
+		return q, nil
+	case "error":
+		q := bleve.NewTermQuery(strings.ToLower(c.value))
+		q.SetField("error_stage")
+		return q, nil
+	case "code":
+		return exactTextQuery(c.value, c.quoted, "code"), nil
+	case "dir", "in":
+		return dirQuery(expandHome(c.value, p.homeDir)), nil
+	case "after", "before":
+		return c.dateQuery()
+	case "has":
+		name := strings.ToLower(c.value)
+		if name == "error" {
+			// documents without an error index an empty stage
+			stages := make([]query.Query, 0, len(scanStages))
+			for _, stage := range scanStages {
+				q := bleve.NewTermQuery(stage)
+				q.SetField("error_stage")
+				stages = append(stages, q)
+			}
+			return bleve.NewDisjunctionQuery(stages...), nil
+		}
+		if _, ok := entityFields[name]; !ok {
+			return nil, c.errorf("unknown entity %q, expected error or one of %s", c.value, strings.Join(entityNames(), ", "))
+		}
+		q := bleve.NewTermQuery(name)
+		q.SetField("entity_types")
+		return q, nil
+	}
+	if _, ok := entityFields[c.field]; ok {
+		return entityQuery(c.field, c.value), nil
+	}
+
+	return nil, &QueryError{Position: c.pos, Message: fmt.Sprintf("unknown field %q", c.field)}
+}
+
+// textQuery matches free text and tags, widening single words to prefix,
+// fuzzy and OCR confusion matches when fuzziness is enabled
+func (p *queryParser) textQuery(value string, quoted bool, field string) query.Query {
+	if p.fuzziness > 0 && !quoted && isSingleTerm(value) {
+		return fuzzyTextQuery(value, field, p.fuzziness)
+	}
+
+	return exactTextQuery(value, quoted, field)
+}
+
+// partialWordQuery matches a word that may not be complete yet, either as
+// typed or as the start of a longer word
+func partialWordQuery(word string, complete query.Query) query.Query {
+	prefix := bleve.NewPrefixQuery(strings.ToLower(word))
+
+	return bleve.NewDisjunctionQuery(complete, prefix)
+}
+
+func exactTextQuery(value string, quoted bool, field string) query.Query {
+	if quoted {
+		q := bleve.NewMatchPhraseQuery(value)
+		q.SetField(field)
+		return q
+	}
+
+	q := bleve.NewMatchQuery(value)
+	q.SetField(field)
+	q.SetOperator(query.MatchQueryOperatorAnd)
+	return q
+}
+
+func expandHome(path string, homeDir string) string {
+	if path == "~" {
+		return homeDir
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir, path[2:])
+	}
+
+	return path
+}
+
+// dirQuery matches files in dir and any directory below it
+func dirQuery(dir string) query.Query {
+	dir = filepath.Clean(dir)
+
+	exact := bleve.NewTermQuery(dir)
+	exact.SetField("dir")
+
+	below := bleve.NewPrefixQuery(strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator))
+	below.SetField("dir")
+
+	return bleve.NewDisjunctionQuery(exact, below)
+}
+
+func (c clause) dateQuery() (query.Query, error) {
+	var date time.Time
+	var err error
+	for _, layout := range dateLayouts {
+		date, err = time.ParseInLocation(layout, c.value, time.Local)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, c.errorf("invalid date %q, expected YYYY-MM-DD", c.value)
+	}
+
+	// after is inclusive and before exclusive, so after:D before:D+1
+	// covers exactly one day
+	inclusive := true
+	var q *query.DateRangeQuery
+	if c.field == "after" {
+		q = bleve.NewDateRangeInclusiveQuery(date, time.Time{}, &inclusive, nil)
+	} else {
+		q = bleve.NewDateRangeQuery(time.Time{}, date)
+	}
+	q.SetField("created")
+
+	return q, nil
+}
+
+func (c clause) numericQuery() (query.Query, error) {
+	value, err := strconv.ParseFloat(c.value, 64)
+	if err != nil {
+		return nil, c.errorf("invalid number %q", c.value)
+	}
+
+	inclusive, exclusive := true, false
+	var q *query.NumericRangeQuery
+
+	switch c.op {
+	case ":":
+		q = bleve.NewNumericRangeInclusiveQuery(&value, &value, &inclusive, &inclusive)
+	case ">":
+		q = bleve.NewNumericRangeInclusiveQuery(&value, nil, &exclusive, nil)
+	case ">=":
+		q = bleve.NewNumericRangeInclusiveQuery(&value, nil, &inclusive, nil)
+	case "<":
+		q = bleve.NewNumericRangeInclusiveQuery(nil, &value, nil, &exclusive)
+	case "<=":
+		q = bleve.NewNumericRangeInclusiveQuery(nil, &value, nil, &inclusive)
+	default:
+		return nil, &QueryError{Position: c.pos, Message: fmt.Sprintf("%s does not support %s", c.field, c.op)}
+	}
+	q.SetField(c.field)
+
+	return q, nil
+}
@@ -0,0 +1,185 @@
+package screenshots
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+func newTestIndex(t testing.TB, docs ...ScreenshotDoc) bleve.Index {
+	t.Helper()
+
+	idx, err := bleve.NewMemOnly(newIndexMapping())
+	if err != nil {
+		t.Fatalf("error creating index: %v", err)
+	}
+	t.Cleanup(func() { idx.Close() })
+
+	for i := range docs {
+		if err := idx.Index(docs[i].Path, &docs[i]); err != nil {
+			t.Fatalf("error indexing %s: %v", docs[i].Path, err)
+		}
+	}
+
+	return idx
+}
+
+func testDocs() []ScreenshotDoc {
+	return []ScreenshotDoc{
+		{
+			Path: "/home/me/Desktop/work/grafana.png",
+			Tags: []string{"grafana dashboard", "error rate"},
+			Dir: "/home/me/Desktop/work",
+			Ext: "png",
+			Created: time.Date(2025, 3, 5, 9, 0, 0, 0, time.Local),
+			Width: 2880,
+			Height: 1800,
+		},
+		{
+			Path: "/home/me/Desktop/workshop/notes.jpg",
+			Tags: []string{"meeting notes", "error budget"},
+			Dir: "/home/me/Desktop/workshop",
+			Ext: "jpg",
+			Created: time.Date(2025, 2, 20, 9, 0, 0, 0, time.Local),
+			Width: 1280,
+			Height: 720,
+		},
+		{
+			Path: "/home/me/Desktop/work/old/trace.png",
+			Tags: []string{"stack trace", "grafana"},
+			Dir: "/home/me/Desktop/work/old",
+			Ext: "png",
+			Created: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local),
+			Width: 1920,
+			Height: 1080,
+		},
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	idx := newTestIndex(t, testDocs()...)
+
+	tests := []struct {
+		input string
+		expected []string
+	}{
+		{input: "grafana", expected: []string{"/home/me/Desktop/work/grafana.png", "/home/me/Desktop/work/old/trace.png"}},
+		{input: `"error rate"`, expected: []string{"/home/me/Desktop/work/grafana.png"}},
+		{input: "tag:grafana -tag:trace", expected: []string{"/home/me/Desktop/work/grafana.png"}},
+		{input: `tag:"stack trace"`, expected: []string{"/home/me/Desktop/work/old/trace.png"}},
+		{input: "ext:.JPG", expected: []string{"/home/me/Desktop/workshop/notes.jpg"}},
+		{input: "in:~/Desktop/work", expected: []string{"/home/me/Desktop/work/grafana.png", "/home/me/Desktop/work/old/trace.png"}},
+		{input: "dir:/home/me/Desktop/workshop", expected: []string{"/home/me/Desktop/workshop/notes.jpg"}},
+		{input: "after:2025-03-01", expected: []string{"/home/me/Desktop/work/grafana.png", "/home/me/Desktop/work/old/trace.png"}},
+		{input: "before:2025-03-01", expected: []string{"/home/me/Desktop/workshop/notes.jpg"}},
+		{input: "width>1920", expected: []string{"/home/me/Desktop/work/grafana.png"}},
+		{input: "height>=1080 ext:png", expected: []string{"/home/me/Desktop/work/grafana.png", "/home/me/Desktop/work/old/trace.png"}},
+		{input: "width:1280", expected: []string{"/home/me/Desktop/workshop/notes.jpg"}},
+		{input: "-ext:png", expected: []string{"/home/me/Desktop/workshop/notes.jpg"}},
+	}
+
+	for _, tt := range tests {
+		p := &queryParser{homeDir: "/home/me"}
+		q, err := p.parse(tt.input)
+		if err != nil {
+			t.Errorf("%q: expected no error, got: %v", tt.input, err)
+			continue
+		}
+
+		res, err := idx.Search(bleve.NewSearchRequest(q))
+		if err != nil {
+			t.Errorf("%q: expected no search error, got: %v", tt.input, err)
+			continue
+		}
+
+		got := make([]string, 0, len(res.Hits))
+		for _, hit := range res.Hits {
+			got = append(got, hit.ID)
+		}
+		sort.Strings(got)
+
+		if len(got) != len(tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("%q: expected %v, got %v", tt.input, tt.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestParseQueryFreeText(t *testing.T) {
+	// colons and comparisons outside known fields are part of the text
+	inputs := []string{
+		"TypeError: undefined",
+		"localhost:8080",
+		"standup 10:30",
+		"https://grafana.example.com/d/abc",
+		"foo:bar",
+		"Error: connection refused",
+		"Tag: foo",
+		"Note: x",
+		"status: 502",
+		"width>",
+		"a<b",
+		"::1",
+	}
+
+	for _, input := range inputs {
+		clauses, err := lexQuery(input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", input, err)
+			continue
+		}
+		for _, c := range clauses {
+			if c.field != "" {
+				t.Errorf("%q: expected free text, got field %q", input, c.field)
+			}
+		}
+
+		p := &queryParser{homeDir: "/home/me"}
+		if _, err := p.parse(input); err != nil {
+			t.Errorf("%q: unexpected error: %v", input, err)
+		}
+	}
+
+	clauses, _ := lexQuery("TypeError: undefined")
+	if len(clauses) != 2 || clauses[0].value != "TypeError:" || clauses[1].value != "undefined" {
+		t.Errorf("expected two words, got %+v", clauses)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		position int
+		message string
+	}{
+		{input: "", position: 0, message: "empty query"},
+		{input: `grafana "error rate`, position: 8, message: "unterminated quote"},
+		{input: "after:yesterday", position: 6, message: `invalid date "yesterday", expected YYYY-MM-DD`},
+		{input: "width>wide", position: 6, message: `invalid number "wide"`},
+		{input: "tag>3", position: 0, message: "tag does not support >"},
+		{input: `tag:""`, position: 4, message: "empty phrase"},
+	}
+
+	for _, tt := range tests {
+		p := &queryParser{homeDir: "/home/me"}
+		_, err := p.parse(tt.input)
+
+		var qerr *QueryError
+		if !errors.As(err, &qerr) {
+			t.Errorf("%q: expected QueryError, got: %v", tt.input, err)
+			continue
+		}
+		if qerr.Position != tt.position || qerr.Message != tt.message {
+			t.Errorf("%q: expected %q at %d, got %q at %d", tt.input, tt.message, tt.position, qerr.Message, qerr.Position)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package screenshots
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearch is a query kept for reuse along with the options it was run
+// with. Smart collections also keep their number of matches up to date.
+type SavedSearch struct {
+	ID string `json:"id"`
+	Name string `json:"name"`
+	Query string `json:"query"`
+	Fuzziness int `json:"fuzziness"`
+	Sort string `json:"sort,omitempty"`
+	Filters map[string][]string `json:"filters,omitempty"`
+	Created time.Time `json:"created"`
+
+	// Smart collections are recounted whenever documents are indexed
+	Smart bool `json:"smart"`
+	Count uint64 `json:"count"`
+}
+
+// options returns the search options of the saved search on top of opts,
+// which supplies the session ID and the page to fetch
+func (s *SavedSearch) options(opts SearchOptions) SearchOptions {
+	opts.AsYouType = false
+	opts.Fuzziness = s.Fuzziness
+	opts.Sort = s.Sort
+	opts.Filters = s.Filters
+
+	return opts
+}
+
+type SavedSearchProvider interface {
+	List() ([]SavedSearch, error)
+	Get(id string) (*SavedSearch, error)
+	Create(search SavedSearch) (*SavedSearch, error)
+	Rename(id string, name string) error
+	Delete(id string) error
+	SetCount(id string, count uint64) error
+}
+
+// SavedSearches keeps saved searches in a JSON file next to the index
+type SavedSearches struct {
+	appName string
+	fileName string
+	o osProvider
+
+	mu sync.Mutex
+	searches []SavedSearch
+	loaded bool
+}
+
+func NewSavedSearches() *SavedSearches {
+	return &SavedSearches{
+		appName: "Glimpse",
+		fileName: "saved_searches.json",
+		o: &realOsProvider{},
+	}
+}
+
+func (s *SavedSearches) path() (string, error) {
+	return appDataPath(s.o, s.appName, s.fileName)
+}
+
+// load reads the saved searches on first use, a missing file means none
+// were saved yet. Callers hold mu.
+func (s *SavedSearches) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	path, err := s.path()
+	if err != nil {
+		return fmt.Errorf("error finding saved searches: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error reading saved searches: %v", err)
+	}
+
+	searches := make([]SavedSearch, 0)
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &searches); err != nil {
+			return fmt.Errorf("error parsing saved searches: %v", err)
+		}
+	}
+
+	s.searches = searches
+	s.loaded = true
+
+	return nil
+}
+
+// save writes the saved searches to a temporary file first so a crash can't
+// leave a truncated file behind. Callers hold mu.
+func (s *SavedSearches) save() error {
+	path, err := s.path()
+	if err != nil {
+		return fmt.Errorf("error finding saved searches: %v", err)
+	}
+
+	data, err := json.MarshalIndent(s.searches, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing saved searches: %v", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (s *SavedSearches) find(id string) (int, error) {
+	for i := range s.searches {
+		if s.searches[i].ID == id {
+			return i, nil
+		}
+	}
+
+	return -1, ErrSavedSearchNotFound
+}
+
+func (s *SavedSearches) List() ([]SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	return append([]SavedSearch(nil), s.searches...), nil
+}
+
+func (s *SavedSearches) Get(id string) (*SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	i, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	search := s.searches[i]
+
+	return &search, nil
+}
+
+// Create saves search under a new ID
+func (s *SavedSearches) Create(search SavedSearch) (*SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	search.ID = id
+	search.Created = time.Now()
+
+	s.searches = append(s.searches, search)
+	if err := s.save(); err != nil {
+		s.searches = s.searches[:len(s.searches)-1]
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+func (s *SavedSearches) Rename(id string, name string) error {
+	return s.update(id, func(search *SavedSearch) {
+		search.Name = name
+	})
+}
+
+func (s *SavedSearches) SetCount(id string, count uint64) error {
+	return s.update(id, func(search *SavedSearch) {
+		search.Count = count
+	})
+}
+
+func (s *SavedSearches) update(id string, change func(search *SavedSearch)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	i, err := s.find(id)
+	if err != nil {
+		return err
+	}
+
+	previous := s.searches[i]
+	change(&s.searches[i])
+	if err := s.save(); err != nil {
+		s.searches[i] = previous
+		return err
+	}
+
+	return nil
+}
+
+func (s *SavedSearches) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	i, err := s.find(id)
+	if err != nil {
+		return err
+	}
+
+	previous := s.searches
+	s.searches = append(append([]SavedSearch(nil), s.searches[:i]...), s.searches[i+1:]...)
+	if err := s.save(); err != nil {
+		s.searches = previous
+		return err
+	}
+
+	return nil
+}
+
+// newID returns a random ID for saved searches and undo batches
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating id: %v", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// SaveSearch saves query and the options it is run with under name. The
+// query is parsed first so a saved search can't fail later on.
+func (s *ScreenshotService) SaveSearch(name string, query string, opts SearchOptions, smart bool) (*SavedSearch, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("saved search needs a name")
+	}
+
+	search := SavedSearch{
+		Name: name,
+		Query: strings.TrimSpace(query),
+		Fuzziness: opts.Fuzziness,
+		Sort: opts.Sort,
+		Filters: opts.Filters,
+		Smart: smart,
+	}
+
+	count, err := s.countMatches(&search)
+	if err != nil {
+		return nil, err
+	}
+	if smart {
+		search.Count = count
+	}
+
+	return s.Saved.Create(search)
+}
+
+func (s *ScreenshotService) SavedSearches() ([]SavedSearch, error) {
+	return s.Saved.List()
+}
+
+func (s *ScreenshotService) RenameSavedSearch(id string, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("saved search needs a name")
+	}
+
+	return s.Saved.Rename(id, name)
+}
+
+func (s *ScreenshotService) DeleteSavedSearch(id string) error {
+	return s.Saved.Delete(id)
+}
+
+// RunSavedSearch searches like Search with the query and options of the
+// saved search, opts only picks the session ID and page
+func (s *ScreenshotService) RunSavedSearch(id string, opts SearchOptions) (*SearchResults, error) {
+	search, err := s.Saved.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Search(search.Query, search.options(opts))
+}
+
+// countMatches counts the documents search matches without fetching them
+func (s *ScreenshotService) countMatches(search *SavedSearch) (uint64, error) {
+	err := s.Indexer.Open()
+	if err != nil {
+		return 0, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	homeDir, err := s.Dir.GetHomeDir()
+	if err != nil {
+		return 0, fmt.Errorf("error getting homedir: %v", err)
+	}
+
+	parser := &queryParser{homeDir: homeDir, fuzziness: search.Fuzziness}
+	q, err := parser.parse(search.Query)
+	if err != nil {
+		return 0, err
+	}
+
+	q, err = filterQuery(q, search.Filters)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.Indexer.Search(s.ctx, bleve.NewSearchRequestOptions(q, 0, 0, false))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Total, nil
+}
+
+// refreshCollections recounts every smart collection and sends the ones
+// whose count changed as collection:count events. A collection that can't
+// be counted, like one whose query no longer parses, is logged and skipped:
+// the scan that triggered the count still succeeded.
+func (s *ScreenshotService) refreshCollections() {
+	searches, err := s.Saved.List()
+	if err != nil {
+		s.logger().Error("error listing smart collections", "error", err)
+		return
+	}
+
+	for i := range searches {
+		search := &searches[i]
+		if !search.Smart {
+			continue
+		}
+
+		count, err := s.countMatches(search)
+		if err != nil {
+			s.logger().Warn("error counting smart collection", "name", search.Name, "query", search.Query, "error", err)
+			continue
+		}
+		if count == search.Count {
+			continue
+		}
+
+		if err := s.Saved.SetCount(search.ID, count); err != nil {
+			s.logger().Error("error saving smart collection count", "name", search.Name, "error", err)
+			continue
+		}
+		search.Count = count
+		s.emit("collection:count", search)
+	}
+}
@@ -0,0 +1,180 @@
+package screenshots
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestSavedSearches(t *testing.T) *SavedSearches {
+	configDir := t.TempDir()
+	// the mock doesn't create directories
+	if err := os.MkdirAll(filepath.Join(configDir, "Glimpse"), 0755); err != nil {
+		t.Fatalf("error creating config dir: %v", err)
+	}
+
+	return &SavedSearches{
+		appName: "Glimpse",
+		fileName: "saved_searches.json",
+		o: &mockOsProvider{userConfigDir: configDir},
+	}
+}
+
+func TestSavedSearches(t *testing.T) {
+	t.Run("Create, rename and delete", func(t *testing.T) {
+		s := newTestSavedSearches(t)
+
+		invoices, err := s.Create(SavedSearch{Name: "Invoices", Query: "invoice", Smart: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if invoices.ID == "" || invoices.Created.IsZero() {
+			t.Errorf("expected an ID and creation time, got %+v", invoices)
+		}
+		if _, err := s.Create(SavedSearch{Name: "Traces", Query: `"stack trace"`}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := s.Rename(invoices.ID, "Bills"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.SetCount(invoices.ID, 7); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got, err := s.Get(invoices.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Name != "Bills" || got.Count != 7 || got.Query != "invoice" {
+			t.Errorf("expected renamed search with 7 matches, got %+v", got)
+		}
+
+		if err := s.Delete(invoices.ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		searches, _ := s.List()
+		if len(searches) != 1 || searches[0].Name != "Traces" {
+			t.Errorf("expected only Traces to be left, got %+v", searches)
+		}
+	})
+
+	t.Run("Persisted across restarts", func(t *testing.T) {
+		s := newTestSavedSearches(t)
+		created, err := s.Create(SavedSearch{Name: "Grafana", Query: "tag:grafana", Filters: map[string][]string{"ext": {"png"}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		reopened := &SavedSearches{appName: s.appName, fileName: s.fileName, o: s.o}
+		got, err := reopened.Get(created.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Query != "tag:grafana" || got.Filters["ext"][0] != "png" {
+			t.Errorf("expected saved search to be read back, got %+v", got)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		s := newTestSavedSearches(t)
+
+		if _, err := s.Get("missing"); !errors.Is(err, ErrSavedSearchNotFound) {
+			t.Errorf("expected ErrSavedSearchNotFound, got %v", err)
+		}
+		if err := s.Rename("missing", "name"); !errors.Is(err, ErrSavedSearchNotFound) {
+			t.Errorf("expected ErrSavedSearchNotFound, got %v", err)
+		}
+		if err := s.Delete("missing"); !errors.Is(err, ErrSavedSearchNotFound) {
+			t.Errorf("expected ErrSavedSearchNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Config dir error", func(t *testing.T) {
+		s := &SavedSearches{o: &mockOsProvider{userConfigDirErr: errors.New("no config dir")}}
+
+		if _, err := s.List(); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestSaveSearch(t *testing.T) {
+	s := &ScreenshotService{
+		Dir: newMockDirProvider("/home/me", nil, nil),
+		Indexer: &Indexer{idx: newTestIndex(t, testDocs()...)},
+		Saved: newTestSavedSearches(t),
+		ctx: context.Background(),
+	}
+
+	t.Run("Smart collection is counted", func(t *testing.T) {
+		search, err := s.SaveSearch(" Grafana ", "tag:grafana", SearchOptions{Filters: map[string][]string{"ext": {"png"}}}, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if search.Name != "Grafana" || search.Count != 2 {
+			t.Errorf("expected Grafana with 2 matches, got %+v", search)
+		}
+	})
+
+	t.Run("Filters narrow the count", func(t *testing.T) {
+		search, err := s.SaveSearch("Work", "in:~/Desktop/work", SearchOptions{Filters: map[string][]string{"ext": {"jpg"}}}, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if search.Count != 0 {
+			t.Errorf("expected no jpgs in work, got %d", search.Count)
+		}
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		_, err := s.SaveSearch("Broken", `"unterminated`, SearchOptions{}, false)
+		var qerr *QueryError
+		if !errors.As(err, &qerr) {
+			t.Errorf("expected a QueryError, got %v", err)
+		}
+	})
+
+	t.Run("Missing name", func(t *testing.T) {
+		if _, err := s.SaveSearch("  ", "grafana", SearchOptions{}, false); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	searches, _ := s.SavedSearches()
+	if len(searches) != 2 {
+		t.Errorf("expected 2 saved searches, got %d", len(searches))
+	}
+}
+
+func TestRefreshCollections(t *testing.T) {
+	events := NewChannelEvents(10)
+	s := &ScreenshotService{
+		Dir: newMockDirProvider("/home/me", nil, nil),
+		Indexer: &Indexer{idx: newTestIndex(t, testDocs()...)},
+		Saved: newTestSavedSearches(t),
+		Events: events,
+		ctx: context.Background(),
+	}
+
+	// saved before the query syntax changed, it no longer parses
+	if _, err := s.Saved.Create(SavedSearch{Name: "Broken", Query: `"unterminated`, Smart: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	grafana, err := s.Saved.Create(SavedSearch{Name: "Grafana", Query: "tag:grafana", Smart: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.refreshCollections()
+
+	if len(events.C) != 1 {
+		t.Fatalf("expected one collection:count event, got %d", len(events.C))
+	}
+	event := <-events.C
+	if search, ok := event.Data.(*SavedSearch); event.Name != "collection:count" || !ok || search.ID != grafana.ID || search.Count == 0 {
+		t.Errorf("expected Grafana to be counted past the broken collection, got %+v", event)
+	}
+}
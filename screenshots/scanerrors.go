@@ -0,0 +1,125 @@
+package screenshots
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Stages of indexing a file a ScanError can happen in
+const (
+	StageRead = "read"
+	StageOCR = "ocr"
+	StageExtract = "extract"
+	StageIndex = "index"
+)
+
+var scanStages = []string{StageRead, StageOCR, StageExtract, StageIndex}
+
+// ErrScanFailed is returned when not a single file of a scan could be
+// indexed, the summary says why
+var ErrScanFailed = errors.New("every file failed to index")
+
+// ErrScanInProgress is returned when a scan is started while another one
+// runs, the service only runs one at a time
+var ErrScanInProgress = errors.New("a scan is already running")
+
+// ScanError is why a file couldn't be indexed. It is sent as a scan:error
+// event, listed in the ScanSummary and stored on the file's document as
+// its last error until it's indexed again.
+type ScanError struct {
+	Path string `json:"path"`
+	Stage string `json:"stage"`
+	Message string `json:"message"`
+
+	err error
+}
+
+func newScanError(path string, stage string, err error) *ScanError {
+	return &ScanError{Path: path, Stage: stage, Message: err.Error(), err: err}
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("%s: %s failed: %s", e.Path, e.Stage, e.Message)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.err
+}
+
+// ScanSummary is what a scan did, it is returned and sent as a scan:done
+// event
+type ScanSummary struct {
+	// Files counts the files scanned, Indexed the documents added from them
+	Files int `json:"files"`
+	Indexed int `json:"indexed"`
+	// Skipped counts the files without any text
+	Skipped int `json:"skipped"`
+	Failed int `json:"failed"`
+	Errors []ScanError `json:"errors"`
+}
+
+// asScanError returns err as a ScanError of path, errors from outside the
+// known stages count as indexing errors
+func asScanError(path string, err error) *ScanError {
+	var scanErr *ScanError
+	if errors.As(err, &scanErr) {
+		return scanErr
+	}
+
+	return newScanError(path, StageIndex, err)
+}
+
+// scanTally adds up the outcome of the files of a scan as they finish
+type scanTally struct {
+	mu sync.Mutex
+	summary ScanSummary
+}
+
+func (t *scanTally) add(docs int, err *ScanError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case err != nil:
+		t.summary.Failed++
+		t.summary.Errors = append(t.summary.Errors, *err)
+	case docs == 0:
+		t.summary.Skipped++
+	default:
+		t.summary.Indexed += docs
+	}
+}
+
+// result returns the summary and ErrScanFailed if no file made it
+func (t *scanTally) result() (*ScanSummary, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summary := t.summary
+	if summary.Failed > 0 && summary.Failed == summary.Files {
+		return &summary, fmt.Errorf("%w: %s", ErrScanFailed, summary.Errors[0].Error())
+	}
+
+	return &summary, nil
+}
+
+// recordFailure stores a scan error on the document of the file, so it can
+// be found with has:error. A document indexed before keeps its text, a new
+// file gets a document with just its metadata.
+func (s *ScreenshotService) recordFailure(scanErr *ScanError) error {
+	doc, err := s.storedDoc(scanErr.Path)
+	if err != nil {
+		doc = &ScreenshotDoc{Path: scanErr.Path}
+		if info, err := os.Stat(scanErr.Path); err == nil {
+			applyMetadata(doc, scanErr.Path, info, nil)
+		}
+		s.annotate(doc)
+	}
+
+	doc.LastError = scanErr.Message
+	doc.ErrorStage = scanErr.Stage
+
+	return s.Indexer.Index(doc.Path, doc)
+}
@@ -0,0 +1,192 @@
+package screenshots
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingOCR reads every file as text but fails on the files named broken
+func failingOCR(text string) *OCR {
+	return NewMockOCR(nil, "ocr", &mockFileSystem{tempFile: &mockFile{name: "ocr-helper"}}, &mockCmdRunner{
+		commandFn: func(name string, arg ...string) ([]byte, error) {
+			if len(arg) > 0 && strings.Contains(filepath.Base(arg[0]), "broken") {
+				return nil, errors.New("vision request failed")
+			}
+			return []byte(text), nil
+		},
+	})
+}
+
+func TestScanErrors(t *testing.T) {
+	dir := t.TempDir()
+	paths := make([]string, 0, 3)
+	for _, name := range []string{"good.png", "broken.png", "broken-new.png"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, newTestPNG(t, 40, 20), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+	good, broken, brokenNew := paths[0], paths[1], paths[2]
+
+	events := NewChannelEvents(50)
+	s := newTestService(t, dir, nil, "invoice total", events)
+	if _, err := s.ScanFiles([]string{good, broken}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for len(events.C) > 0 {
+		<-events.C
+	}
+
+	s.OCR = failingOCR("invoice total")
+	summary, err := s.ScanFiles(paths)
+	if err != nil {
+		t.Fatalf("expected no error while a file is indexed, got %v", err)
+	}
+	if summary.Files != 3 || summary.Indexed != 1 || summary.Failed != 2 || len(summary.Errors) != 2 {
+		t.Fatalf("expected two failures, got %+v", summary)
+	}
+	for _, scanErr := range summary.Errors {
+		if scanErr.Stage != StageOCR || scanErr.Message != "vision request failed" {
+			t.Errorf("expected an ocr error, got %+v", scanErr)
+		}
+	}
+
+	failed := 0
+	for len(events.C) > 0 {
+		event := <-events.C
+		if event.Name == "scan:error" {
+			failed++
+		}
+		if event.Name == "scan:done" && event.Data.(*ScanSummary).Failed != 2 {
+			t.Errorf("expected the summary with scan:done, got %+v", event.Data)
+		}
+	}
+	if failed != 2 {
+		t.Errorf("expected 2 scan:error events, got %d", failed)
+	}
+
+	tests := []struct {
+		query string
+		expected uint64
+	}{
+		{"has:error", 2},
+		{"error:ocr", 2},
+		{"error:index", 0},
+		{"invoice has:error", 1},
+	}
+	for _, tt := range tests {
+		results, err := s.Search(tt.query, SearchOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tt.query, err)
+		}
+		if results.Total != tt.expected {
+			t.Errorf("expected %d hits for %q, got %d", tt.expected, tt.query, results.Total)
+		}
+		for len(events.C) > 0 {
+			<-events.C
+		}
+	}
+
+	// a file indexed before keeps its text, a new one gets its metadata
+	doc, err := s.Document(broken)
+	if err != nil || doc.Text != "invoice total" || doc.ErrorStage != StageOCR {
+		t.Errorf("expected the old text and the error, got %+v, %v", doc, err)
+	}
+	doc, err = s.Document(brokenNew)
+	if err != nil || doc.Size == 0 || doc.LastError != "vision request failed" {
+		t.Errorf("expected the metadata and the error, got %+v, %v", doc, err)
+	}
+
+	// indexing a file again clears its error
+	s.OCR = NewMockOCR(nil, "ocr", &mockFileSystem{tempFile: &mockFile{name: "ocr-helper"}}, &mockCmdRunner{output: []byte("invoice paid")})
+	if _, err := s.ScanFiles([]string{brokenNew}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc, _ := s.Document(brokenNew); doc.LastError != "" || doc.ErrorStage != "" {
+		t.Errorf("expected the error to be cleared, got %+v", doc)
+	}
+}
+
+func TestScanFailed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.png")
+	if err := os.WriteFile(path, newTestPNG(t, 40, 20), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", path, err)
+	}
+
+	s := newTestService(t, dir, nil, "", nil)
+	s.OCR = failingOCR("invoice total")
+	var logs strings.Builder
+	s.Log = NewLogger(&logs, slog.LevelInfo)
+
+	summary, err := s.ScanFiles([]string{path})
+	if !errors.Is(err, ErrScanFailed) {
+		t.Errorf("expected ErrScanFailed, got %v", err)
+	}
+	if !strings.Contains(logs.String(), `level=WARN msg="file failed to index" path=`+path+` stage=ocr error="vision request failed"`) {
+		t.Errorf("expected the failure to be logged, got %s", logs.String())
+	}
+	if summary == nil || summary.Failed != 1 {
+		t.Errorf("expected the summary, got %+v", summary)
+	}
+
+	// files without text are skipped, not failed
+	s.OCR = NewMockOCR(nil, "ocr", &mockFileSystem{tempFile: &mockFile{name: "ocr-helper"}}, &mockCmdRunner{})
+	summary, err = s.ScanFiles([]string{path})
+	if err != nil || summary.Skipped != 1 || summary.Failed != 0 {
+		t.Errorf("expected the file to be skipped, got %+v, %v", summary, err)
+	}
+}
+
+func TestPagedScanFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.7"), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", path, err)
+	}
+
+	s := newTestService(t, dir, nil, "", nil)
+	fs := &mockFileSystem{
+		tempFile: &mockFile{name: "/tmp/glimpse-page"},
+		files: map[string][]byte{"/tmp/glimpse-page.png": []byte("png")},
+	}
+	s.OCR = NewMockOCR(nil, "ocr", fs, &mockCmdRunner{cmdError: errors.New("pdfinfo not found")})
+	if _, err := s.ScanFiles([]string{path}); !errors.Is(err, ErrScanFailed) {
+		t.Fatalf("expected ErrScanFailed, got %v", err)
+	}
+	if doc, err := s.Document(path); err != nil || doc.ErrorStage != StageExtract {
+		t.Fatalf("expected the error on the file, got %+v, %v", doc, err)
+	}
+
+	// the pages replace the error once the file is read
+	s.OCR = NewMockOCR(nil, "ocr", fs, &mockCmdRunner{
+		commandFn: func(name string, arg ...string) ([]byte, error) {
+			switch name {
+			case pdfInfoBinary:
+				return []byte(testPDFInfo), nil
+			case pdfToTextBinary:
+				return []byte("incident report"), nil
+			}
+			return nil, nil
+		},
+	})
+	summary, err := s.ScanFiles([]string{path})
+	if err != nil || summary.Indexed != 2 {
+		t.Fatalf("expected both pages to be indexed, got %+v, %v", summary, err)
+	}
+	if _, err := s.Document(path); !errors.Is(err, ErrNotIndexed) {
+		t.Errorf("expected the error document to be removed, got %v", err)
+	}
+	results, err := s.Search("has:error", SearchOptions{})
+	if err != nil || results.Total != 0 {
+		t.Errorf("expected no failed files, got %+v, %v", results, err)
+	}
+	if _, err := s.Document(path + "#page=1"); err != nil {
+		t.Errorf("expected the first page, got %v", err)
+	}
+}
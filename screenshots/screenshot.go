@@ -3,20 +3,47 @@ package screenshots
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
-
-	rake "github.com/afjoseph/RAKE.go"
-	"github.com/blevesearch/bleve/v2"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"sync/atomic"
+	"time"
 
 	b64 "encoding/base64"
 )
 
 type Service interface {
-	ScanAndIndex() error
-	Search(query string) error
+	ScanAndIndex() (*ScanSummary, error)
+	ScanDir(dir string) (*ScanSummary, error)
+	ScanFiles(paths []string) (*ScanSummary, error)
+	Reindex() (*ScanSummary, error)
+	ReindexIfOutdated() (*ScanSummary, error)
+	Documents(fn func(doc *ScreenshotDoc) error) error
+	Stats() (*IndexStats, error)
+	Search(query string, opts SearchOptions) (*SearchResults, error)
+	SearchHits(query string, opts SearchOptions) (*SearchResults, []SearchHit, error)
+	Document(id string) (*ScreenshotDoc, error)
+	Image(id string) ([]byte, string, error)
+	Thumbnail(id string, size int) ([]byte, string, error)
+	Suggest(prefix string) ([]Suggestion, error)
+	SemanticSearchAvailable() bool
+	SavedSearches() ([]SavedSearch, error)
+	SaveSearch(name string, query string, opts SearchOptions, smart bool) (*SavedSearch, error)
+	RenameSavedSearch(id string, name string) error
+	DeleteSavedSearch(id string) error
+	RunSavedSearch(id string, opts SearchOptions) (*SearchResults, error)
+	FindSimilar(path string, maxDistance int) ([]SimilarImage, error)
+	FindDuplicates(opts DuplicateOptions) (*DuplicateReport, error)
+	ResolveDuplicates(groups []DuplicateGroup, keep string, dryRun bool) (*DuplicateResolution, error)
+	UndoDuplicates(batchID string) (*DuplicateResolution, error)
+	AddTags(id string, tags []string) (*Annotation, error)
+	RemoveTags(id string, tags []string) (*Annotation, error)
+	SetNote(id string, note string) (*Annotation, error)
+	RecomputeTags(all bool) (int, error)
+	SetKeywordExtractor(name string, opts KeywordOptions) error
 	Shutdown()
 }
 
@@ -24,14 +51,119 @@ type ScreenshotService struct {
 	Dir DirProvider
 	OCR OCRProvider
 	Indexer IndexerProvider
+	Saved SavedSearchProvider
+	Annotations AnnotationProvider
+	// Keywords turns the text of a screenshot into its tags, RAKE unless
+	// set otherwise
+	Keywords KeywordExtractor
+	KeywordOptions KeywordOptions
+	// Normalizer cleans up OCR'd text before it is indexed and tagged, it
+	// is skipped when nil
+	Normalizer *TextNormalizer
+	// Classifier files screenshots under a category, they are left
+	// uncategorised when nil
+	Classifier Classifier
+	// Embedder is optional, without it documents are indexed without an
+	// embedding and only keyword search is available
+	Embedder Embedder
+	// Events receives scan progress and search hits, they are dropped when
+	// nil
+	Events EventSink
+	// Log records what the service does, slog.Default() when nil
+	Log *slog.Logger
 
 	ctx context.Context
+
+	// the latest search-as-you-type query and how to cancel it
+	searchMu sync.Mutex
+	searchID int64
+	cancelSearch context.CancelFunc
+
+	// perceptual hashes of the indexed images for FindSimilar
+	similar similarImages
+
+	// duplicates moved to the trash by ResolveDuplicates
+	undo *undoLog
+
+	// guards Keywords and KeywordOptions when SetKeywordExtractor changes
+	// them during a scan
+	keywordsMu sync.RWMutex
+
+	// set while a scan runs
+	scanning atomic.Bool
+
+	// queries searched for, most recent first
+	recentMu sync.Mutex
+	recent []string
 }
 
 type ScreenshotDoc struct {
 	Path string `json:"path"`
 	Tags []string `json:"tags"`
+	// TagExtractor is the name of the KeywordExtractor that chose Tags
+	TagExtractor string `json:"tag_extractor,omitempty"`
+	Text string `json:"text"`
+	// UserTags and Note are added by hand and kept outside the index, Tags
+	// are extracted from the text on every scan
+	UserTags []string `json:"user_tags,omitempty"`
+	Note string `json:"note,omitempty"`
 	URL string `json:"url"`
+
+	// Parent is set on documents that are a single page or frame of a PDF or
+	// screen recording, and points to the file they were taken from
+	Parent string `json:"parent,omitempty"`
+	Page int `json:"page,omitempty"`
+	Timestamp float64 `json:"timestamp,omitempty"`
+
+	Dir string `json:"dir"`
+	Ext string `json:"ext"`
+	Size int64 `json:"size"`
+	Created time.Time `json:"created"`
+	// Month is the capture month as YYYY-MM, for faceting
+	Month string `json:"month"`
+	Modified time.Time `json:"modified"`
+	Width int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	DPI float64 `json:"dpi,omitempty"`
+	Scale float64 `json:"scale,omitempty"`
+	EXIF map[string]string `json:"exif,omitempty"`
+	SourceApp string `json:"source_app,omitempty"`
+	WindowTitle string `json:"window_title,omitempty"`
+	// Category is what the screenshot shows, like terminal or code, see
+	// Classifier
+	Category string `json:"category,omitempty"`
+	// Code repeats the text of screenshots of code for an analyzer that
+	// splits identifiers, Language is guessed from it
+	Code string `json:"code,omitempty"`
+	Language string `json:"language,omitempty"`
+
+	// WordBoxes holds the encoded position of every OCR'd word when word
+	// boxes are enabled, it is stored but not searchable
+	WordBoxes string `json:"word_boxes,omitempty"`
+
+	// PHash is the perceptual hash of the image as 16 hex digits, used to
+	// find similar images
+	PHash string `json:"phash,omitempty"`
+	// ContentHash is the SHA-256 of the file, used to find exact duplicates
+	ContentHash string `json:"content_hash,omitempty"`
+
+	// Entities are extracted from Text, each type in its own keyword field
+	Entities
+
+	// LastError and ErrorStage are set when the file failed to index on
+	// the last scan, see ScanError
+	LastError string `json:"last_error,omitempty"`
+	ErrorStage string `json:"error_stage,omitempty"`
+
+	// Embedding is the vector of the text for semantic search, indexed but
+	// not stored
+	Embedding []float32 `json:"embedding,omitempty"`
+
+	// Highlights and Regions are only set on search results. Highlights are
+	// HTML-escaped snippets per field with matches wrapped in <mark>, Regions
+	// are the boxes of the matched words on the image.
+	Highlights map[string][]string `json:"highlights,omitempty"`
+	Regions []WordBox `json:"regions,omitempty"`
 }
 
 var supportedImageExts = map[string]struct{}{
@@ -42,62 +174,128 @@ var supportedImageExts = map[string]struct{}{
     ".svg":  {},
 }
 
-func NewScreenshotService(d DirProvider, o OCRProvider, i IndexerProvider, ctx context.Context) Service {
+// NewScreenshotService returns the service, sending its events to events,
+// or nowhere if events is nil
+func NewScreenshotService(d DirProvider, o OCRProvider, i IndexerProvider, sv SavedSearchProvider, an AnnotationProvider, e Embedder, events EventSink, ctx context.Context) Service {
+	if events == nil {
+		events = NoEvents{}
+	}
+
 	return &ScreenshotService{
 		Dir: d,
 		OCR: o,
 		Indexer: i,
+		Saved: sv,
+		Annotations: an,
+		Keywords: &RAKE{},
+		Normalizer: NewTextNormalizer(),
+		Classifier: &HeuristicClassifier{},
+		Embedder: e,
+		Events: events,
 		ctx: ctx,
+		undo: newUndoLog(),
 	}
 }
 
-func (s *ScreenshotService) ScanAndIndex() error {
+// ScanAndIndex indexes the screenshots on the desktop
+func (s *ScreenshotService) ScanAndIndex() (*ScanSummary, error) {
 	homeDir, err := s.Dir.GetHomeDir()
 	if err != nil {
-		return fmt.Errorf("error getting homedir: %v", err)
+		return nil, fmt.Errorf("error getting homedir: %v", err)
 	}
 
 	entries, err := s.Dir.ReadDir(homeDir)
 	if err != nil {
-		return fmt.Errorf("error reading screenshots dir: %v", err)
+		return nil, fmt.Errorf("error reading screenshots dir: %v", err)
 	}
 
-	var wg sync.WaitGroup
-	resultChan := make(chan ScreenshotDoc, len(entries))
-	errChan := make(chan error, 100)
+	return s.ScanFiles(supportedPaths(filepath.Join(homeDir, "Desktop"), entries))
+}
 
-	_, err = s.OCR.WriteOCRHelper()
+// ScanDir indexes the supported files in dir, without its subdirectories
+func (s *ScreenshotService) ScanDir(dir string) (*ScanSummary, error) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return fmt.Errorf("error reading binary for OR: %v", err)
+		return nil, fmt.Errorf("error reading %s: %v", dir, err)
 	}
 
-	err = s.Indexer.Open()
+	return s.ScanFiles(supportedPaths(dir, entries))
+}
+
+func supportedPaths(dir string, entries []fs.DirEntry) []string {
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() && IsSupported(fullPath) {
+			paths = append(paths, fullPath)
+		}
+	}
+
+	return paths
+}
+
+// ScanProgress is sent as a scan:progress event after every file of a scan
+type ScanProgress struct {
+	// Done counts the files scanned out of Total, Path is the last one
+	Done int `json:"done"`
+	Total int `json:"total"`
+	Path string `json:"path"`
+}
+
+// ScanFiles indexes the given files, sending a result:found event for every
+// document added, a scan:progress event for every file and a scan:error
+// event for every file that fails. It returns an error if none could be
+// indexed, the summary lists what failed either way. Only one scan runs at
+// a time, ErrScanInProgress is returned while another one does.
+func (s *ScreenshotService) ScanFiles(paths []string) (*ScanSummary, error) {
+	done, err := s.startScan()
 	if err != nil {
-		return fmt.Errorf("error opening indexer: %v", err)
+		return nil, err
 	}
+	defer done()
 
-	go func(){
-		for r := range resultChan {
-			fmt.Println(r)
-			runtime.EventsEmit(s.ctx, "result:found", r)
-		}  
-	}()
+	return s.scanFiles(paths)
+}
 
-	go func(){
-		for e := range errChan {
-			fmt.Println(e)
-		}  
-	}()
+// startScan claims the one scan the service runs at a time, the returned
+// func releases it
+func (s *ScreenshotService) startScan() (func(), error) {
+	if !s.scanning.CompareAndSwap(false, true) {
+		return nil, ErrScanInProgress
+	}
 
-	for _, entry := range entries {
-		fullPath := filepath.Join(filepath.Join(homeDir, "Desktop"), entry.Name())
+	return func() { s.scanning.Store(false) }, nil
+}
 
-		ext := filepath.Ext(fullPath)
+func (s *ScreenshotService) scanFiles(paths []string) (*ScanSummary, error) {
+	var wg sync.WaitGroup
+	var done atomic.Int64
+	start := time.Now()
+	tally := &scanTally{summary: ScanSummary{Files: len(paths), Errors: make([]ScanError, 0)}}
+	resultChan := make(chan ScreenshotDoc, len(paths))
 
-		if _, ok := supportedImageExts[ext]; !ok {
-			continue
+	_, err := s.OCR.WriteOCRHelper()
+	if err != nil {
+		return nil, fmt.Errorf("error reading binary for OR: %v", err)
+	}
+
+	err = s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	s.logger().Info("scan started", "files", len(paths))
+
+	// every result is sent before returning
+	sent := make(chan struct{})
+	go func(){
+		for r := range resultChan {
+			s.emit("result:found", r)
 		}
+		close(sent)
+	}()
 
+	for _, fullPath := range paths {
 		wg.Add(1)
 		go func(fullPath string){
 			defer wg.Done()
@@ -109,80 +307,356 @@ func (s *ScreenshotService) ScanAndIndex() error {
 			default:
 			}
 
-			text, err := s.OCR.ExtractText(fullPath)
+			docs, err := s.indexFile(fullPath)
 			if err != nil {
-				errChan <- fmt.Errorf("error extracting text %v", err)
-				return
+				scanErr := asScanError(fullPath, err)
+				tally.add(0, scanErr)
+				s.emit("scan:error", scanErr)
+				s.logger().Warn("file failed to index", "path", fullPath, "stage", scanErr.Stage, "error", scanErr.Message)
+				// the failure is only for display, the scan goes on
+				if err := s.recordFailure(scanErr); err != nil {
+					s.logger().Error("error recording scan failure", "path", fullPath, "error", err)
+				}
+			} else {
+				tally.add(len(docs), nil)
+				s.logger().Debug("file indexed", "path", fullPath, "documents", len(docs))
 			}
-			if len(text) == 0 { // skip screenshots with no texts
-				return
-			}
-
-			candidates := rake.RunRake(text)
 
-			// get the first 20 tags (sorted by how relevant it is)
-			tags := make([]string, 0, 20)
-			
-			for i := 0; i < cap(tags) && i < len(candidates); i++ {
-				tags = append(tags, candidates[i].Key)
+			for _, doc := range docs {
+				resultChan <- doc
 			}
-
-			bytes, err := os.ReadFile(fullPath)
-			if err != nil {
-				errChan <- fmt.Errorf("error reading file: %v", err)
-				return
-			}
-			
-			doc := ScreenshotDoc{
+			s.emit("scan:progress", ScanProgress{
+				Done: int(done.Add(1)),
+				Total: len(paths),
 				Path: fullPath,
-				Tags: tags,
-				URL: b64.StdEncoding.EncodeToString(bytes),
-			}
-
-			err = s.Indexer.Index(doc.Path, &doc)
-			if err != nil {
-				errChan <- fmt.Errorf("error indexing image: %v", err)
-				return
-			}
-
-			resultChan <- doc
+			})
 		}(fullPath)
 	}
 
 	wg.Wait()
 	close(resultChan)
-	close(errChan)
+	<-sent
+
+	summary, err := tally.result()
+	s.emit("scan:done", summary)
+	s.logger().Info("scan done",
+		"files", summary.Files,
+		"indexed", summary.Indexed,
+		"skipped", summary.Skipped,
+		"failed", summary.Failed,
+		"took", time.Since(start).Round(time.Millisecond),
+	)
+
+	if summary.Indexed > 0 {
+		s.refreshCollections()
+	}
+
+	return summary, err
+}
+
+// IsSupported reports whether the file at path is of a type that is indexed
+func IsSupported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if _, ok := supportedImageExts[ext]; ok {
+		return true
+	}
+	if _, ok := supportedDocumentExts[ext]; ok {
+		return true
+	}
+	_, ok := supportedVideoExts[ext]
+
+	return ok
+}
+
+// indexFile extracts and indexes a single file, returning the documents that
+// were added. PDFs and recordings produce one document per page or frame.
+func (s *ScreenshotService) indexFile(fullPath string) ([]ScreenshotDoc, error) {
+	ext := strings.ToLower(filepath.Ext(fullPath))
+	if _, ok := supportedImageExts[ext]; !ok {
+		return s.indexPages(fullPath)
+	}
+
+	text, err := s.OCR.ExtractText(fullPath)
+	if err != nil {
+		return nil, newScanError(fullPath, StageOCR, err)
+	}
+	if len(text) == 0 { // skip screenshots with no texts
+		return nil, nil
+	}
+
+	// word boxes only improve how hits are displayed and which words are
+	// trusted, a file is still worth indexing without them
+	words, err := s.OCR.ExtractWords(fullPath)
+	if err != nil {
+		s.logger().Debug("indexing without word boxes", "path", fullPath, "error", err)
+		words = nil
+	}
+
+	raw := text
+	text = s.normalize(text, words)
+	if len(text) == 0 { // nothing but noise
+		return nil, nil
+	}
+
+	bytes, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, newScanError(fullPath, StageRead, err)
+	}
 
-	return nil
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, newScanError(fullPath, StageRead, err)
+	}
+
+	doc := ScreenshotDoc{
+		Path: fullPath,
+		Text: text,
+		URL: b64.StdEncoding.EncodeToString(bytes),
+	}
+	s.tagDoc(&doc)
+	doc.Entities = ExtractEntities(text)
+	applyMetadata(&doc, fullPath, info, bytes)
+	s.classify(&doc, raw, words, bytes)
+	indexCode(&doc, raw)
+	s.annotate(&doc)
+	s.hashImage(&doc, bytes)
+	doc.ContentHash = contentHash(bytes)
+	s.embed(&doc)
+	if len(words) > 0 {
+		doc.WordBoxes = encodeWordBoxes(words)
+	}
+
+	err = s.Indexer.Index(doc.Path, &doc)
+	if err != nil {
+		return nil, newScanError(fullPath, StageIndex, err)
+	}
+	s.trackHash(&doc)
+
+	return []ScreenshotDoc{doc}, nil
+}
+
+// indexPages indexes every page of a document or frame of a recording as a
+// child of fullPath
+func (s *ScreenshotService) indexPages(fullPath string) ([]ScreenshotDoc, error) {
+	pages, err := s.OCR.ExtractPages(fullPath)
+	if err != nil {
+		return nil, newScanError(fullPath, StageExtract, err)
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, newScanError(fullPath, StageRead, err)
+	}
+
+	docs := make([]ScreenshotDoc, 0, len(pages))
+	for _, page := range pages {
+		text := s.normalize(page.Text, nil)
+		if len(text) == 0 {
+			continue
+		}
+
+		doc := ScreenshotDoc{
+			Path: childID(fullPath, page),
+			Text: text,
+			URL: b64.StdEncoding.EncodeToString(page.Image),
+			Parent: fullPath,
+			Page: page.Number,
+			Timestamp: page.Timestamp,
+		}
+		s.tagDoc(&doc)
+		doc.Entities = ExtractEntities(text)
+		// size, dates and source come from the parent file, dimensions
+		// from the rendered page
+		applyMetadata(&doc, fullPath, info, page.Image)
+		s.classify(&doc, page.Text, nil, page.Image)
+		indexCode(&doc, page.Text)
+		s.annotate(&doc)
+		s.hashImage(&doc, page.Image)
+		s.embed(&doc)
+
+		err = s.Indexer.Index(doc.Path, &doc)
+		if err != nil {
+			return nil, newScanError(fullPath, StageIndex, fmt.Errorf("page %d: %v", page.Number, err))
+		}
+		s.trackHash(&doc)
+
+		docs = append(docs, doc)
+	}
+
+	// the pages replace the document a failed scan left at the file's path
+	if err := s.Indexer.Delete(fullPath); err != nil {
+		return nil, newScanError(fullPath, StageIndex, err)
+	}
+
+	return docs, nil
+}
+
+// childID builds the document ID of a page or frame using media fragment
+// syntax, e.g. report.pdf#page=3 or demo.mov#t=12.500
+func childID(parent string, page Page) string {
+	if _, ok := supportedVideoExts[strings.ToLower(filepath.Ext(parent))]; ok {
+		return fmt.Sprintf("%s#t=%.3f", parent, page.Timestamp)
+	}
+
+	return fmt.Sprintf("%s#page=%d", parent, page.Number)
 }
 
-func (s *ScreenshotService) Search(keyword string) error {
+// Search sends a search:found event for every hit of the query and returns
+// the totals, facets and cursor of the page
+func (s *ScreenshotService) Search(keyword string, opts SearchOptions) (*SearchResults, error) {
+	return s.search(keyword, opts, func(hit SearchHit) {
+		s.emit("search:found", hit)
+	})
+}
+
+// SearchHits runs a search like Search but returns the hits instead of
+// sending them as events, for callers that aren't listening to them
+func (s *ScreenshotService) SearchHits(keyword string, opts SearchOptions) (*SearchResults, []SearchHit, error) {
+	hits := make([]SearchHit, 0)
+	results, err := s.search(keyword, opts, func(hit SearchHit) {
+		hits = append(hits, hit)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return results, hits, nil
+}
+
+// search runs a query, passing each hit to send in order
+func (s *ScreenshotService) search(keyword string, opts SearchOptions, send func(hit SearchHit)) (*SearchResults, error) {
 	err := s.Indexer.Open()
 	if err != nil {
-		return fmt.Errorf("error opening indexer: %v", err)
+		return nil, fmt.Errorf("error opening indexer: %w", err)
 	}
 
-	query := bleve.NewQueryStringQuery(keyword)
-	searchRequest := bleve.NewSearchRequest(query)
-	searchRequest.Fields = []string{"*"}
+	homeDir, err := s.Dir.GetHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting homedir: %v", err)
+	}
 
-	searchResult, err := s.Indexer.Search(searchRequest)
+	parser := &queryParser{
+		homeDir: homeDir,
+		fuzziness: opts.Fuzziness,
+		prefixLast: opts.AsYouType,
+	}
+
+	query, err := parser.parse(keyword)
 	if err != nil {
-		return err
+		return nil, s.queryFailed(err, opts.ID)
+	}
+
+	searchRequest, err := newSearchRequest(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	semantic, err := isSemantic(opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+	if semantic {
+		if err := s.addSemantic(searchRequest, keyword, parser, opts); err != nil {
+			return nil, s.queryFailed(err, opts.ID)
+		}
+	}
+
+	ctx, cancel, err := s.beginSearch(opts.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	searchResult, err := s.Indexer.Search(ctx, searchRequest)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ErrStaleSearch
+		}
+		s.logger().Error("search failed", "query", keyword, "error", err)
+		return nil, err
 	}
 
 	for _, d := range searchResult.Hits {
+		// a newer query took over while this one was sending its hits
+		if !s.isCurrentSearch(opts.ID) {
+			return nil, ErrStaleSearch
+		}
+
+		// files that failed to index may have no image
+		url, _ := d.Fields["url"].(string)
 		doc := ScreenshotDoc{
 			Path: d.ID,
-			URL: d.Fields["url"].(string),
+			URL: url,
+		}
+		if parent, ok := d.Fields["parent"].(string); ok {
+			doc.Parent = parent
 		}
+		if page, ok := d.Fields["page"].(float64); ok {
+			doc.Page = int(page)
+		}
+		if ts, ok := d.Fields["timestamp"].(float64); ok {
+			doc.Timestamp = ts
+		}
+		if width, ok := d.Fields["width"].(float64); ok {
+			doc.Width = int(width)
+		}
+		if height, ok := d.Fields["height"].(float64); ok {
+			doc.Height = int(height)
+		}
+		doc.UserTags = storedStrings(d.Fields["user_tags"])
+		if note, ok := d.Fields["note"].(string); ok {
+			doc.Note = note
+		}
+		doc.Entities = entitiesFromFields(d.Fields)
+		if category, ok := d.Fields["category"].(string); ok {
+			doc.Category = category
+		}
+		if language, ok := d.Fields["language"].(string); ok {
+			doc.Language = language
+		}
+		if lastError, ok := d.Fields["last_error"].(string); ok {
+			doc.LastError = lastError
+			doc.ErrorStage, _ = d.Fields["error_stage"].(string)
+		}
+		doc.Highlights = d.Fragments
+		if boxes, ok := d.Fields["word_boxes"].(string); ok {
+			doc.Regions = matchedRegions(decodeWordBoxes(boxes), d.Locations)
+		}
+
+		send(SearchHit{ScreenshotDoc: doc, QueryID: opts.ID})
+	}
+
+	results := newSearchResults(searchResult, searchRequest)
+	results.QueryID = opts.ID
+	s.logger().Debug("search", "query", keyword, "mode", opts.Mode, "total", results.Total, "took", searchResult.Took)
+
+	// only submitted queries are worth suggesting again, not every keystroke
+	if !opts.AsYouType && opts.Cursor == nil {
+		s.addRecentQuery(keyword)
+	}
+
+	return results, nil
+}
 
-		runtime.EventsEmit(s.ctx, "search:found", doc)
+// queryFailed tags a query error with the query ID and sends it as a
+// search:error event so the UI can point at the offending input
+func (s *ScreenshotService) queryFailed(err error, id int64) error {
+	qerr, ok := err.(*QueryError)
+	if !ok {
+		return err
 	}
 
-	return nil
+	qerr.QueryID = id
+	if s.isCurrentSearch(id) {
+		s.emit("search:error", qerr)
+	}
+
+	return qerr
 }
 
 func (s *ScreenshotService) Shutdown() {
-	s.Indexer.Close()
+	if err := s.Indexer.Close(); err != nil {
+		s.logger().Warn("error closing index", "error", err)
+	}
 }
\ No newline at end of file
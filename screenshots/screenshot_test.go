@@ -1,42 +1,125 @@
 package screenshots
 
-// import (
-// 	"io/fs"
-// 	"testing"
-// )
-
-// type fakeDir struct {
-// 	homeDir string
-// 	entries []fs.DirEntry
-// 	errHomeDir error
-// 	errReadDir error
-// }
-// func (f *fakeDir) GetHomeDir() (string, error) { return f.homeDir, f.errHomeDir}
-// func (f *fakeDir) ReadDir(string) ([]fs.DirEntry, error) { return f.entries, f.errReadDir}
-
-// type fakeOcr struct {}
-// func (f *fakeOcr) ExtractText(path string) (string, error) { return "extracted text", nil}
-// func (f *fakeOcr) Close()
-
-// type fakeEntry struct { name string }
-// func (f fakeEntry) Name() string               { return f.name }
-// func (fakeEntry) IsDir() bool                  { return false }
-// func (fakeEntry) Type() fs.FileMode            { return 0 }
-// func (fakeEntry) Info() (fs.FileInfo, error)   { return nil, nil }
-
-// func TestScanAndIndex(t *testing.T) {
-// 	dir := &fakeDir{
-//         homeDir: "/home",
-// 		entries: []fs.DirEntry{
-// 			fakeEntry{name: "img1.png"},
-// 			fakeEntry{name: "img2.png"},
-// 		},
-//     }
-// 	ocr := &fakeOcr{}
-
-// 	svc := NewScreenshotService(dir, ocr)
-// 	if 	err := svc.ScanAndIndex(); err != nil {
-// 		t.Fatal("expected no error; got", err)
-// 	}
-
-// }
\ No newline at end of file
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestService returns a service indexing into memory, with OCR that
+// reads every file as text
+func newTestService(t *testing.T, homeDir string, entries []fs.DirEntry, text string, events EventSink) *ScreenshotService {
+	t.Helper()
+
+	ocr := NewMockOCR(nil, "ocr", &mockFileSystem{tempFile: &mockFile{name: "ocr-helper"}}, &mockCmdRunner{output: []byte(text)})
+	return NewScreenshotService(
+		newMockDirProvider(homeDir, entries, nil),
+		ocr,
+		&Indexer{idx: newTestIndex(t)},
+		newTestSavedSearches(t),
+		newTestAnnotations(t),
+		nil,
+		events,
+		context.Background(),
+	).(*ScreenshotService)
+}
+
+func TestScanAndIndex(t *testing.T) {
+	homeDir := t.TempDir()
+	desktop := filepath.Join(homeDir, "Desktop")
+	if err := os.MkdirAll(desktop, 0755); err != nil {
+		t.Fatalf("error creating desktop: %v", err)
+	}
+	for _, name := range []string{"one.png", "two.png"} {
+		if err := os.WriteFile(filepath.Join(desktop, name), newTestPNG(t, 40, 20), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+	}
+	entries := []fs.DirEntry{
+		&MockDirEntry{name: "one.png"},
+		&MockDirEntry{name: "two.png"},
+		&MockDirEntry{name: "notes.txt"},
+	}
+
+	events := NewChannelEvents(20)
+	s := newTestService(t, homeDir, entries, "deploy failed with error 502", events)
+
+	summary, err := s.ScanAndIndex()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary.Files != 2 || summary.Indexed != 2 || summary.Failed != 0 {
+		t.Errorf("expected two files indexed, got %+v", summary)
+	}
+
+	found := make(map[string]bool)
+	for len(events.C) > 0 {
+		event := <-events.C
+		if event.Name == "result:found" {
+			found[filepath.Base(event.Data.(ScreenshotDoc).Path)] = true
+		}
+	}
+	if len(found) != 2 || !found["one.png"] || !found["two.png"] {
+		t.Errorf("expected both screenshots to be sent, got %v", found)
+	}
+
+	results, err := s.Search("error 502", SearchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results.Total != 2 || len(events.C) != 2 {
+		t.Errorf("expected both screenshots to be found and sent, got %d and %d events", results.Total, len(events.C))
+	}
+	if event := <-events.C; event.Name != "search:found" {
+		t.Errorf("expected a search:found event, got %s", event.Name)
+	}
+}
+
+func TestScanAndIndexDirError(t *testing.T) {
+	s := newTestService(t, "", nil, "", nil)
+	s.Dir = newMockDirProvider("", nil, errors.New("permission denied"))
+
+	if _, err := s.ScanAndIndex(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestOneScanAtATime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "one.png")
+	if err := os.WriteFile(path, newTestPNG(t, 40, 20), 0644); err != nil {
+		t.Fatalf("error writing png: %v", err)
+	}
+	s := newTestService(t, dir, nil, "invoice", nil)
+
+	done, err := s.startScan()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.ScanFiles([]string{path}); !errors.Is(err, ErrScanInProgress) {
+		t.Errorf("expected ErrScanInProgress, got %v", err)
+	}
+	if _, err := s.Reindex(); !errors.Is(err, ErrScanInProgress) {
+		t.Errorf("expected the reindex to wait for the scan, got %v", err)
+	}
+
+	done()
+	if summary, err := s.ScanFiles([]string{path}); err != nil || summary.Indexed != 1 {
+		t.Errorf("expected the file to be indexed once the scan is done, got %+v, %v", summary, err)
+	}
+}
+
+func TestNoEvents(t *testing.T) {
+	// without a sink the service still scans and searches
+	s := newTestService(t, "", nil, "", nil)
+	if _, ok := s.Events.(NoEvents); !ok {
+		t.Fatalf("expected NoEvents, got %T", s.Events)
+	}
+
+	if _, err := s.Search("anything", SearchOptions{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
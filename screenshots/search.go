@@ -0,0 +1,145 @@
+package screenshots
+
+import (
+	"fmt"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize = 100
+)
+
+const (
+	SortRelevance = "relevance"
+	SortNewest = "newest"
+	SortOldest = "oldest"
+	SortPath = "path"
+	SortSize = "size"
+)
+
+// sortOrders maps each sort option to bleve sort fields. Every order ends
+// on the document ID so ties are stable across pages.
+var sortOrders = map[string][]string{
+	SortRelevance: {"-_score", "_id"},
+	SortNewest: {"-created", "_id"},
+	SortOldest: {"created", "_id"},
+	SortPath: {"_id"},
+	SortSize: {"-size", "_id"},
+}
+
+// SearchOptions tune how a query is matched and which slice of the results
+// is returned
+type SearchOptions struct {
+	// ID identifies the query in a search-as-you-type session. A search
+	// cancels any in-flight search with a lower ID and its events carry the
+	// ID. 0 runs the search outside of the session.
+	ID int64 `json:"id"`
+	// AsYouType matches the last word as a prefix while it is still being
+	// typed
+	AsYouType bool `json:"asYouType"`
+
+	// Fuzziness is the edit distance (0-2) tolerated on free text and tags.
+	// Anything above 0 also matches prefixes and common OCR misreadings.
+	Fuzziness int `json:"fuzziness"`
+
+	// Page is 1-based, Size defaults to 20 and is capped at 100
+	Page int `json:"page"`
+	Size int `json:"size"`
+	// Cursor continues from the NextCursor of a previous page and takes
+	// precedence over Page, which suits infinite scrolling
+	Cursor []string `json:"cursor,omitempty"`
+	// Sort is one of relevance (default), newest, oldest, path or size
+	Sort string `json:"sort"`
+	// Filters are facet values picked from a previous search, keyed by
+	// facet name (dir, ext, month or tag)
+	Filters map[string][]string `json:"filters,omitempty"`
+
+	// Mode is keyword (default), semantic or hybrid. Semantic search ranks by
+	// how close the meaning of the free text is, hybrid adds that closeness,
+	// times SemanticWeight (default 1), to the keyword score.
+	Mode string `json:"mode,omitempty"`
+	SemanticWeight float64 `json:"semanticWeight,omitempty"`
+}
+
+// SearchResults describe the page of hits sent as search:found events
+type SearchResults struct {
+	QueryID int64 `json:"queryId"`
+	Total uint64 `json:"total"`
+	Page int `json:"page"`
+	Size int `json:"size"`
+	// NextCursor is empty on the last page
+	NextCursor []string `json:"nextCursor,omitempty"`
+	// Facets count the hits by dir, ext, month and tag
+	Facets map[string][]FacetValue `json:"facets,omitempty"`
+}
+
+func newSearchRequest(q query.Query, opts SearchOptions) (*bleve.SearchRequest, error) {
+	sortBy := sortOrders[SortRelevance]
+	if opts.Sort != "" {
+		order, ok := sortOrders[opts.Sort]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort order %q", opts.Sort)
+		}
+		sortBy = order
+	}
+
+	size := opts.Size
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	page := opts.Page
+	if page < 1 {
+		page = 1
+	}
+
+	from := (page - 1) * size
+	if len(opts.Cursor) > 0 {
+		if len(opts.Cursor) != len(sortBy) {
+			return nil, fmt.Errorf("cursor does not match sort order %q", opts.Sort)
+		}
+		from = 0
+	}
+
+	q, err := filterQuery(q, opts.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	request := bleve.NewSearchRequestOptions(q, size, from, false)
+	request.Fields = []string{"*"}
+	request.Highlight = newHighlight()
+	request.SortBy(sortBy)
+	if len(opts.Cursor) > 0 {
+		request.SetSearchAfter(opts.Cursor)
+	}
+	addFacets(request)
+
+	return request, nil
+}
+
+func newSearchResults(result *bleve.SearchResult, request *bleve.SearchRequest) *SearchResults {
+	results := &SearchResults{
+		Total: result.Total,
+		Page: request.From/request.Size + 1,
+		Size: request.Size,
+		Facets: collectFacets(result.Facets),
+	}
+
+	// a full page may be followed by more hits, hand out the sort values of
+	// its last hit to continue from
+	if len(result.Hits) == request.Size {
+		last := result.Hits[len(result.Hits)-1]
+		if request.SearchAfter != nil || uint64(request.From+request.Size) < result.Total {
+			results.NextCursor = last.Sort
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,117 @@
+package screenshots
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+func TestNewSearchRequest(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		req, err := newSearchRequest(bleve.NewMatchAllQuery(), SearchOptions{})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if req.Size != defaultPageSize || req.From != 0 {
+			t.Errorf("expected size %d from 0, got size %d from %d", defaultPageSize, req.Size, req.From)
+		}
+		if len(req.Sort) != 2 {
+			t.Errorf("expected relevance sort with id tie-breaker, got: %v", req.Sort)
+		}
+	})
+
+	t.Run("Page and size", func(t *testing.T) {
+		req, err := newSearchRequest(bleve.NewMatchAllQuery(), SearchOptions{Page: 3, Size: 500})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if req.Size != maxPageSize || req.From != 2*maxPageSize {
+			t.Errorf("expected size %d from %d, got size %d from %d", maxPageSize, 2*maxPageSize, req.Size, req.From)
+		}
+	})
+
+	t.Run("Unknown sort", func(t *testing.T) {
+		_, err := newSearchRequest(bleve.NewMatchAllQuery(), SearchOptions{Sort: "colour"})
+		if err == nil || err.Error() != `unknown sort order "colour"` {
+			t.Errorf("expected 'unknown sort order \"colour\"', got: %v", err)
+		}
+	})
+
+	t.Run("Cursor mismatch", func(t *testing.T) {
+		_, err := newSearchRequest(bleve.NewMatchAllQuery(), SearchOptions{Sort: SortPath, Cursor: []string{"a", "b"}})
+		if err == nil {
+			t.Errorf("expected cursor error, got nil")
+		}
+	})
+}
+
+func TestSearchPaging(t *testing.T) {
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	docs := make([]ScreenshotDoc, 0, 5)
+	for i := 0; i < 5; i++ {
+		docs = append(docs, ScreenshotDoc{
+			Path: fmt.Sprintf("/shots/%d.png", i),
+			Text: "invoice",
+			Created: base.AddDate(0, 0, i),
+			Size: int64(100 * (5 - i)),
+		})
+	}
+	idx := newTestIndex(t, docs...)
+
+	search := func(opts SearchOptions) (*bleve.SearchResult, *SearchResults) {
+		t.Helper()
+
+		req, err := newSearchRequest(bleve.NewMatchQuery("invoice"), opts)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		res, err := idx.Search(req)
+		if err != nil {
+			t.Fatalf("expected no search error, got: %v", err)
+		}
+
+		return res, newSearchResults(res, req)
+	}
+
+	t.Run("Newest first", func(t *testing.T) {
+		res, results := search(SearchOptions{Sort: SortNewest, Size: 2})
+		if results.Total != 5 || results.Page != 1 {
+			t.Errorf("expected total 5 on page 1, got: %+v", results)
+		}
+		if res.Hits[0].ID != "/shots/4.png" || res.Hits[1].ID != "/shots/3.png" {
+			t.Errorf("expected newest first, got: %v", res.Hits)
+		}
+	})
+
+	t.Run("Page 3", func(t *testing.T) {
+		res, results := search(SearchOptions{Sort: SortOldest, Size: 2, Page: 3})
+		if results.Page != 3 || len(res.Hits) != 1 || res.Hits[0].ID != "/shots/4.png" {
+			t.Errorf("expected last page with the newest doc, got: %+v %v", results, res.Hits)
+		}
+		if results.NextCursor != nil {
+			t.Errorf("expected no cursor on the last page, got: %v", results.NextCursor)
+		}
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		seen := make([]string, 0, 5)
+		opts := SearchOptions{Sort: SortSize, Size: 2}
+		for i := 0; i < 5; i++ {
+			res, results := search(opts)
+			for _, hit := range res.Hits {
+				seen = append(seen, hit.ID)
+			}
+			if results.NextCursor == nil {
+				break
+			}
+			opts.Cursor = results.NextCursor
+		}
+
+		expected := []string{"/shots/0.png", "/shots/1.png", "/shots/2.png", "/shots/3.png", "/shots/4.png"}
+		if fmt.Sprint(seen) != fmt.Sprint(expected) {
+			t.Errorf("expected largest first across pages %v, got: %v", expected, seen)
+		}
+	})
+}
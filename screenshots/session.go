@@ -0,0 +1,57 @@
+package screenshots
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStaleSearch is returned for a search that was superseded by a newer
+// query ID before it finished
+var ErrStaleSearch = errors.New("search superseded by a newer query")
+
+// SearchHit is a search:found event payload, tagged with the query ID it
+// belongs to so the UI can drop hits of queries it no longer shows
+type SearchHit struct {
+	ScreenshotDoc
+	QueryID int64 `json:"queryId"`
+}
+
+// beginSearch registers id as the latest query of the session, cancelling
+// the one in flight. It returns the context to run the search in and a func
+// to release it once done.
+func (s *ScreenshotService) beginSearch(id int64) (context.Context, context.CancelFunc, error) {
+	// ID 0 opts out of sessions, the search just runs to completion
+	if id == 0 {
+		ctx, cancel := context.WithCancel(s.ctx)
+		return ctx, cancel, nil
+	}
+
+	s.searchMu.Lock()
+	defer s.searchMu.Unlock()
+
+	if id < s.searchID {
+		return nil, nil, ErrStaleSearch
+	}
+
+	if s.cancelSearch != nil {
+		s.cancelSearch()
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.searchID = id
+	s.cancelSearch = cancel
+
+	return ctx, cancel, nil
+}
+
+// isCurrentSearch reports whether results for id should still be sent
+func (s *ScreenshotService) isCurrentSearch(id int64) bool {
+	if id == 0 {
+		return true
+	}
+
+	s.searchMu.Lock()
+	defer s.searchMu.Unlock()
+
+	return id == s.searchID
+}
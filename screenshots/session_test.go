@@ -0,0 +1,101 @@
+package screenshots
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBeginSearch(t *testing.T) {
+	t.Run("Newer query cancels older", func(t *testing.T) {
+		s := &ScreenshotService{ctx: context.Background()}
+
+		first, cancelFirst, err := s.beginSearch(1)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		defer cancelFirst()
+
+		second, cancelSecond, err := s.beginSearch(2)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		defer cancelSecond()
+
+		if first.Err() == nil {
+			t.Errorf("expected first search to be cancelled")
+		}
+		if second.Err() != nil {
+			t.Errorf("expected second search to still run, got: %v", second.Err())
+		}
+		if s.isCurrentSearch(1) || !s.isCurrentSearch(2) {
+			t.Errorf("expected only query 2 to be current")
+		}
+	})
+
+	t.Run("Older query is stale", func(t *testing.T) {
+		s := &ScreenshotService{ctx: context.Background()}
+
+		_, cancel, _ := s.beginSearch(5)
+		defer cancel()
+
+		_, _, err := s.beginSearch(4)
+		if err != ErrStaleSearch {
+			t.Errorf("expected ErrStaleSearch, got: %v", err)
+		}
+	})
+
+	t.Run("No session", func(t *testing.T) {
+		s := &ScreenshotService{ctx: context.Background()}
+
+		_, cancel, _ := s.beginSearch(3)
+		defer cancel()
+
+		ctx, cancelZero, err := s.beginSearch(0)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		defer cancelZero()
+
+		if ctx.Err() != nil || !s.isCurrentSearch(0) || !s.isCurrentSearch(3) {
+			t.Errorf("expected ID 0 to run alongside the session")
+		}
+	})
+}
+
+func TestPrefixLast(t *testing.T) {
+	idx := newTestIndex(t,
+		ScreenshotDoc{Path: "/shots/grafana.png", Text: "grafana dashboard"},
+		ScreenshotDoc{Path: "/shots/graph.png", Text: "graph of requests"},
+	)
+
+	tests := []struct {
+		input string
+		expected int
+	}{
+		{input: "gra", expected: 2},
+		{input: "dashboard gra", expected: 1},
+		// a trailing space means the word is finished
+		{input: "gra ", expected: 0},
+		{input: "tag:gra", expected: 0},
+	}
+
+	for _, tt := range tests {
+		p := &queryParser{prefixLast: true}
+		q, err := p.parse(tt.input)
+		if err != nil {
+			t.Fatalf("%q: expected no error, got: %v", tt.input, err)
+		}
+
+		req, err := newSearchRequest(q, SearchOptions{})
+		if err != nil {
+			t.Fatalf("%q: expected no error, got: %v", tt.input, err)
+		}
+		res, err := idx.Search(req)
+		if err != nil {
+			t.Fatalf("%q: expected no search error, got: %v", tt.input, err)
+		}
+		if len(res.Hits) != tt.expected {
+			t.Errorf("%q: expected %d hits, got: %v", tt.input, tt.expected, res.Hits)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package screenshots
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// maxHashDistance is the number of bits in a hash, any two images are
+	// within it
+	maxHashDistance = 64
+	// documentPageSize is how many documents are read per request when
+	// going through the whole index
+	documentPageSize = 1000
+)
+
+// SimilarImage is a screenshot that looks like the one searched with,
+// Distance is the number of hash bits they differ in (0-64)
+type SimilarImage struct {
+	ScreenshotDoc
+	Distance int `json:"distance"`
+}
+
+// similarImages keeps the perceptual hash of every indexed image in a
+// BK-tree next to the bleve index. It is loaded from the index on first use
+// and kept up to date as documents are indexed.
+type similarImages struct {
+	mu sync.Mutex
+	loaded bool
+	tree bkTree
+	// hashes holds the current hash of each ID, the tree may still hold
+	// the hash of an image from before it was edited
+	hashes map[string]uint64
+}
+
+// add records the hash of a newly indexed document, it's a no-op until the
+// hashes were loaded since loading picks it up from the index
+func (s *similarImages) add(id string, hash uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		return
+	}
+	s.hashes[id] = hash
+	s.tree.insert(hash, id)
+}
+
+// reset forgets every hash, they are loaded again on next use
+func (s *similarImages) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loaded = false
+	s.tree = bkTree{}
+	s.hashes = nil
+}
+
+// remove forgets the hash of a document deleted from the index
+func (s *similarImages) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.hashes, id)
+}
+
+// hashImage stores the perceptual hash of the encoded image on doc
+func (s *ScreenshotService) hashImage(doc *ScreenshotDoc, data []byte) {
+	if hash, ok := perceptualHash(data); ok {
+		doc.PHash = formatHash(hash)
+	}
+}
+
+// trackHash makes a newly indexed document findable by FindSimilar
+func (s *ScreenshotService) trackHash(doc *ScreenshotDoc) {
+	if doc.PHash == "" {
+		return
+	}
+
+	if hash, err := parseHash(doc.PHash); err == nil {
+		s.similar.add(doc.Path, hash)
+	}
+}
+
+// loadHashes reads the hash of every document from the index. Callers hold
+// similar.mu.
+func (s *ScreenshotService) loadHashes() error {
+	if s.similar.loaded {
+		return nil
+	}
+
+	tree := bkTree{}
+	hashes := make(map[string]uint64)
+
+	err := s.eachDocument([]string{"phash"}, func(hit *search.DocumentMatch) error {
+		value, ok := hit.Fields["phash"].(string)
+		if !ok {
+			return nil
+		}
+		hash, err := parseHash(value)
+		if err != nil {
+			return nil
+		}
+		hashes[hit.ID] = hash
+		tree.insert(hash, hit.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error loading hashes: %v", err)
+	}
+
+	s.similar.tree = tree
+	s.similar.hashes = hashes
+	s.similar.loaded = true
+
+	return nil
+}
+
+// eachDocument calls fn with the given stored fields of every document in
+// the index, paging through them in ID order. It stops at the first error fn
+// returns.
+func (s *ScreenshotService) eachDocument(fields []string, fn func(hit *search.DocumentMatch) error) error {
+	var after []string
+	for {
+		request := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), documentPageSize, 0, false)
+		request.Fields = fields
+		request.SortBy([]string{"_id"})
+		if after != nil {
+			request.SetSearchAfter(after)
+		}
+
+		result, err := s.Indexer.Search(s.ctx, request)
+		if err != nil {
+			return err
+		}
+
+		for _, hit := range result.Hits {
+			if err := fn(hit); err != nil {
+				return err
+			}
+		}
+
+		if len(result.Hits) < documentPageSize {
+			return nil
+		}
+		after = result.Hits[len(result.Hits)-1].Sort
+	}
+}
+
+// FindSimilar returns the screenshots whose perceptual hash is at most
+// maxDistance bits away from the one at path, closest first. A distance of
+// 0 finds copies, around 10 finds other captures of the same screen.
+func (s *ScreenshotService) FindSimilar(path string, maxDistance int) ([]SimilarImage, error) {
+	if maxDistance < 0 || maxDistance > maxHashDistance {
+		return nil, fmt.Errorf("max distance must be between 0 and %d", maxHashDistance)
+	}
+
+	err := s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	s.similar.mu.Lock()
+	if err := s.loadHashes(); err != nil {
+		s.similar.mu.Unlock()
+		return nil, err
+	}
+
+	hash, ok := s.similar.hashes[path]
+	if !ok {
+		s.similar.mu.Unlock()
+		return nil, fmt.Errorf("no image hash for %s", path)
+	}
+
+	distances := make(map[string]int)
+	for _, m := range s.similar.tree.search(hash, maxDistance) {
+		// skip the image itself and hashes that were since replaced
+		if current, ok := s.similar.hashes[m.id]; m.id == path || !ok || current != m.hash {
+			continue
+		}
+		distances[m.id] = m.distance
+	}
+	s.similar.mu.Unlock()
+
+	return s.similarDocs(distances)
+}
+
+// similarDocs fetches what is needed to display the matched images
+func (s *ScreenshotService) similarDocs(distances map[string]int) ([]SimilarImage, error) {
+	similar := make([]SimilarImage, 0, len(distances))
+	if len(distances) == 0 {
+		return similar, nil
+	}
+
+	ids := make([]string, 0, len(distances))
+	for id := range distances {
+		ids = append(ids, id)
+	}
+
+	request := bleve.NewSearchRequestOptions(query.NewDocIDQuery(ids), len(ids), 0, false)
+	request.Fields = []string{"url", "width", "height", "size", "parent"}
+
+	result, err := s.Indexer.Search(s.ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range result.Hits {
+		doc := ScreenshotDoc{Path: hit.ID}
+		if url, ok := hit.Fields["url"].(string); ok {
+			doc.URL = url
+		}
+		if parent, ok := hit.Fields["parent"].(string); ok {
+			doc.Parent = parent
+		}
+		if width, ok := hit.Fields["width"].(float64); ok {
+			doc.Width = int(width)
+		}
+		if height, ok := hit.Fields["height"].(float64); ok {
+			doc.Height = int(height)
+		}
+		if size, ok := hit.Fields["size"].(float64); ok {
+			doc.Size = int64(size)
+		}
+
+		similar = append(similar, SimilarImage{ScreenshotDoc: doc, Distance: distances[hit.ID]})
+	}
+
+	sort.Slice(similar, func(a, b int) bool {
+		if similar[a].Distance != similar[b].Distance {
+			return similar[a].Distance < similar[b].Distance
+		}
+		return similar[a].Path < similar[b].Path
+	})
+
+	return similar, nil
+}
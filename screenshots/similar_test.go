@@ -0,0 +1,82 @@
+package screenshots
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindSimilar(t *testing.T) {
+	docs := testDocs()
+	docs[0].PHash = formatHash(0xf0f0f0f0f0f0f0f0)
+	docs[0].URL = "Z3JhZmFuYQ=="
+	// the same dashboard a day later, two bits off
+	docs[1].PHash = formatHash(0xf0f0f0f0f0f0f0f3)
+	docs[1].Size = 2048
+	docs[2].PHash = formatHash(0x0f0f0f0f0f0f0f0f)
+
+	s := &ScreenshotService{
+		Indexer: &Indexer{idx: newTestIndex(t, docs...)},
+		ctx: context.Background(),
+	}
+
+	t.Run("Near copies", func(t *testing.T) {
+		similar, err := s.FindSimilar(docs[0].Path, 10)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(similar) != 1 || similar[0].Path != docs[1].Path || similar[0].Distance != 2 {
+			t.Fatalf("expected only %s at distance 2, got %+v", docs[1].Path, similar)
+		}
+		if similar[0].Size != 2048 || similar[0].Width != 1280 {
+			t.Errorf("expected display fields to be loaded, got %+v", similar[0].ScreenshotDoc)
+		}
+	})
+
+	t.Run("Closest first", func(t *testing.T) {
+		similar, err := s.FindSimilar(docs[1].Path, maxHashDistance)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(similar) != 2 || similar[0].Path != docs[0].Path || similar[1].Path != docs[2].Path {
+			t.Errorf("expected grafana then trace, got %+v", similar)
+		}
+	})
+
+	t.Run("Newly indexed image", func(t *testing.T) {
+		doc := ScreenshotDoc{Path: "/home/me/Desktop/copy.png", PHash: docs[2].PHash}
+		if err := s.Indexer.Index(doc.Path, &doc); err != nil {
+			t.Fatalf("error indexing: %v", err)
+		}
+		s.trackHash(&doc)
+
+		similar, err := s.FindSimilar(docs[2].Path, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(similar) != 1 || similar[0].Path != doc.Path {
+			t.Errorf("expected the copy, got %+v", similar)
+		}
+	})
+
+	t.Run("Edited image", func(t *testing.T) {
+		doc := ScreenshotDoc{Path: "/home/me/Desktop/copy.png", PHash: formatHash(0x1234)}
+		s.trackHash(&doc)
+
+		similar, _ := s.FindSimilar(docs[2].Path, 0)
+		if len(similar) != 0 {
+			t.Errorf("expected the old hash of the copy to be ignored, got %+v", similar)
+		}
+	})
+
+	t.Run("Unknown image", func(t *testing.T) {
+		if _, err := s.FindSimilar("/home/me/Desktop/missing.png", 10); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("Invalid distance", func(t *testing.T) {
+		if _, err := s.FindSimilar(docs[0].Path, 65); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package screenshots
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search"
+)
+
+// IndexStats describe what is in the index
+type IndexStats struct {
+	// Documents counts every page and frame, Files the files they came from
+	Documents uint64 `json:"documents"`
+	Files int `json:"files"`
+	IndexPath string `json:"indexPath"`
+	IndexBytes int64 `json:"indexBytes"`
+	// Facets count the documents by category, dir, ext, language, month
+	// and tag
+	Facets map[string][]FacetValue `json:"facets,omitempty"`
+}
+
+// Stats counts the indexed documents and files and measures the index on
+// disk
+func (s *ScreenshotService) Stats() (*IndexStats, error) {
+	err := s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	request := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), 0, 0, false)
+	addFacets(request)
+	result, err := s.Indexer.Search(s.ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.indexedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &IndexStats{
+		Documents: result.Total,
+		Files: len(files),
+		Facets: collectFacets(result.Facets),
+	}
+
+	stats.IndexPath, err = s.Indexer.GetIndexPath()
+	if err != nil {
+		return nil, err
+	}
+	// an index that can't be measured is still worth reporting on
+	filepath.WalkDir(stats.IndexPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			stats.IndexBytes += info.Size()
+		}
+		return nil
+	})
+
+	return stats, nil
+}
+
+// indexedFiles returns the sorted paths of the files in the index, pages
+// and frames count as the file they came from
+func (s *ScreenshotService) indexedFiles() ([]string, error) {
+	seen := make(map[string]struct{})
+	err := s.eachDocument([]string{"parent"}, func(hit *search.DocumentMatch) error {
+		path := hit.ID
+		if parent, ok := hit.Fields["parent"].(string); ok && parent != "" {
+			path = parent
+		}
+		seen[path] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(seen))
+	for path := range seen {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// Documents calls fn with every indexed document, rebuilt from its stored
+// fields, in order of ID. It stops at the first error fn returns.
+func (s *ScreenshotService) Documents(fn func(doc *ScreenshotDoc) error) error {
+	err := s.Indexer.Open()
+	if err != nil {
+		return fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	return s.eachDocument([]string{"*"}, func(hit *search.DocumentMatch) error {
+		doc, err := docFromFields(hit)
+		if err != nil {
+			return err
+		}
+		return fn(doc)
+	})
+}
+
+// Document returns the indexed document with the given ID, rebuilt from its
+// stored fields
+func (s *ScreenshotService) Document(id string) (*ScreenshotDoc, error) {
+	err := s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	return s.storedDoc(id)
+}
+
+// Reindex rebuilds the index from scratch with the current mapping and
+// extractors, running OCR again on every indexed file that still exists.
+// The user's tags and notes are kept, they live outside the index.
+func (s *ScreenshotService) Reindex() (*ScanSummary, error) {
+	// no scan may write to the index while it is reset
+	done, err := s.startScan()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	err = s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	files, err := s.indexedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Indexer.Reset(); err != nil {
+		return nil, fmt.Errorf("error resetting index: %v", err)
+	}
+	s.similar.reset()
+	s.logger().Info("index reset to reindex", "files", len(files))
+
+	paths := make([]string, 0, len(files))
+	for _, path := range files {
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+
+	return s.scanFiles(paths)
+}
+
+// ReindexIfOutdated rebuilds the index with Reindex when it was made with an
+// older mapping, like by an earlier version of the app. It returns a nil
+// summary when the index is current.
+func (s *ScreenshotService) ReindexIfOutdated() (*ScanSummary, error) {
+	err := s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+	if !s.Indexer.Outdated() {
+		return nil, nil
+	}
+
+	s.logger().Info("index mapping is outdated, reindexing", "version", mappingVersion)
+	return s.Reindex()
+}
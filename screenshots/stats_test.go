@@ -0,0 +1,61 @@
+package screenshots
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	docs := testDocs()
+	// a page of a PDF counts as its file
+	docs = append(docs, ScreenshotDoc{
+		Path: "/home/me/Desktop/report.pdf#page=2",
+		Parent: "/home/me/Desktop/report.pdf",
+		Page: 2,
+		Dir: "/home/me/Desktop",
+		Ext: "pdf",
+	}, ScreenshotDoc{
+		Path: "/home/me/Desktop/report.pdf#page=1",
+		Parent: "/home/me/Desktop/report.pdf",
+		Page: 1,
+		Dir: "/home/me/Desktop",
+		Ext: "pdf",
+	})
+
+	s := &ScreenshotService{
+		Indexer: &Indexer{
+			appName: "Glimpse",
+			blevePath: "screenshots.bleve",
+			o: &mockOsProvider{userConfigDir: t.TempDir()},
+			idx: newTestIndex(t, docs...),
+		},
+		ctx: context.Background(),
+	}
+
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Documents != 5 || stats.Files != 4 {
+		t.Errorf("expected 5 documents in 4 files, got %d in %d", stats.Documents, stats.Files)
+	}
+	if n := facetCount(stats.Facets[FacetExt], "png"); n != 2 {
+		t.Errorf("expected 2 png files, got %d", n)
+	}
+	// nothing has a language, it isn't counted as one
+	if languages := stats.Facets[FacetLanguage]; len(languages) != 0 {
+		t.Errorf("expected no languages, got %v", languages)
+	}
+
+	var paths []string
+	err = s.Documents(func(doc *ScreenshotDoc) error {
+		paths = append(paths, doc.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(paths) != 5 || paths[0] != "/home/me/Desktop/report.pdf#page=1" {
+		t.Errorf("expected every document in order of ID, got %v", paths)
+	}
+}
@@ -0,0 +1,186 @@
+package screenshots
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxSuggestions = 10
+	maxRecentQueries = 20
+)
+
+// Suggestion kinds
+const (
+	SuggestionRecent = "recent"
+	SuggestionTag = "tag"
+	SuggestionTerm = "term"
+)
+
+// Suggestion is a completion of what the user typed so far. Text is the full
+// query to search for, Count the number of documents the completed word or
+// tag appears in.
+type Suggestion struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"`
+	Count uint64 `json:"count,omitempty"`
+}
+
+// addRecentQuery remembers a query that was searched for, most recent first
+func (s *ScreenshotService) addRecentQuery(query string) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return
+	}
+
+	s.recentMu.Lock()
+	defer s.recentMu.Unlock()
+
+	recent := make([]string, 0, maxRecentQueries)
+	recent = append(recent, query)
+	for _, q := range s.recent {
+		if q != query && len(recent) < maxRecentQueries {
+			recent = append(recent, q)
+		}
+	}
+	s.recent = recent
+}
+
+// recentQueries returns the recent queries starting with prefix, ignoring case
+func (s *ScreenshotService) recentQueries(prefix string) []string {
+	s.recentMu.Lock()
+	defer s.recentMu.Unlock()
+
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	matches := make([]string, 0)
+	for _, q := range s.recent {
+		lower := strings.ToLower(q)
+		if lower != prefix && strings.HasPrefix(lower, prefix) {
+			matches = append(matches, q)
+		}
+	}
+
+	return matches
+}
+
+// Suggest completes the input with recently searched queries, then with tags
+// and OCR'd words starting with the word being typed, most common first
+func (s *ScreenshotService) Suggest(prefix string) ([]Suggestion, error) {
+	suggestions := make([]Suggestion, 0, maxSuggestions)
+	for _, q := range s.recentQueries(prefix) {
+		suggestions = append(suggestions, Suggestion{Text: q, Kind: SuggestionRecent})
+	}
+
+	// nothing to complete after a space
+	if prefix == "" || strings.TrimRightFunc(prefix, unicode.IsSpace) != prefix {
+		return capSuggestions(suggestions), nil
+	}
+
+	err := s.Indexer.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error opening indexer: %w", err)
+	}
+
+	start := strings.LastIndexFunc(prefix, unicode.IsSpace) + 1
+	head, word := prefix[:start], prefix[start:]
+
+	if strings.HasPrefix(word, "-") {
+		head += "-"
+		word = word[1:]
+	}
+
+	onlyTags := strings.HasPrefix(strings.ToLower(word), "tag:")
+	if onlyTags {
+		word = strings.TrimPrefix(word[len("tag:"):], `"`)
+	} else if strings.ContainsAny(word, `:<>"`) {
+		// other fields take paths, dates and numbers, not words
+		return capSuggestions(suggestions), nil
+	}
+
+	word = strings.ToLower(word)
+	if word == "" {
+		return capSuggestions(suggestions), nil
+	}
+
+	completions, err := s.completeWord(word, onlyTags)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(suggestions))
+	for _, sg := range suggestions {
+		seen[sg.Text] = struct{}{}
+	}
+
+	for _, c := range completions {
+		if c.Kind == SuggestionTag && (onlyTags || strings.ContainsRune(c.Text, ' ')) {
+			c.Text = `tag:"` + c.Text + `"`
+		}
+		c.Text = head + c.Text
+
+		if _, ok := seen[c.Text]; ok {
+			continue
+		}
+		seen[c.Text] = struct{}{}
+		suggestions = append(suggestions, c)
+	}
+
+	return capSuggestions(suggestions), nil
+}
+
+// completeWord looks up the tags, and unless onlyTags the words, starting
+// with word, ranked by the number of documents they appear in
+func (s *ScreenshotService) completeWord(word string, onlyTags bool) ([]Suggestion, error) {
+	tags, err := s.Indexer.Terms("tag", word)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tags: %v", err)
+	}
+	userTags, err := s.Indexer.Terms("user_tag", word)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tags: %v", err)
+	}
+	tags = append(tags, userTags...)
+
+	completions := make([]Suggestion, 0, len(tags))
+	for _, t := range tags {
+		if t.Term != word {
+			completions = append(completions, Suggestion{Text: t.Term, Kind: SuggestionTag, Count: t.Count})
+		}
+	}
+
+	if !onlyTags {
+		terms, err := s.Indexer.Terms("text", word)
+		if err != nil {
+			return nil, fmt.Errorf("error reading terms: %v", err)
+		}
+		for _, t := range terms {
+			if t.Term != word {
+				completions = append(completions, Suggestion{Text: t.Term, Kind: SuggestionTerm, Count: t.Count})
+			}
+		}
+	}
+
+	// a single word tag is often an OCR'd word too, Suggest keeps whichever
+	// comes first
+	sort.SliceStable(completions, func(a, b int) bool {
+		if completions[a].Count != completions[b].Count {
+			return completions[a].Count > completions[b].Count
+		}
+		if completions[a].Text != completions[b].Text {
+			return completions[a].Text < completions[b].Text
+		}
+		return completions[a].Kind == SuggestionTag && completions[b].Kind != SuggestionTag
+	})
+
+	return completions, nil
+}
+
+func capSuggestions(suggestions []Suggestion) []Suggestion {
+	if len(suggestions) > maxSuggestions {
+		return suggestions[:maxSuggestions]
+	}
+
+	return suggestions
+}
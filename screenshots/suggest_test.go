@@ -0,0 +1,122 @@
+package screenshots
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func newSuggestService(t testing.TB, docs ...ScreenshotDoc) *ScreenshotService {
+	idx := newTestIndex(t, docs...)
+
+	return &ScreenshotService{Indexer: &Indexer{idx: idx}, ctx: context.Background()}
+}
+
+func suggestionTexts(suggestions []Suggestion) []string {
+	texts := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		texts = append(texts, s.Text)
+	}
+	return texts
+}
+
+func TestSuggest(t *testing.T) {
+	docs := testDocs()
+	docs[0].Text = "grafana error rate graphs"
+	docs[1].Text = "error budget graphs"
+	docs[2].Text = "panic in grafana handler"
+
+	s := newSuggestService(t, docs...)
+
+	tests := []struct {
+		name string
+		prefix string
+		expected []string
+	}{
+		{"words and tags by document count", "gra", []string{"grafana", "graphs", `tag:"grafana dashboard"`}},
+		{"last word only", "panic gra", []string{"panic grafana", "panic graphs", `panic tag:"grafana dashboard"`}},
+		{"tags only", "tag:err", []string{`tag:"error budget"`, `tag:"error rate"`}},
+		{"negated", "-bud", []string{"-budget"}},
+		{"other fields", "ext:pn", []string{}},
+		{"after a space", "grafana ", []string{}},
+		{"no match", "zzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions, err := s.Suggest(tt.prefix)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := suggestionTexts(suggestions); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("counts", func(t *testing.T) {
+		suggestions, _ := s.Suggest("gra")
+		if suggestions[0].Kind != SuggestionTerm || suggestions[0].Count != 2 {
+			t.Errorf("expected grafana in 2 documents, got %+v", suggestions[0])
+		}
+	})
+}
+
+func TestSuggestRecentQueries(t *testing.T) {
+	docs := testDocs()
+	docs[0].Text = "grafana error rate"
+
+	s := newSuggestService(t, docs...)
+	s.addRecentQuery("Grafana after:2025-03-01")
+	s.addRecentQuery("stack trace")
+	s.addRecentQuery("grafana")
+
+	suggestions, err := s.Suggest("gra")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"grafana", "Grafana after:2025-03-01", `tag:"grafana dashboard"`}
+	if got := suggestionTexts(suggestions); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if suggestions[0].Kind != SuggestionRecent {
+		t.Errorf("expected recent query first, got %+v", suggestions[0])
+	}
+
+	// searching again moves the query to the front without duplicating it
+	s.addRecentQuery("stack trace")
+	if s.recent[0] != "stack trace" || len(s.recent) != 3 {
+		t.Errorf("expected stack trace first of 3, got %q", s.recent)
+	}
+
+	for i := 0; i < maxRecentQueries+5; i++ {
+		s.addRecentQuery(fmt.Sprintf("query %d", i))
+	}
+	if len(s.recent) != maxRecentQueries {
+		t.Errorf("expected %d recent queries, got %d", maxRecentQueries, len(s.recent))
+	}
+}
+
+func BenchmarkSuggest(b *testing.B) {
+	words := []string{"grafana", "graph", "gradle", "grep", "error", "budget", "trace", "panic", "handler", "invoice"}
+
+	docs := make([]ScreenshotDoc, 10000)
+	for i := range docs {
+		docs[i] = ScreenshotDoc{
+			Path: fmt.Sprintf("/home/me/Desktop/%d.png", i),
+			Text: fmt.Sprintf("%s %s word%d", words[i%len(words)], words[(i/7)%len(words)], i),
+			Tags: []string{fmt.Sprintf("%s %d", words[i%len(words)], i%500)},
+		}
+	}
+
+	s := newSuggestService(b, docs...)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Suggest("gr"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
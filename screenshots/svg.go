@@ -0,0 +1,109 @@
+package screenshots
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// svgTextElements are the SVG elements whose character data is rendered text
+var svgTextElements = map[string]struct{}{
+	"text": {},
+	"tspan": {},
+	"textPath": {},
+}
+
+// parseSVGText returns the character data of every <text> element in the
+// document, one element per line
+func parseSVGText(r io.Reader) (string, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	lines := make([]string, 0)
+	var current strings.Builder
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if _, ok := svgTextElements[t.Name.Local]; ok {
+				depth++
+			}
+		case xml.EndElement:
+			if _, ok := svgTextElements[t.Name.Local]; !ok || depth == 0 {
+				continue
+			}
+			depth--
+
+			// flush once the outermost <text> closes so nested tspans
+			// end up on the same line
+			if depth == 0 {
+				if line := strings.Join(strings.Fields(current.String()), " "); line != "" {
+					lines = append(lines, line)
+				}
+				current.Reset()
+			}
+		case xml.CharData:
+			if depth > 0 {
+				current.WriteString(" ")
+				current.Write(t)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (o *OCR) extractSVGText(path string) (string, error) {
+	file, err := o.fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	text, err := parseSVGText(file)
+	if err != nil {
+		return "", fmt.Errorf("error parsing svg: %v", err)
+	}
+
+	if !o.RasterizeSVG {
+		return text, nil
+	}
+
+	rasterText, err := o.rasterizeAndOCR(path)
+	if err != nil {
+		// the embedded text is still worth indexing on its own
+		if text != "" {
+			return text, nil
+		}
+		return "", err
+	}
+
+	return mergeText([]string{text, rasterText}), nil
+}
+
+// rasterizeAndOCR renders the SVG to a temporary PNG with rsvg-convert and
+// runs it through the OCR helper, catching text drawn as paths
+func (o *OCR) rasterizeAndOCR(path string) (string, error) {
+	tmpFile, err := o.fs.CreateTemp("", "glimpse-svg-*.png")
+	if err != nil {
+		return "", err
+	}
+	tmpFile.Close()
+	defer o.fs.Remove(tmpFile.Name())
+
+	if _, err := o.cmdRunner.Command("rsvg-convert", "-o", tmpFile.Name(), path); err != nil {
+		return "", fmt.Errorf("error rasterizing svg: %v", err)
+	}
+
+	return o.runOCR(tmpFile.Name())
+}
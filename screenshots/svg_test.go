@@ -0,0 +1,108 @@
+package screenshots
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="200" height="100">
+	<rect width="200" height="100" fill="white"/>
+	<text x="10" y="20">Request <tspan font-weight="bold">latency</tspan></text>
+	<g><text x="10" y="40">p99   420ms</text></g>
+	<title>not rendered</title>
+</svg>`
+
+func TestParseSVGText(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		text, err := parseSVGText(strings.NewReader(testSVG))
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if text != "Request latency\np99 420ms" {
+			t.Errorf("expected 'Request latency\\np99 420ms', got: %q", text)
+		}
+	})
+
+	t.Run("No text", func(t *testing.T) {
+		text, err := parseSVGText(strings.NewReader(`<svg><rect/></svg>`))
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if text != "" {
+			t.Errorf("expected empty text, got: %q", text)
+		}
+	})
+}
+
+func TestExtractSVGText(t *testing.T) {
+	t.Run("Text only", func(t *testing.T) {
+		fs := &mockFileSystem{
+			files: map[string][]byte{"/shots/chart.svg": []byte(testSVG)},
+		}
+		cmdRunner := &mockCmdRunner{cmdError: errors.New("should not be called")}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+
+		text, err := ocr.ExtractText("/shots/chart.svg")
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if text != "Request latency\np99 420ms" {
+			t.Errorf("expected svg text, got: %q", text)
+		}
+	})
+
+	t.Run("Rasterized", func(t *testing.T) {
+		fs := &mockFileSystem{
+			files: map[string][]byte{"/shots/chart.svg": []byte(testSVG)},
+			tempFile: &mockFile{name: "/tmp/glimpse-svg.png"},
+		}
+		cmdRunner := &mockCmdRunner{
+			commandFn: func(name string, arg ...string) ([]byte, error) {
+				if name == "rsvg-convert" {
+					return nil, nil
+				}
+				return []byte("Request latency\nlegend"), nil
+			},
+		}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+		ocr.RasterizeSVG = true
+
+		text, err := ocr.ExtractText("/shots/chart.svg")
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if text != "Request latency\np99 420ms\nlegend" {
+			t.Errorf("expected merged text, got: %q", text)
+		}
+	})
+
+	t.Run("Rasterize error falls back to text", func(t *testing.T) {
+		fs := &mockFileSystem{
+			files: map[string][]byte{"/shots/chart.svg": []byte(testSVG)},
+			tempFile: &mockFile{name: "/tmp/glimpse-svg.png"},
+		}
+		cmdRunner := &mockCmdRunner{cmdError: errors.New("rsvg-convert not found")}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+		ocr.RasterizeSVG = true
+
+		text, err := ocr.ExtractText("/shots/chart.svg")
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+		}
+		if text != "Request latency\np99 420ms" {
+			t.Errorf("expected svg text, got: %q", text)
+		}
+	})
+
+	t.Run("Open error", func(t *testing.T) {
+		fs := &mockFileSystem{openError: errors.New("open error")}
+		ocr := NewMockOCR(nil, "", fs, nil)
+
+		_, err := ocr.ExtractText("/shots/chart.svg")
+		if err == nil || err.Error() != "open error" {
+			t.Errorf("expected 'open error', got: %v", err)
+		}
+	})
+}
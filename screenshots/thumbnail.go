@@ -0,0 +1,115 @@
+package screenshots
+
+import (
+	"bytes"
+	b64 "encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultThumbnailSize = 256
+	maxThumbnailSize = 1024
+	thumbnailQuality = 80
+)
+
+// ErrNoThumbnail is returned for images that can't be decoded to be shrunk,
+// like SVGs, the full image has to do
+var ErrNoThumbnail = errors.New("no thumbnail for this image")
+
+// Image returns the indexed image of a document and its content type: the
+// screenshot itself, or the rendered page or frame of a PDF or recording
+func (s *ScreenshotService) Image(id string) ([]byte, string, error) {
+	doc, err := s.Document(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := b64.StdEncoding.DecodeString(doc.URL)
+	if err != nil {
+		return nil, "", fmt.Errorf("error decoding image of %s: %v", id, err)
+	}
+
+	if doc.Parent == "" && strings.EqualFold(filepath.Ext(doc.Path), ".svg") {
+		return data, "image/svg+xml", nil
+	}
+
+	return data, http.DetectContentType(data), nil
+}
+
+// Thumbnail returns the image of a document shrunk to fit in a size x size
+// square, as a JPEG. Images smaller than that are only re-encoded.
+func (s *ScreenshotService) Thumbnail(id string, size int) ([]byte, string, error) {
+	if size <= 0 {
+		size = DefaultThumbnailSize
+	}
+	size = min(size, maxThumbnailSize)
+
+	data, _, err := s.Image(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrNoThumbnail
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, shrinkImage(img, size), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, "", err
+	}
+
+	return out.Bytes(), "image/jpeg", nil
+}
+
+// shrinkImage scales img down to fit in a size x size square, keeping its
+// aspect ratio, by averaging the block of pixels behind each output pixel
+// like shrinkGray. Transparent pixels are put on white.
+func shrinkImage(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(height*size/bounds.Dx(), 1)
+		} else {
+			width, height = max(width*size/bounds.Dy(), 1), size
+		}
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return out
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+
+			var r, g, b uint64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					pr, pg, pb, pa := img.At(px, py).RGBA()
+					// premultiplied, so adding the missing alpha as white
+					r += uint64(pr + 0xffff - pa)
+					g += uint64(pg + 0xffff - pa)
+					b += uint64(pb + 0xffff - pa)
+				}
+			}
+			n := uint64((y1 - y0) * (x1 - x0))
+			out.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: 0xffff})
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,81 @@
+package screenshots
+
+import (
+	"bytes"
+	"context"
+	b64 "encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestShrinkImage(t *testing.T) {
+	tests := []struct {
+		name string
+		width int
+		height int
+		expected image.Point
+	}{
+		{"Landscape", 400, 100, image.Pt(64, 16)},
+		{"Portrait", 100, 400, image.Pt(16, 64)},
+		{"Small", 20, 10, image.Pt(20, 10)},
+		{"Thin", 1000, 2, image.Pt(64, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			if got := shrinkImage(img, 64).Bounds().Size(); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// transparent pixels end up white
+	transparent := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	r, g, b, _ := shrinkImage(transparent, 2).At(0, 0).RGBA()
+	if r != 0xffff || g != 0xffff || b != 0xffff {
+		t.Errorf("expected white, got %d %d %d", r, g, b)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	png := newTestPNG(t, 600, 300)
+	docs := []ScreenshotDoc{
+		{Path: "/home/me/Desktop/wide.png", URL: b64.StdEncoding.EncodeToString(png)},
+		{Path: "/home/me/Desktop/logo.svg", URL: b64.StdEncoding.EncodeToString([]byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`))},
+	}
+	s := &ScreenshotService{Indexer: &Indexer{idx: newTestIndex(t, docs...)}, ctx: context.Background()}
+
+	data, contentType, err := s.Image(docs[0].Path)
+	if err != nil || contentType != "image/png" || !bytes.Equal(data, png) {
+		t.Errorf("expected the png, got %q, %v", contentType, err)
+	}
+	if _, contentType, _ := s.Image(docs[1].Path); contentType != "image/svg+xml" {
+		t.Errorf("expected an svg, got %q", contentType)
+	}
+
+	data, contentType, err = s.Thumbnail(docs[0].Path, 0)
+	if err != nil || contentType != "image/jpeg" {
+		t.Fatalf("expected a jpeg, got %q, %v", contentType, err)
+	}
+	thumbnail, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size := thumbnail.Bounds().Size(); size != image.Pt(DefaultThumbnailSize, DefaultThumbnailSize/2) {
+		t.Errorf("expected the default size, got %v", size)
+	}
+	if _, ok := thumbnail.At(0, 0).(color.YCbCr); !ok {
+		t.Errorf("expected a colour jpeg, got %T", thumbnail.At(0, 0))
+	}
+
+	if _, _, err := s.Thumbnail(docs[1].Path, 64); !errors.Is(err, ErrNoThumbnail) {
+		t.Errorf("expected ErrNoThumbnail, got %v", err)
+	}
+	if _, _, err := s.Thumbnail("/home/me/Desktop/missing.png", 64); !errors.Is(err, ErrNotIndexed) {
+		t.Errorf("expected ErrNotIndexed, got %v", err)
+	}
+}
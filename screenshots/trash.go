@@ -0,0 +1,96 @@
+package screenshots
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+var errTrashCollision = errors.New("a file already exists at the original location")
+
+// uniqueTrashName returns name, or name with a number added before the
+// extension, so it doesn't replace anything already in dir
+func uniqueTrashName(dir string, name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := name
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(filepath.Join(dir, candidate)); errors.Is(err, os.ErrNotExist) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s %d%s", base, n, ext)
+	}
+}
+
+// moveBack puts a trashed file back where it came from, refusing to replace
+// a file that was created there since
+func moveBack(trashPath string, originalPath string) error {
+	if _, err := os.Lstat(originalPath); err == nil {
+		return errTrashCollision
+	}
+
+	if err := os.MkdirAll(filepath.Dir(originalPath), 0755); err != nil {
+		return err
+	}
+
+	return moveFile(trashPath, originalPath)
+}
+
+// moveFile renames src to dst. The trash is on the home volume, so files
+// from other volumes are copied over and then removed instead, keeping
+// their mode and modification time.
+func moveFile(src string, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	// the copy is complete, only then the original goes
+	if err := os.Remove(src); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return nil
+}
+
+func copyFile(src string, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,34 @@
+//go:build darwin
+
+package screenshots
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// moveToTrash moves path to the user's Trash. Finder lists it there, but
+// only files it trashed itself can be put back from Finder, these are
+// restored with UndoDuplicates.
+func moveToTrash(path string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(home, ".Trash")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	trashPath := filepath.Join(dir, uniqueTrashName(dir, filepath.Base(path)))
+	if err := moveFile(path, trashPath); err != nil {
+		return "", err
+	}
+
+	return trashPath, nil
+}
+
+func restoreFromTrash(trashPath string, originalPath string) error {
+	return moveBack(trashPath, originalPath)
+}
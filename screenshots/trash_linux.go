@@ -0,0 +1,79 @@
+//go:build linux
+
+package screenshots
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// trashDir follows the freedesktop.org trash spec, which file managers
+// restore from
+func trashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+// moveToTrash moves path to the home trash. The .trashinfo file is written
+// first, as the spec asks, so the trash never holds a file without its
+// original location.
+func moveToTrash(path string) (string, error) {
+	dir, err := trashDir()
+	if err != nil {
+		return "", err
+	}
+
+	filesDir := filepath.Join(dir, "files")
+	infoDir := filepath.Join(dir, "info")
+	for _, d := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return "", err
+		}
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	name := uniqueTrashName(filesDir, filepath.Base(path))
+	infoPath := filepath.Join(infoDir, name+".trashinfo")
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: absPath}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	if err := os.WriteFile(infoPath, []byte(info), 0600); err != nil {
+		return "", err
+	}
+
+	trashPath := filepath.Join(filesDir, name)
+	if err := moveFile(path, trashPath); err != nil {
+		os.Remove(infoPath)
+		return "", err
+	}
+
+	return trashPath, nil
+}
+
+func restoreFromTrash(trashPath string, originalPath string) error {
+	if err := moveBack(trashPath, originalPath); err != nil {
+		return err
+	}
+
+	filesDir := filepath.Dir(trashPath)
+	if strings.HasSuffix(filesDir, "files") {
+		os.Remove(filepath.Join(filepath.Dir(filesDir), "info", filepath.Base(trashPath)+".trashinfo"))
+	}
+
+	return nil
+}
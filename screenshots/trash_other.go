@@ -0,0 +1,33 @@
+//go:build !darwin && !linux
+
+package screenshots
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// moveToTrash moves path to a Trash folder next to the index, since there is
+// no portable way to reach the Recycle Bin
+func moveToTrash(path string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(configDir, "Glimpse", "Trash")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	trashPath := filepath.Join(dir, uniqueTrashName(dir, filepath.Base(path)))
+	if err := moveFile(path, trashPath); err != nil {
+		return "", err
+	}
+
+	return trashPath, nil
+}
+
+func restoreFromTrash(trashPath string, originalPath string) error {
+	return moveBack(trashPath, originalPath)
+}
@@ -0,0 +1,115 @@
+package screenshots
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ffmpegBinary = "ffmpeg"
+
+	defaultSceneThreshold = 0.3
+	defaultMaxVideoFrames = 30
+)
+
+var supportedVideoExts = map[string]struct{}{
+	".mp4": {},
+	".mov": {},
+}
+
+// parseSceneTimestamps reads the pts_time of every frame printed by ffmpeg's
+// metadata filter
+func parseSceneTimestamps(out []byte) []float64 {
+	timestamps := make([]float64, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			value, ok := strings.CutPrefix(field, "pts_time:")
+			if !ok {
+				continue
+			}
+
+			ts, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				timestamps = append(timestamps, ts)
+			}
+		}
+	}
+
+	return timestamps
+}
+
+// sceneTimestamps returns the start of the recording followed by every scene
+// change, capped at max frames
+func (o *OCR) sceneTimestamps(path string) ([]float64, error) {
+	filter := fmt.Sprintf("select='gt(scene,%.2f)',metadata=print:file=-", o.SceneThreshold)
+
+	out, err := o.cmdRunner.Command(ffmpegBinary, "-hide_banner", "-nostats", "-v", "error", "-i", path, "-vf", filter, "-an", "-f", "null", "-")
+	if err != nil {
+		return nil, fmt.Errorf("error detecting scenes: %v", err)
+	}
+
+	timestamps := append([]float64{0}, parseSceneTimestamps(out)...)
+	if o.MaxVideoFrames > 0 && len(timestamps) > o.MaxVideoFrames {
+		sampled := make([]float64, 0, o.MaxVideoFrames)
+		for _, idx := range keyFrameIndices(len(timestamps), o.MaxVideoFrames) {
+			sampled = append(sampled, timestamps[idx])
+		}
+		timestamps = sampled
+	}
+
+	return timestamps, nil
+}
+
+func (o *OCR) extractVideoFrames(path string) ([]Page, error) {
+	timestamps, err := o.sceneTimestamps(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]Page, 0, len(timestamps))
+	for i, ts := range timestamps {
+		page, err := o.extractVideoFrame(path, ts)
+		if err != nil {
+			return nil, fmt.Errorf("error extracting frame at %.2fs: %v", ts, err)
+		}
+		page.Number = i + 1
+		pages = append(pages, page)
+	}
+
+	return pages, nil
+}
+
+func (o *OCR) extractVideoFrame(path string, ts float64) (Page, error) {
+	page := Page{Timestamp: ts}
+
+	tmpFile, err := o.fs.CreateTemp("", "glimpse-frame-*.png")
+	if err != nil {
+		return page, err
+	}
+	tmpFile.Close()
+
+	seek := strconv.FormatFloat(ts, 'f', 3, 64)
+	if _, err := o.cmdRunner.Command(ffmpegBinary, "-v", "error", "-ss", seek, "-i", path, "-frames:v", "1", "-y", tmpFile.Name()); err != nil {
+		o.fs.Remove(tmpFile.Name())
+		return page, err
+	}
+
+	text, err := o.runOCR(tmpFile.Name())
+	if err != nil {
+		o.fs.Remove(tmpFile.Name())
+		return page, err
+	}
+	page.Text = text
+
+	page.Image, err = o.readTempImage(tmpFile.Name())
+	if err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
@@ -0,0 +1,93 @@
+package screenshots
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testSceneOutput = `frame:0    pts:3003    pts_time:3.003
+lavfi.scene_score=0.452100
+frame:1    pts:10510   pts_time:10.51
+lavfi.scene_score=0.610000
+`
+
+func TestParseSceneTimestamps(t *testing.T) {
+	timestamps := parseSceneTimestamps([]byte(testSceneOutput))
+
+	expected := []float64{3.003, 10.51}
+	if !reflect.DeepEqual(timestamps, expected) {
+		t.Errorf("expected %v, got %v", expected, timestamps)
+	}
+}
+
+func TestExtractVideoFrames(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		fs := &mockFileSystem{
+			tempFile: &mockFile{name: "/tmp/glimpse-frame.png"},
+			files: map[string][]byte{"/tmp/glimpse-frame.png": []byte("png")},
+		}
+		seeks := make([]string, 0)
+		cmdRunner := &mockCmdRunner{
+			commandFn: func(name string, arg ...string) ([]byte, error) {
+				if name != ffmpegBinary {
+					return []byte("frame text"), nil
+				}
+				if arg[0] == "-hide_banner" {
+					return []byte(testSceneOutput), nil
+				}
+				seeks = append(seeks, arg[3])
+				return nil, nil
+			},
+		}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+		ocr.SceneThreshold = defaultSceneThreshold
+		ocr.MaxVideoFrames = defaultMaxVideoFrames
+
+		pages, err := ocr.ExtractPages("/videos/demo.mov")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(pages) != 3 {
+			t.Fatalf("expected 3 frames, got: %d", len(pages))
+		}
+		if !reflect.DeepEqual(seeks, []string{"0.000", "3.003", "10.510"}) {
+			t.Errorf("expected frames at scene changes, got: %v", seeks)
+		}
+		if pages[2].Number != 3 || pages[2].Timestamp != 10.51 || pages[2].Text != "frame text" {
+			t.Errorf("unexpected last frame: %+v", pages[2])
+		}
+	})
+
+	t.Run("Max frames", func(t *testing.T) {
+		fs := &mockFileSystem{tempFile: &mockFile{name: "/tmp/glimpse-frame.png"}}
+		cmdRunner := &mockCmdRunner{
+			commandFn: func(name string, arg ...string) ([]byte, error) {
+				if name == ffmpegBinary && arg[0] == "-hide_banner" {
+					return []byte(testSceneOutput), nil
+				}
+				return nil, nil
+			},
+		}
+		ocr := NewMockOCR(nil, "/path/to/binary", fs, cmdRunner)
+		ocr.MaxVideoFrames = 2
+
+		pages, err := ocr.ExtractPages("/videos/demo.mp4")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(pages) != 2 || pages[0].Timestamp != 0 || pages[1].Timestamp != 10.51 {
+			t.Errorf("expected first and last frame, got: %+v", pages)
+		}
+	})
+
+	t.Run("Ffmpeg error", func(t *testing.T) {
+		cmdRunner := &mockCmdRunner{cmdError: errors.New("ffmpeg not found")}
+		ocr := NewMockOCR(nil, "", nil, cmdRunner)
+
+		_, err := ocr.ExtractPages("/videos/demo.mp4")
+		if err == nil || err.Error() != "error detecting scenes: ffmpeg not found" {
+			t.Errorf("expected 'error detecting scenes: ffmpeg not found', got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"sync"
+
+	"glimpse/screenshots"
+)
+
+// subscriberBuffer is how many events a slow client can fall behind before
+// it misses some
+const subscriberBuffer = 64
+
+// streamedEvents are the events of the service sent to /api/events, the
+// search events belong to whoever searched
+var streamedEvents = map[string]struct{}{
+	"result:found": {},
+	"scan:progress": {},
+	"scan:error": {},
+	"scan:done": {},
+	"scan:failed": {},
+	"collection:count": {},
+}
+
+// indexedDocument is sent for result:found instead of the whole document
+// and its image
+type indexedDocument struct {
+	Path string `json:"path"`
+	Parent string `json:"parent,omitempty"`
+	Category string `json:"category,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Broadcaster is an EventSink that fans the scan events of the service out
+// to every client of /api/events. A client that can't keep up misses
+// events rather than slowing the scan down.
+type Broadcaster struct {
+	mu sync.Mutex
+	subscribers map[chan screenshots.Event]struct{}
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subscribers: make(map[chan screenshots.Event]struct{})}
+}
+
+func (b *Broadcaster) Emit(name string, data interface{}) {
+	if _, ok := streamedEvents[name]; !ok {
+		return
+	}
+	if doc, ok := data.(screenshots.ScreenshotDoc); ok {
+		data = indexedDocument{Path: doc.Path, Parent: doc.Parent, Category: doc.Category, Tags: doc.Tags}
+	}
+	event := screenshots.Event{Name: name, Data: data}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of the events sent from now on and a func to
+// stop receiving them
+func (b *Broadcaster) Subscribe() (<-chan screenshots.Event, func()) {
+	events := make(chan screenshots.Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[events] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, events)
+			b.mu.Unlock()
+			close(events)
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"glimpse/screenshots"
+)
+
+// sortOrders are the values of the sort parameter
+var sortOrders = map[string]struct{}{
+	screenshots.SortRelevance: {},
+	screenshots.SortNewest: {},
+	screenshots.SortOldest: {},
+	screenshots.SortPath: {},
+	screenshots.SortSize: {},
+}
+
+// document is a ScreenshotDoc as the API returns it: the base64 image is
+// replaced by links to the image endpoints, word boxes and the embedding
+// are left out
+type document struct {
+	screenshots.ScreenshotDoc
+	URL string `json:"url"`
+	Thumbnail string `json:"thumbnail"`
+	WordBoxes string `json:"word_boxes,omitempty"`
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+func newDocument(doc screenshots.ScreenshotDoc) document {
+	id := url.QueryEscape(doc.Path)
+	return document{
+		ScreenshotDoc: doc,
+		URL: "/api/image?id=" + id,
+		Thumbnail: "/api/thumbnail?id=" + id,
+	}
+}
+
+// searchResponse is the page of results and its hits
+type searchResponse struct {
+	*screenshots.SearchResults
+	Hits []document `json:"hits"`
+}
+
+// errorResponse is the body of every error, Query is set when the query
+// couldn't be parsed
+type errorResponse struct {
+	Error string `json:"error"`
+	Query *screenshots.QueryError `json:"query,omitempty"`
+	// Summary says why when every file of a scan failed
+	Summary *screenshots.ScanSummary `json:"summary,omitempty"`
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// search takes q and the optional page, size, sort, fuzzy, mode, cursor and
+// filter parameters. cursor and filter repeat, a filter is facet:value.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	q := params.Get("q")
+	if strings.TrimSpace(q) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing q"))
+		return
+	}
+
+	opts := screenshots.SearchOptions{
+		Sort: params.Get("sort"),
+		Mode: params.Get("mode"),
+		Cursor: params["cursor"],
+	}
+	if _, ok := sortOrders[opts.Sort]; opts.Sort != "" && !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown sort order %q", opts.Sort))
+		return
+	}
+
+	var err error
+	for name, value := range map[string]*int{"page": &opts.Page, "size": &opts.Size, "fuzzy": &opts.Fuzziness} {
+		if *value, err = intParam(params, name); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	for _, filter := range params["filter"] {
+		facet, value, ok := strings.Cut(filter, ":")
+		if !ok || facet == "" || value == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid filter %q, expected facet:value", filter))
+			return
+		}
+		if opts.Filters == nil {
+			opts.Filters = make(map[string][]string)
+		}
+		opts.Filters[facet] = append(opts.Filters[facet], value)
+	}
+
+	results, hits, err := s.service.SearchHits(q, opts)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	response := searchResponse{SearchResults: results, Hits: make([]document, 0, len(hits))}
+	for _, hit := range hits {
+		response.Hits = append(response.Hits, newDocument(hit.ScreenshotDoc))
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) suggest(w http.ResponseWriter, r *http.Request) {
+	suggestions, err := s.service.Suggest(r.URL.Query().Get("prefix"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, suggestions)
+}
+
+// document returns the metadata and text of the document with the given id
+func (s *Server) document(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+
+	doc, err := s.service.Document(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newDocument(*doc))
+}
+
+func (s *Server) image(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+
+	data, contentType, err := s.service.Image(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeImage(w, data, contentType)
+}
+
+// thumbnail takes an optional size in pixels, images that can't be shrunk
+// like SVGs are returned whole
+func (s *Server) thumbnail(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	size, err := intParam(r.URL.Query(), "size")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	data, contentType, err := s.service.Thumbnail(id, size)
+	if errors.Is(err, screenshots.ErrNoThumbnail) {
+		data, contentType, err = s.service.Image(id)
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeImage(w, data, contentType)
+}
+
+// scan indexes ~/Desktop like the app's scan button and returns the
+// summary, progress and the files that fail are streamed from /api/events
+// meanwhile. A scan already running, from the app or another request, is
+// a conflict.
+func (s *Server) scan(w http.ResponseWriter, r *http.Request) {
+	summary, err := s.service.ScanAndIndex()
+	if errors.Is(err, screenshots.ErrScanInProgress) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		s.events.Emit("scan:failed", errorResponse{Error: err.Error()})
+	}
+	// the summary says why when every file failed
+	if errors.Is(err, screenshots.ErrScanFailed) && summary != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error(), Summary: summary})
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// stream sends the scan events as Server-Sent Events until the client goes
+// away
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	// a comment so clients know the stream is open
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, data)
+			flusher.Flush()
+		}
+	}
+}
+
+func idParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing id"))
+		return "", false
+	}
+
+	return id, true
+}
+
+// intParam returns the integer parameter name, 0 when it isn't set
+func intParam(params url.Values, name string) (int, error) {
+	value := params.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeImage(w http.ResponseWriter, data []byte, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	// SVGs are served as images, never run as documents
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// writeServiceError answers with the status that fits an error of the
+// screenshot service
+func writeServiceError(w http.ResponseWriter, err error) {
+	var qerr *screenshots.QueryError
+	switch {
+	case errors.As(err, &qerr):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error(), Query: qerr})
+	case errors.Is(err, screenshots.ErrNotIndexed):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, screenshots.ErrSemanticUnavailable):
+		writeError(w, http.StatusNotImplemented, err)
+	case errors.Is(err, screenshots.ErrIndexLocked):
+		writeError(w, http.StatusServiceUnavailable, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
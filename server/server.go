@@ -0,0 +1,223 @@
+// Package server serves the screenshot index over HTTP on the loopback
+// interface, for editor plugins, launchers and dashboards. Every endpoint
+// but /api/health needs the token, as a bearer token or a token query
+// parameter for clients like EventSource that can't set headers.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"glimpse/screenshots"
+)
+
+// DefaultAddr is where the server listens unless told otherwise
+const DefaultAddr = "127.0.0.1:7373"
+
+const (
+	appName = "Glimpse"
+	tokenFileName = "api-token"
+	tokenBytes = 32
+	shutdownTimeout = 5 * time.Second
+)
+
+// ErrNotLoopback is returned for addresses other machines could reach
+var ErrNotLoopback = errors.New("the server only listens on a loopback address")
+
+// Options configure a Server
+type Options struct {
+	// Addr is host:port on the loopback interface, DefaultAddr if empty
+	Addr string
+	// Token authenticates requests. If empty the token in TokenFile is used,
+	// and one is generated and saved there the first time.
+	Token string
+	// TokenFile defaults to api-token in the Glimpse config folder
+	TokenFile string
+}
+
+// Server is the HTTP API of a screenshot service
+type Server struct {
+	service screenshots.Service
+	events *Broadcaster
+	addr string
+	token string
+	tokenFile string
+}
+
+// New returns a server for service. events has to be the sink service
+// sends its events to, it is streamed from /api/events.
+func New(service screenshots.Service, events *Broadcaster, opts Options) (*Server, error) {
+	addr := opts.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %v", addr, err)
+	}
+	if !isLoopback(host) {
+		return nil, ErrNotLoopback
+	}
+
+	server := &Server{
+		service: service,
+		events: events,
+		addr: addr,
+		token: opts.Token,
+	}
+	if server.token == "" {
+		server.tokenFile = opts.TokenFile
+		if server.tokenFile == "" {
+			if server.tokenFile, err = defaultTokenFile(); err != nil {
+				return nil, err
+			}
+		}
+		if server.token, err = loadToken(server.tokenFile); err != nil {
+			return nil, fmt.Errorf("error loading API token: %v", err)
+		}
+	}
+
+	return server, nil
+}
+
+// Addr is the address the server listens on
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// TokenFile is where the token was read from, empty when it was given
+func (s *Server) TokenFile() string {
+	return s.tokenFile
+}
+
+// Handler routes the API, checking the host and token of every request
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/health", s.health)
+	mux.Handle("GET /api/search", s.authorized(s.search))
+	mux.Handle("GET /api/suggest", s.authorized(s.suggest))
+	mux.Handle("GET /api/documents", s.authorized(s.document))
+	mux.Handle("GET /api/image", s.authorized(s.image))
+	mux.Handle("GET /api/thumbnail", s.authorized(s.thumbnail))
+	mux.Handle("POST /api/scan", s.authorized(s.scan))
+	mux.Handle("GET /api/events", s.authorized(s.stream))
+
+	return s.loopbackOnly(mux)
+}
+
+// ListenAndServe serves the API until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler: s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// loopbackOnly turns away requests for other hosts, so a web page can't
+// reach the API through DNS rebinding
+func (s *Server) loopbackOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !isLoopback(host) {
+			writeError(w, http.StatusForbidden, errors.New("forbidden host"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized only passes on requests carrying the token
+func (s *Server) authorized(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="glimpse"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+
+	return ip != nil && ip.IsLoopback()
+}
+
+func defaultTokenFile() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, appName, tokenFileName), nil
+}
+
+// loadToken reads the token saved in path, generating one the first time
+func loadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"glimpse/screenshots"
+)
+
+const testToken = "secret"
+
+// fakeService answers the calls the server makes, the embedded interface
+// panics on anything else
+type fakeService struct {
+	screenshots.Service
+
+	docs map[string]screenshots.ScreenshotDoc
+	query string
+	opts screenshots.SearchOptions
+	scanned chan struct{}
+	scanSummary *screenshots.ScanSummary
+	scanErr error
+}
+
+func (f *fakeService) SearchHits(query string, opts screenshots.SearchOptions) (*screenshots.SearchResults, []screenshots.SearchHit, error) {
+	f.query, f.opts = query, opts
+	if strings.HasPrefix(query, "(") {
+		return nil, nil, &screenshots.QueryError{Position: 0, Message: "unclosed group"}
+	}
+
+	hits := make([]screenshots.SearchHit, 0)
+	for _, doc := range f.docs {
+		if strings.Contains(doc.Text, query) {
+			hits = append(hits, screenshots.SearchHit{ScreenshotDoc: doc})
+		}
+	}
+
+	return &screenshots.SearchResults{Total: uint64(len(hits)), Page: 1, Size: 20}, hits, nil
+}
+
+func (f *fakeService) Suggest(prefix string) ([]screenshots.Suggestion, error) {
+	return []screenshots.Suggestion{{Text: prefix + "ana", Kind: "tag"}}, nil
+}
+
+func (f *fakeService) Document(id string) (*screenshots.ScreenshotDoc, error) {
+	doc, ok := f.docs[id]
+	if !ok {
+		return nil, screenshots.ErrNotIndexed
+	}
+	return &doc, nil
+}
+
+func (f *fakeService) Image(id string) ([]byte, string, error) {
+	if _, ok := f.docs[id]; !ok {
+		return nil, "", screenshots.ErrNotIndexed
+	}
+	return []byte("<svg/>"), "image/svg+xml", nil
+}
+
+func (f *fakeService) Thumbnail(id string, size int) ([]byte, string, error) {
+	return nil, "", screenshots.ErrNoThumbnail
+}
+
+func (f *fakeService) ScanAndIndex() (*screenshots.ScanSummary, error) {
+	f.scanned <- struct{}{}
+	return f.scanSummary, f.scanErr
+}
+
+func newTestServer(t *testing.T) (*Server, *fakeService) {
+	t.Helper()
+
+	service := &fakeService{
+		docs: map[string]screenshots.ScreenshotDoc{
+			"/home/me/Desktop/grafana.png": {
+				Path: "/home/me/Desktop/grafana.png",
+				Text: "grafana error rate",
+				URL: "aW1hZ2U=",
+				WordBoxes: "1,2,3,4",
+			},
+		},
+		scanned: make(chan struct{}, 1),
+		scanErr: errors.New("no desktop"),
+	}
+
+	srv, err := New(service, NewBroadcaster(), Options{Token: testToken})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return srv, service
+}
+
+func get(t *testing.T, srv *Server, target string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:7373"+target, nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&fakeService{}, NewBroadcaster(), Options{Addr: "0.0.0.0:7373", Token: testToken}); !errors.Is(err, ErrNotLoopback) {
+		t.Errorf("expected ErrNotLoopback, got %v", err)
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "Glimpse", "api-token")
+	srv, err := New(&fakeService{}, NewBroadcaster(), Options{Addr: "localhost:0", TokenFile: tokenFile})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(tokenFile)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected a private token file, got %v, %v", info, err)
+	}
+
+	// the saved token is used again
+	again, err := New(&fakeService{}, NewBroadcaster(), Options{Addr: "localhost:0", TokenFile: tokenFile})
+	if err != nil || again.token != srv.token || len(srv.token) != 2*tokenBytes {
+		t.Errorf("expected the same token twice, got %q and %q", srv.token, again.token)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	tests := []struct {
+		name string
+		target string
+		header map[string]string
+		expected int
+	}{
+		{"Health needs no token", "/api/health", nil, http.StatusOK},
+		{"No token", "/api/suggest?prefix=graf", nil, http.StatusUnauthorized},
+		{"Wrong token", "/api/suggest?prefix=graf", map[string]string{"Authorization": "Bearer guess"}, http.StatusUnauthorized},
+		{"Bearer token", "/api/suggest?prefix=graf", map[string]string{"Authorization": "Bearer " + testToken}, http.StatusOK},
+		{"Query token", "/api/suggest?prefix=graf&token=" + testToken, nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := get(t, srv, tt.target, tt.header); rec.Code != tt.expected {
+				t.Errorf("expected %d, got %d: %s", tt.expected, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	t.Run("Other host", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://attacker.example.com/api/health", nil)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+}
+
+func TestSearch(t *testing.T) {
+	srv, service := newTestServer(t)
+	auth := map[string]string{"Authorization": "Bearer " + testToken}
+
+	rec := get(t, srv, "/api/search?q=grafana&page=2&size=5&sort=newest&filter=ext:png&filter=tag:grafana", auth)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if service.opts.Page != 2 || service.opts.Size != 5 || service.opts.Sort != "newest" || len(service.opts.Filters) != 2 {
+		t.Errorf("expected the parameters to be passed on, got %+v", service.opts)
+	}
+
+	var response struct {
+		Total int `json:"total"`
+		Hits []map[string]interface{} `json:"hits"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Total != 1 || len(response.Hits) != 1 {
+		t.Fatalf("expected one hit, got %s", rec.Body)
+	}
+	hit := response.Hits[0]
+	if hit["url"] != "/api/image?id=%2Fhome%2Fme%2FDesktop%2Fgrafana.png" || hit["thumbnail"] == nil {
+		t.Errorf("expected links instead of the image, got %v and %v", hit["url"], hit["thumbnail"])
+	}
+	if _, ok := hit["word_boxes"]; ok {
+		t.Error("expected no word boxes")
+	}
+
+	for _, target := range []string{"/api/search", "/api/search?q=x&sort=random", "/api/search?q=x&size=-1", "/api/search?q=x&filter=png"} {
+		if rec := get(t, srv, target, auth); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected %s to be a bad request, got %d", target, rec.Code)
+		}
+	}
+
+	rec = get(t, srv, "/api/search?q=(grafana", auth)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"query":{"position":0`) {
+		t.Errorf("expected the query error, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestDocumentAndImages(t *testing.T) {
+	srv, _ := newTestServer(t)
+	auth := map[string]string{"Authorization": "Bearer " + testToken}
+
+	rec := get(t, srv, "/api/documents?id=%2Fhome%2Fme%2FDesktop%2Fgrafana.png", auth)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"text":"grafana error rate"`) {
+		t.Errorf("expected the document, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := get(t, srv, "/api/documents?id=missing.png", auth); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if rec := get(t, srv, "/api/documents", auth); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+
+	// SVGs have no thumbnail, the image is sent instead
+	rec = get(t, srv, "/api/thumbnail?id=%2Fhome%2Fme%2FDesktop%2Fgrafana.png&size=64", auth)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/svg+xml" || rec.Body.String() != "<svg/>" {
+		t.Errorf("expected the svg, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Header().Get("Content-Security-Policy"), "sandbox") {
+		t.Error("expected images to be sandboxed")
+	}
+}
+
+func TestScan(t *testing.T) {
+	srv, service := newTestServer(t)
+
+	scan := func() *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:7373/api/scan", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		<-service.scanned
+
+		return rec
+	}
+
+	// every file failed, the summary says why
+	service.scanSummary = &screenshots.ScanSummary{Files: 1, Failed: 1, Errors: []screenshots.ScanError{{Path: "/home/me/Desktop/broken.png", Message: "bad image"}}}
+	service.scanErr = screenshots.ErrScanFailed
+	rec := scan()
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d: %s", rec.Code, rec.Body)
+	}
+	var response errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Error != screenshots.ErrScanFailed.Error() || response.Summary == nil || response.Summary.Failed != 1 {
+		t.Errorf("expected the error and the summary, got %s", rec.Body)
+	}
+
+	// some files failed
+	service.scanSummary = &screenshots.ScanSummary{Files: 2, Indexed: 1, Failed: 1}
+	service.scanErr = nil
+	if rec := scan(); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"indexed":1`) {
+		t.Errorf("expected the summary, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestScanEvents(t *testing.T) {
+	srv, service := newTestServer(t)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/events?token="+testToken, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", res.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(res.Body)
+	// wait for the stream to open before sending events
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("expected the stream to open, got %q", line)
+	}
+
+	scan, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/scan", nil)
+	scan.Header.Set("Authorization", "Bearer "+testToken)
+	scanRes, err := http.DefaultClient.Do(scan)
+	if err != nil || scanRes.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected the scan to fail, got %v, %v", scanRes, err)
+	}
+	<-service.scanned
+
+	// the app or another request is scanning
+	service.scanErr = screenshots.ErrScanInProgress
+	busy, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/scan", nil)
+	busy.Header.Set("Authorization", "Bearer "+testToken)
+	busyRes, err := http.DefaultClient.Do(busy)
+	if err != nil || busyRes.StatusCode != http.StatusConflict {
+		t.Fatalf("expected a conflict, got %v, %v", busyRes, err)
+	}
+	<-service.scanned
+
+	// what the service sends while scanning, search events aren't streamed
+	srv.events.Emit("search:found", screenshots.SearchHit{})
+	srv.events.Emit("result:found", screenshots.ScreenshotDoc{Path: "/home/me/Desktop/new.png", URL: "aW1hZ2U="})
+
+	// the failed scan may be reported before or after the new file
+	expected := map[string]string{
+		"event: scan:failed": `data: {"error":"no desktop"}`,
+		"event: result:found": `data: {"path":"/home/me/Desktop/new.png"}`,
+	}
+	done := make(chan map[string]string)
+	go func() {
+		got := make(map[string]string)
+		for len(got) < len(expected) {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			if !strings.HasPrefix(line, "event: ") {
+				continue
+			}
+			data, _ := reader.ReadString('\n')
+			got[strings.TrimSpace(line)] = strings.TrimSpace(data)
+		}
+		done <- got
+	}()
+
+	select {
+	case got := <-done:
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for events")
+	}
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster()
+	events, unsubscribe := b.Subscribe()
+
+	// a client that doesn't read misses events instead of blocking
+	for i := 0; i < subscriberBuffer+10; i++ {
+		b.Emit("scan:progress", screenshots.ScanProgress{Done: i})
+	}
+	if len(events) != subscriberBuffer {
+		t.Errorf("expected %d buffered events, got %d", subscriberBuffer, len(events))
+	}
+
+	unsubscribe()
+	unsubscribe()
+	b.Emit("scan:done", screenshots.ScanProgress{})
+}